@@ -179,14 +179,17 @@ func DialContext(ctx context.Context, target string, opts ...DialOption) (conn *
 		cc.csMgr.channelzID = cc.channelzID
 	}
 
-	if !cc.dopts.insecure {
+	switch {
+	case cc.dopts.inProcess != nil:
+		// in-process connections need no transport security.
+	case !cc.dopts.insecure:
 		if cc.dopts.copts.TransportCredentials == nil && cc.dopts.copts.CredsBundle == nil {
 			return nil, errNoTransportSecurity
 		}
 		if cc.dopts.copts.TransportCredentials != nil && cc.dopts.copts.CredsBundle != nil {
 			return nil, errTransportCredsAndBundle
 		}
-	} else {
+	default:
 		if cc.dopts.copts.TransportCredentials != nil || cc.dopts.copts.CredsBundle != nil {
 			return nil, errCredentialsConflict
 		}
@@ -0,0 +1,144 @@
+/*
+ *
+ * Copyright 2020 gRPC authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+// greeterFile returns the descriptor of a service with methods of every
+// streaming kind.
+func greeterFile() *descriptorpb.FileDescriptorProto {
+	method := func(name string, clientStreaming, serverStreaming bool) *descriptorpb.MethodDescriptorProto {
+		return &descriptorpb.MethodDescriptorProto{
+			Name:            proto.String(name),
+			InputType:       proto.String(".helloworld.HelloRequest"),
+			OutputType:      proto.String(".helloworld.HelloReply"),
+			ClientStreaming: proto.Bool(clientStreaming),
+			ServerStreaming: proto.Bool(serverStreaming),
+		}
+	}
+	message := func(name string) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{
+			Name: proto.String(name),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("name"),
+				JsonName: proto.String("name"),
+				Number:   proto.Int32(1),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			}},
+		}
+	}
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("helloworld.proto"),
+		Package: proto.String("helloworld"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{
+			GoPackage: proto.String("github.com/dubbogo/grpc-go/examples/helloworld/helloworld"),
+		},
+		MessageType: []*descriptorpb.DescriptorProto{message("HelloRequest"), message("HelloReply")},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Greeter"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("SayHello", false, false),
+				method("SayHelloServerStream", false, true),
+				method("SayHelloClientStream", true, false),
+				method("SayHelloStream", true, true),
+			},
+		}},
+	}
+}
+
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		golden    string
+		parameter string
+	}{
+		{golden: "helloworld_grpc.pb.go.golden", parameter: ""},
+		{golden: "helloworld_triple_grpc.pb.go.golden", parameter: "triple=true"},
+		{golden: "helloworld_triple_interface_grpc.pb.go.golden", parameter: "triple=true,interface_name=helloworld.Greeter=org.apache.dubbo.demo.Greeter"},
+	}
+	for _, test := range tests {
+		t.Run(test.golden, func(t *testing.T) {
+			got := generate(t, test.parameter)
+			path := filepath.Join("testdata", test.golden)
+			if *update {
+				if err := ioutil.WriteFile(path, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("generated code differs from %s, run go test -update to regenerate it:\n%s", path, got)
+			}
+		})
+	}
+}
+
+// generate runs the plugin with @parameter on greeterFile and returns the
+// content of the generated file.
+func generate(t *testing.T, parameter string) []byte {
+	var flags flag.FlagSet
+	requireUnimplemented = flags.Bool("require_unimplemented_servers", true, "")
+	triple = flags.Bool("triple", false, "")
+	interfaceNames = make(interfaceNameFlag)
+	flags.Var(interfaceNames, "interface_name", "")
+
+	file := greeterFile()
+	gen, err := protogen.Options{ParamFunc: flags.Set}.New(&pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{file.GetName()},
+		Parameter:      proto.String(parameter),
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{file},
+	})
+	if err != nil {
+		t.Fatalf("protogen.Options.New() failed: %v", err)
+	}
+	for _, f := range gen.Files {
+		if f.Generate {
+			generateFile(gen, f)
+		}
+	}
+	resp := gen.Response()
+	if resp.Error != nil {
+		t.Fatalf("generation failed: %v", resp.GetError())
+	}
+	if len(resp.File) != 1 {
+		t.Fatalf("generated %d files, want 1", len(resp.File))
+	}
+	return []byte(resp.File[0].GetContent())
+}
@@ -31,10 +31,11 @@ import (
 )
 
 const (
-	contextPackage = protogen.GoImportPath("context")
-	grpcPackage    = protogen.GoImportPath("github.com/dubbogo/grpc-go")
-	codesPackage   = protogen.GoImportPath("github.com/dubbogo/grpc-go/codes")
-	statusPackage  = protogen.GoImportPath("github.com/dubbogo/grpc-go/status")
+	contextPackage  = protogen.GoImportPath("context")
+	grpcPackage     = protogen.GoImportPath("github.com/dubbogo/grpc-go")
+	codesPackage    = protogen.GoImportPath("github.com/dubbogo/grpc-go/codes")
+	metadataPackage = protogen.GoImportPath("github.com/dubbogo/grpc-go/metadata")
+	statusPackage   = protogen.GoImportPath("github.com/dubbogo/grpc-go/status")
 )
 
 // generateFile generates a _grpc.pb.go file containing gRPC service definitions.
@@ -109,6 +110,10 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
 		}
 		g.P(method.Comments.Leading,
 			clientSignature(g, method))
+		if *triple && isUnary(method) {
+			g.P("// ", method.GoName, "WithAttachments is ", method.GoName, " returning the attachments of the response as well.")
+			g.P(attachmentsClientSignature(g, method))
+		}
 	}
 	g.P("}")
 	g.P()
@@ -116,6 +121,9 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
 	// Client structure.
 	g.P("type ", unexport(clientName), " struct {")
 	g.P("cc ", grpcPackage.Ident("ClientConnInterface"))
+	if *triple {
+		g.P("group, version string")
+	}
 	g.P("}")
 	g.P()
 
@@ -124,10 +132,14 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
 		g.P(deprecationComment)
 	}
 	g.P("func New", clientName, " (cc ", grpcPackage.Ident("ClientConnInterface"), ") ", clientName, " {")
-	g.P("return &", unexport(clientName), "{cc}")
+	g.P("return &", unexport(clientName), "{cc: cc}")
 	g.P("}")
 	g.P()
 
+	if *triple {
+		genTripleClient(g, service, clientName)
+	}
+
 	var methodIndex, streamIndex int
 	// Client method implementations.
 	for _, method := range service.Methods {
@@ -189,6 +201,16 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
 	if *requireUnimplemented {
 		g.P("func (Unimplemented", serverType, ") mustEmbedUnimplemented", serverType, "() {}")
 	}
+	if *triple {
+		g.P("// XXX_InterfaceName returns the dubbo interface name of ", service.GoName, " service.")
+		g.P("func (Unimplemented", serverType, ") XXX_InterfaceName() string {")
+		g.P("return ", service.GoName, "_InterfaceName")
+		g.P("}")
+		g.P("// XXX_ServiceDesc returns the ", grpcPackage.Ident("ServiceDesc"), " of ", service.GoName, " service.")
+		g.P("func (Unimplemented", serverType, ") XXX_ServiceDesc() *", grpcPackage.Ident("ServiceDesc"), " {")
+		g.P("return &", service.GoName, "_ServiceDesc")
+		g.P("}")
+	}
 	g.P()
 
 	// Unsafe Server interface to opt-out of forward compatibility.
@@ -208,6 +230,17 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
 	g.P("s.RegisterService(&", serviceDescVar, `, srv)`)
 	g.P("}")
 	g.P()
+	if *triple {
+		g.P("// Register", service.GoName, "ServerWithGroupVersion registers the implementation of ", service.GoName, " service")
+		g.P("// of dubbo group and version.")
+		if service.Desc.Options().(*descriptorpb.ServiceOptions).GetDeprecated() {
+			g.P(deprecationComment)
+		}
+		g.P("func Register", service.GoName, "ServerWithGroupVersion(s ", grpcPackage.Ident("TripleServiceRegistrar"), ", srv ", serverType, ", group, version string) {")
+		g.P("s.RegisterServiceWithGroupVersion(&", serviceDescVar, `, srv, group, version)`)
+		g.P("}")
+		g.P()
+	}
 
 	// Server handler implementations.
 	handlerNames := make([]string, 0, len(service.Methods))
@@ -221,7 +254,11 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
 	g.P("// It's only intended for direct use with ", grpcPackage.Ident("RegisterService"), ",")
 	g.P("// and not to be introspected or modified (even as a copy)")
 	g.P("var ", serviceDescVar, " = ", grpcPackage.Ident("ServiceDesc"), " {")
-	g.P("ServiceName: ", strconv.Quote(string(service.Desc.FullName())), ",")
+	if *triple {
+		g.P("ServiceName: ", service.GoName, "_InterfaceName,")
+	} else {
+		g.P("ServiceName: ", strconv.Quote(string(service.Desc.FullName())), ",")
+	}
 	g.P("HandlerType: (*", serverType, ")(nil),")
 	g.P("Methods: []", grpcPackage.Ident("MethodDesc"), "{")
 	for i, method := range service.Methods {
@@ -271,6 +308,56 @@ func clientSignature(g *protogen.GeneratedFile, method *protogen.Method) string
 	return s
 }
 
+func attachmentsClientSignature(g *protogen.GeneratedFile, method *protogen.Method) string {
+	return method.GoName + "WithAttachments(ctx " + g.QualifiedGoIdent(contextPackage.Ident("Context")) +
+		", in *" + g.QualifiedGoIdent(method.Input.GoIdent) +
+		", opts ..." + g.QualifiedGoIdent(grpcPackage.Ident("CallOption")) +
+		") (*" + g.QualifiedGoIdent(method.Output.GoIdent) + ", map[string]interface{}, error)"
+}
+
+// genTripleClient generates the dubbo interface name of @service, the
+// factory of clients of a group and a version, and the context of calls.
+func genTripleClient(g *protogen.GeneratedFile, service *protogen.Service, clientName string) {
+	g.P("// ", service.GoName, "_InterfaceName is the dubbo interface name of ", service.GoName, " service.")
+	g.P("const ", service.GoName, "_InterfaceName = ", strconv.Quote(interfaceName(service)))
+	g.P()
+
+	g.P("// New", service.GoName, "TripleClient returns a ", clientName, " calling the implementation of")
+	g.P("// dubbo group and version, any of them may be empty.")
+	if service.Desc.Options().(*descriptorpb.ServiceOptions).GetDeprecated() {
+		g.P(deprecationComment)
+	}
+	g.P("func New", service.GoName, "TripleClient (cc ", grpcPackage.Ident("ClientConnInterface"), ", group, version string) ", clientName, " {")
+	g.P("return &", unexport(clientName), "{cc: cc, group: group, version: version}")
+	g.P("}")
+	g.P()
+
+	g.P("func (c *", unexport(clientName), ") withServiceKey(ctx ", contextPackage.Ident("Context"), ") ", contextPackage.Ident("Context"), " {")
+	g.P("if c.group != \"\" {")
+	g.P("ctx = ", metadataPackage.Ident("AppendToOutgoingContext"), "(ctx, ", grpcPackage.Ident("TripleServiceGroupHeader"), ", c.group)")
+	g.P("}")
+	g.P("if c.version != \"\" {")
+	g.P("ctx = ", metadataPackage.Ident("AppendToOutgoingContext"), "(ctx, ", grpcPackage.Ident("TripleServiceVersionHeader"), ", c.version)")
+	g.P("}")
+	g.P("return ctx")
+	g.P("}")
+	g.P()
+}
+
+// interfaceName returns the dubbo interface name of @service, given by the
+// interface_name option, or else its proto full name.
+func interfaceName(service *protogen.Service) string {
+	fullName := string(service.Desc.FullName())
+	if name, ok := interfaceNames[fullName]; ok {
+		return name
+	}
+	return fullName
+}
+
+func isUnary(method *protogen.Method) bool {
+	return !method.Desc.IsStreamingClient() && !method.Desc.IsStreamingServer()
+}
+
 func genClientMethod(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, method *protogen.Method, index int) {
 	service := method.Parent
 	sname := fmt.Sprintf("/%s/%s", service.Desc.FullName(), method.Desc.Name())
@@ -279,18 +366,32 @@ func genClientMethod(gen *protogen.Plugin, file *protogen.File, g *protogen.Gene
 		g.P(deprecationComment)
 	}
 	g.P("func (c *", unexport(service.GoName), "Client) ", clientSignature(g, method), "{")
+	ctx := "ctx"
+	if *triple {
+		ctx = "c.withServiceKey(ctx)"
+	}
 	if !method.Desc.IsStreamingServer() && !method.Desc.IsStreamingClient() {
 		g.P("out := new(", method.Output.GoIdent, ")")
-		g.P(`err := c.cc.Invoke(ctx, "`, sname, `", in, out, opts...)`)
+		g.P(`_, err := c.cc.Invoke(`, ctx, `, "`, sname, `", in, out, opts...)`)
 		g.P("if err != nil { return nil, err }")
 		g.P("return out, nil")
 		g.P("}")
 		g.P()
+		if *triple {
+			g.P("func (c *", unexport(service.GoName), "Client) ", attachmentsClientSignature(g, method), "{")
+			g.P("out := new(", method.Output.GoIdent, ")")
+			g.P("var header ", metadataPackage.Ident("MD"))
+			g.P(`trailer, err := c.cc.Invoke(`, ctx, `, "`, sname, `", in, out, append(opts[:len(opts):len(opts)], `, grpcPackage.Ident("Header"), `(&header))...)`)
+			g.P("if err != nil { return nil, nil, err }")
+			g.P("return out, ", grpcPackage.Ident("MergeAttachments"), "(header, trailer), nil")
+			g.P("}")
+			g.P()
+		}
 		return
 	}
 	streamType := unexport(service.GoName) + method.GoName + "Client"
 	serviceDescVar := service.GoName + "_ServiceDesc"
-	g.P("stream, err := c.cc.NewStream(ctx, &", serviceDescVar, ".Streams[", index, `], "`, sname, `", opts...)`)
+	g.P("stream, err := c.cc.NewStream(", ctx, ", &", serviceDescVar, ".Streams[", index, `], "`, sname, `", opts...)`)
 	g.P("if err != nil { return nil, err }")
 	g.P("x := &", streamType, "{stream}")
 	if !method.Desc.IsStreamingClient() {
@@ -19,20 +19,24 @@
 // protoc-gen-go-grpc is a plugin for the Google protocol buffer compiler to
 // generate Go code. Install it by building this program and making it
 // accessible within your PATH with the name:
+//
 //	protoc-gen-go-grpc
 //
 // The 'go-grpc' suffix becomes part of the argument for the protocol compiler,
 // such that it can be invoked as:
+//
 //	protoc --go-grpc_out=. path/to/file.proto
 //
 // This generates Go service definitions for the protocol buffer defined by
 // file.proto.  With that input, the output will be written to:
+//
 //	path/to/file_grpc.pb.go
 package main
 
 import (
 	"flag"
 	"fmt"
+	"strings"
 )
 
 import (
@@ -43,7 +47,29 @@ import (
 
 const version = "1.1.0"
 
-var requireUnimplemented *bool
+var (
+	requireUnimplemented *bool
+	triple               *bool
+	// proto service full name -> dubbo interface name
+	interfaceNames = make(interfaceNameFlag)
+)
+
+// interfaceNameFlag collects the repeated option
+// interface_name=<service full name>=<dubbo interface name>.
+type interfaceNameFlag map[string]string
+
+func (f interfaceNameFlag) String() string {
+	return fmt.Sprint(map[string]string(f))
+}
+
+func (f interfaceNameFlag) Set(value string) error {
+	pos := strings.IndexByte(value, '=')
+	if pos <= 0 || pos == len(value)-1 {
+		return fmt.Errorf("interface_name %q is not <service>=<interface>", value)
+	}
+	f[value[:pos]] = value[pos+1:]
+	return nil
+}
 
 func main() {
 	showVersion := flag.Bool("version", false, "print the version and exit")
@@ -55,6 +81,8 @@ func main() {
 
 	var flags flag.FlagSet
 	requireUnimplemented = flags.Bool("require_unimplemented_servers", true, "set to false to match legacy behavior")
+	triple = flags.Bool("triple", false, "set to true to generate dubbo triple compatible stubs")
+	flags.Var(interfaceNames, "interface_name", "set <service>=<interface> to override the dubbo interface name of a service with option triple")
 
 	protogen.Options{
 		ParamFunc: flags.Set,
@@ -0,0 +1,134 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"strings"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/encoding"
+	"github.com/dubbogo/grpc-go/encoding/proto"
+	"github.com/dubbogo/grpc-go/internal/transport"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+// StrictContentSubtype returns a ServerOption that makes the server reject
+// requests whose content-subtype has no registered codec with Unimplemented,
+// instead of decoding them with the proto codec. The error lists the
+// registered codecs. It has no effect with ForceServerCodec.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func StrictContentSubtype(strict bool) ServerOption {
+	return newFuncServerOption(func(o *serverOptions) {
+		o.strictContentSubtype = strict
+	})
+}
+
+// AcceptContentSubtypes returns a CallOption that advertises the
+// content-subtypes the client accepts for responses, in order of preference.
+// The server encodes responses with the first one it has a codec for, and
+// sends it in the response content-type. The request is still encoded with
+// the codec of CallContentSubtype or ForceCodec, which is used for responses
+// if the server supports none of @contentSubtypes.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func AcceptContentSubtypes(contentSubtypes ...string) CallOption {
+	subtypes := make([]string, 0, len(contentSubtypes))
+	for _, st := range contentSubtypes {
+		subtypes = append(subtypes, strings.ToLower(st))
+	}
+	return AcceptContentSubtypesCallOption{ContentSubtypes: subtypes}
+}
+
+// AcceptContentSubtypesCallOption is a CallOption that indicates the
+// content-subtypes accepted for responses.
+//
+// Experimental
+//
+// Notice: This type is EXPERIMENTAL and may be changed or removed in a
+// later release.
+type AcceptContentSubtypesCallOption struct {
+	ContentSubtypes []string
+}
+
+func (o AcceptContentSubtypesCallOption) before(c *callInfo) error {
+	c.acceptContentSubtypes = o.ContentSubtypes
+	return nil
+}
+func (o AcceptContentSubtypesCallOption) after(c *callInfo, attempt *csAttempt) {}
+
+// negotiateContentSubtype checks the content-subtype of @stream in strict
+// mode and picks the content-subtype of its responses. It returns the status
+// to reject @stream with, or nil.
+func (s *Server) negotiateContentSubtype(stream *transport.Stream) *status.Status {
+	if s.opts.codec != nil {
+		return nil
+	}
+	subtype := stream.ContentSubtype()
+	if s.opts.strictContentSubtype && subtype != "" && encoding.GetCodec(subtype) == nil {
+		return status.Newf(codes.Unimplemented, "grpc: no codec registered for content-subtype %q, registered codecs: %s",
+			subtype, strings.Join(encoding.RegisteredCodecs(), ", "))
+	}
+	for _, accept := range stream.AcceptContentSubtypes() {
+		if encoding.GetCodec(accept) != nil {
+			stream.SetSendContentSubtype(accept)
+			break
+		}
+	}
+	return nil
+}
+
+// responseCodec returns the codec decoding the responses of @s, which is
+// the codec of the response content-subtype if the server picked one of the
+// AcceptContentSubtypes. Trailers-only responses have no content-subtype and
+// no message, they use the request codec.
+func (cs *clientStream) responseCodec(s *transport.Stream) (encoding.TwoWayCodec, error) {
+	if len(cs.callInfo.acceptContentSubtypes) == 0 || s.TrailersOnly() {
+		return cs.codec, nil
+	}
+	subtype := s.RecvContentSubtype()
+	if normalizeContentSubtype(subtype) == normalizeContentSubtype(cs.callInfo.contentSubtype) {
+		return cs.codec, nil
+	}
+	for _, accept := range cs.callInfo.acceptContentSubtypes {
+		if accept != subtype {
+			continue
+		}
+		if codec := encoding.GetCodec(subtype); codec != nil {
+			return codec, nil
+		}
+		break
+	}
+	return nil, status.Errorf(codes.Internal, "grpc: no codec for response content-subtype %q", subtype)
+}
+
+// normalizeContentSubtype returns @subtype, or the proto one if it is empty.
+func normalizeContentSubtype(subtype string) string {
+	if subtype == "" {
+		return proto.Name
+	}
+	return subtype
+}
@@ -0,0 +1,165 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+import (
+	wrapperspb "github.com/golang/protobuf/ptypes/wrappers"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/encoding"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+// prefixCodec encodes string values with its name as prefix, and fails to
+// decode the ones encoded by other prefixCodecs.
+type prefixCodec struct {
+	name string
+}
+
+func (c prefixCodec) MarshalRequest(v interface{}) ([]byte, error) {
+	return []byte(c.name + ":" + v.(*wrapperspb.StringValue).Value), nil
+}
+
+func (c prefixCodec) MarshalResponse(v interface{}) ([]byte, error) {
+	return c.MarshalRequest(v)
+}
+
+func (c prefixCodec) UnmarshalRequest(data []byte, v interface{}) error {
+	if !strings.HasPrefix(string(data), c.name+":") {
+		return fmt.Errorf("%s codec cannot decode %q", c.name, data)
+	}
+	v.(*wrapperspb.StringValue).Value = strings.TrimPrefix(string(data), c.name+":")
+	return nil
+}
+
+func (c prefixCodec) UnmarshalResponse(data []byte, v interface{}) error {
+	return c.UnmarshalRequest(data, v)
+}
+
+func (c prefixCodec) Name() string {
+	return c.name
+}
+
+func init() {
+	encoding.RegisterCodec(prefixCodec{name: "subtype-a"})
+	encoding.RegisterCodec(prefixCodec{name: "subtype-b"})
+}
+
+// echoServiceDesc echoes string requests, and fails the "fail" ones.
+var echoServiceDesc = ServiceDesc{
+	ServiceName: "grpc.testing.Echo",
+	HandlerType: (*interface{})(nil),
+	Methods: []MethodDesc{{
+		MethodName: "Echo",
+		Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ UnaryServerInterceptor) (interface{}, error) {
+			in := new(wrapperspb.StringValue)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if in.Value == "fail" {
+				return nil, status.Error(codes.FailedPrecondition, "failed on purpose")
+			}
+			return in, nil
+		},
+	}},
+}
+
+// startEchoServer starts a server with @opts serving echoServiceDesc, and
+// returns a connection to it and a function stopping both.
+func startEchoServer(t *testing.T, opts ...ServerOption) (*ClientConn, func()) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() failed: %v", err)
+	}
+	srv := NewServer(opts...)
+	srv.RegisterService(&echoServiceDesc, struct{}{})
+	go srv.Serve(lis)
+
+	cc, err := Dial(lis.Addr().String(), WithInsecure())
+	if err != nil {
+		srv.Stop()
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	return cc, func() {
+		cc.Close()
+		srv.Stop()
+	}
+}
+
+func echo(cc *ClientConn, in string, opts ...CallOption) (string, error) {
+	out := new(wrapperspb.StringValue)
+	_, err := cc.Invoke(context.Background(), "/grpc.testing.Echo/Echo", &wrapperspb.StringValue{Value: in}, out, opts...)
+	return out.Value, err
+}
+
+func (s) TestStrictContentSubtype(t *testing.T) {
+	unregistered := ForceCodec(prefixCodec{name: "subtype-unregistered"})
+
+	cc, stop := startEchoServer(t, StrictContentSubtype(true))
+	defer stop()
+	_, err := echo(cc, "hello", unregistered)
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("echo() with an unregistered content-subtype = %v, want code Unimplemented", err)
+	}
+	if msg := status.Convert(err).Message(); !strings.Contains(msg, "subtype-unregistered") || !strings.Contains(msg, "subtype-a") {
+		t.Errorf("status message %q does not name the content-subtype and the registered codecs", msg)
+	}
+	if out, err := echo(cc, "hello", CallContentSubtype("subtype-a")); err != nil || out != "hello" {
+		t.Errorf("echo() with a registered content-subtype = %q, %v, want \"hello\", nil", out, err)
+	}
+
+	// without strict mode the request is decoded with the proto codec
+	cc, stopLax := startEchoServer(t)
+	defer stopLax()
+	if _, err := echo(cc, "hello", unregistered); status.Code(err) == codes.Unimplemented {
+		t.Errorf("echo() with an unregistered content-subtype = %v, want a decoding failure", err)
+	}
+}
+
+func (s) TestAcceptContentSubtypes(t *testing.T) {
+	cc, stop := startEchoServer(t)
+	defer stop()
+	for _, test := range []struct {
+		name    string
+		accept  []string
+		in      string
+		wantOut string
+		wantErr codes.Code
+	}{
+		{name: "accepted", accept: []string{"subtype-unregistered", "subtype-b"}, in: "hello", wantOut: "hello"},
+		{name: "none supported", accept: []string{"subtype-unregistered"}, in: "hello", wantOut: "hello"},
+		{name: "trailers-only", accept: []string{"subtype-b"}, in: "fail", wantErr: codes.FailedPrecondition},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := echo(cc, test.in, CallContentSubtype("subtype-a"), AcceptContentSubtypes(test.accept...))
+			if status.Code(err) != test.wantErr || out != test.wantOut {
+				t.Errorf("echo() = %q, %v, want %q, code %v", out, err, test.wantOut, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"net/http"
+)
+
+// corsExposedHeaders are the response headers that scripts of other origins
+// may read: the status of gRPC-Web and json responses, and the custom
+// metadata of the handler.
+const corsExposedHeaders = "grpc-status, grpc-message, grpc-status-details-bin, grpc-encoding, *"
+
+// corsMaxAge is the number of seconds a browser may cache a preflight.
+const corsMaxAge = "600"
+
+// WebCORS returns a ServerOption that lets the pages of the origins for which
+// @allowOrigin returns true call the server through ServeHTTP with gRPC-Web
+// and json requests: their OPTIONS preflights are answered, and the responses
+// carry the Access-Control-Allow-Origin and Access-Control-Expose-Headers
+// headers. Without it, only pages of the server's own origin can call it.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func WebCORS(allowOrigin func(origin string) bool) ServerOption {
+	return newFuncServerOption(func(o *serverOptions) {
+		o.allowOrigin = allowOrigin
+	})
+}
+
+// handleCORS sets the CORS headers of @w for a request @r of an allowed
+// origin. It returns true if @r is a preflight, which it has answered.
+func (s *Server) handleCORS(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if s.opts.allowOrigin == nil || origin == "" {
+		return false
+	}
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	if !s.opts.allowOrigin(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Set("Access-Control-Allow-Origin", origin)
+	if !preflight {
+		h.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		return false
+	}
+	h.Add("Vary", "Access-Control-Request-Headers")
+	h.Set("Access-Control-Allow-Methods", http.MethodPost)
+	if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+		h.Set("Access-Control-Allow-Headers", headers)
+	}
+	h.Set("Access-Control-Max-Age", corsMaxAge)
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
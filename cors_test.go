@@ -0,0 +1,85 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func (s) TestWebCORS(t *testing.T) {
+	srv := NewServer(WebCORS(func(origin string) bool { return origin == "https://allowed.example" }))
+	defer srv.Stop()
+
+	serve := func(method, origin string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/grpc.testing.Echo/Echo", nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+	preflight := http.Header{
+		"Access-Control-Request-Method":  {"POST"},
+		"Access-Control-Request-Headers": {"content-type, x-grpc-web"},
+	}
+
+	rec := serve(http.MethodOptions, "https://allowed.example", preflight)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("preflight of an allowed origin replied %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	for k, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "https://allowed.example",
+		"Access-Control-Allow-Methods": "POST",
+		"Access-Control-Allow-Headers": "content-type, x-grpc-web",
+		"Access-Control-Max-Age":       corsMaxAge,
+	} {
+		if got := rec.Header().Get(k); got != want {
+			t.Errorf("preflight header %s = %q, want %q", k, got, want)
+		}
+	}
+
+	rec = serve(http.MethodOptions, "https://other.example", preflight)
+	if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("preflight of another origin replied %d with origin %q, want %d without origin", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"), http.StatusForbidden)
+	}
+
+	// The request is not a valid gRPC one, but is still replied with the
+	// CORS headers so that the page can read the error.
+	rec = serve(http.MethodPost, "https://allowed.example", http.Header{"Content-Type": {"text/plain"}})
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://allowed.example" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, "https://allowed.example")
+	}
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); got != corsExposedHeaders {
+		t.Errorf("Access-Control-Expose-Headers = %q, want %q", got, corsExposedHeaders)
+	}
+
+	noCORS := NewServer()
+	defer noCORS.Stop()
+	req := httptest.NewRequest(http.MethodOptions, "/grpc.testing.Echo/Echo", nil)
+	req.Header.Set("Origin", "https://allowed.example")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec = httptest.NewRecorder()
+	noCORS.ServeHTTP(rec, req)
+	if rec.Code == http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("server without WebCORS answered the preflight with %d", rec.Code)
+	}
+}
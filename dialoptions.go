@@ -70,6 +70,7 @@ type dialOptions struct {
 	defaultServiceConfig        *ServiceConfig // defaultServiceConfig is parsed from defaultServiceConfigRawJSON.
 	defaultServiceConfigRawJSON *string
 	resolvers                   []resolver.Builder
+	inProcess                   *InProcessListener
 }
 
 // DialOption configures how we set up the connection.
@@ -310,6 +311,24 @@ func WithInsecure() DialOption {
 	})
 }
 
+// WithInProcessDialer returns a DialOption which connects the ClientConn to
+// the Server serving lis in the same process, whatever the target and the
+// resolved addresses are. The messages of the streams are passed as objects,
+// see InProcessListener. Transport security is not required: in-process
+// connections never leave the process, and per-RPC credentials see them as
+// private.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func WithInProcessDialer(lis *InProcessListener) DialOption {
+	return newFuncDialOption(func(o *dialOptions) {
+		o.inProcess = lis
+		o.copts.InProcessDial = lis.dial
+	})
+}
+
 // WithNoProxy returns a DialOption which disables the use of proxies for this
 // ClientConn. This is ignored if WithDialer or WithContextDialer are used.
 //
@@ -0,0 +1,68 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package encoding
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity above which buffers are not put back
+// to the pool, so that a few huge messages do not pin memory forever.
+const maxPooledBufferSize = 1 << 20
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// GetBuffer returns an empty buffer from the pool shared by the codecs and
+// the grpc message encoding.
+func GetBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// PutBuffer resets @buf and puts it back to the pool. @buf and the bytes it
+// returned must not be used afterwards.
+func PutBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// BufferedCodec is implemented by Codecs which can serialize directly into a
+// buffer instead of returning their own []byte.
+type BufferedCodec interface {
+	Codec
+	// MarshalTo appends the wire format of v to buf.
+	MarshalTo(buf *bytes.Buffer, v interface{}) error
+}
+
+// BufferedTwoWayCodec is implemented by TwoWayCodecs which can marshal into a
+// pooled buffer. grpc prefers these methods to MarshalRequest and
+// MarshalResponse, and copies the result out of the buffer exactly once.
+type BufferedTwoWayCodec interface {
+	TwoWayCodec
+	// MarshalRequestTo appends the wire format of request v to buf.
+	MarshalRequestTo(buf *bytes.Buffer, v interface{}) error
+	// MarshalResponseTo appends the wire format of response v to buf.
+	MarshalResponseTo(buf *bytes.Buffer, v interface{}) error
+}
@@ -27,6 +27,7 @@ package encoding
 
 import (
 	"io"
+	"sort"
 	"strings"
 )
 
@@ -139,3 +140,14 @@ func RegisterCodec(codec TwoWayCodec) {
 func GetCodec(contentSubtype string) TwoWayCodec {
 	return registeredCodecs[contentSubtype]
 }
+
+// RegisteredCodecs returns the sorted content-subtypes of the registered
+// Codecs.
+func RegisteredCodecs() []string {
+	names := make([]string, 0, len(registeredCodecs))
+	for name := range registeredCodecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
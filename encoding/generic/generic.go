@@ -1 +1,163 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package generic defines the request and result types used to call triple
+// services without generated stubs.
 package generic
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// Request is a generic triple request. Unlike a plain []interface{} argument
+// list, the java type names of the arguments are given explicitly instead of
+// being inferred by encoding.GetArgType, so that java providers can resolve
+// overloaded methods and POJO parameters without a go struct.
+type Request struct {
+	// ArgTypes are the java class names of Args, e.g. "java.lang.String".
+	ArgTypes []string
+	// Args are the argument values, usually basic types, maps and hessian.Object.
+	Args []interface{}
+}
+
+// NewRequest returns a Request with @argTypes and @args.
+func NewRequest(argTypes []string, args []interface{}) *Request {
+	return &Request{
+		ArgTypes: argTypes,
+		Args:     args,
+	}
+}
+
+// Validate checks that every argument has a java type name.
+func (r *Request) Validate() error {
+	if len(r.ArgTypes) != len(r.Args) {
+		return perrors.Errorf("generic request has %d arg types, but %d args", len(r.ArgTypes), len(r.Args))
+	}
+	return nil
+}
+
+// Normalize converts decoded map[interface{}]interface{} values whose keys are
+// all strings into map[string]interface{}, recursively through maps and
+// slices. Other values, including hessian objects, are returned as is. The
+// maps and slices of @v are copied rather than modified, so that arguments
+// may be normalized and still be reused by the caller.
+func Normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		strMap := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			key, ok := k.(string)
+			if !ok {
+				anyMap := make(map[interface{}]interface{}, len(val))
+				for k, item := range val {
+					anyMap[k] = Normalize(item)
+				}
+				return anyMap
+			}
+			strMap[key] = Normalize(item)
+		}
+		return strMap
+	case map[string]interface{}:
+		strMap := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			strMap[k] = Normalize(item)
+		}
+		return strMap
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = Normalize(item)
+		}
+		return list
+	default:
+		return v
+	}
+}
+
+// Unmarshaler decodes a single serialized argument, it is satisfied by the
+// inner codec of encoding.PBWrapperTwoWayCodec.
+type Unmarshaler interface {
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// RawArgs are the arguments of a TripleRequestWrapper, which are only decoded
+// when asked for. It is filled by encoding.PBWrapperTwoWayCodec.UnmarshalRequest.
+type RawArgs struct {
+	// SerializeType is the serialization of Data, e.g. "hessian2".
+	SerializeType string
+	// Types are the java class names of the arguments.
+	Types []string
+	// Data are the serialized arguments.
+	Data [][]byte
+	// Codec decodes Data.
+	Codec Unmarshaler
+
+	values []interface{}
+}
+
+// Len returns the number of arguments.
+func (r *RawArgs) Len() int {
+	return len(r.Data)
+}
+
+// Decode decodes the @idx th argument into @v, which must be a pointer.
+func (r *RawArgs) Decode(idx int, v interface{}) error {
+	if idx < 0 || idx >= len(r.Data) {
+		return perrors.Errorf("arg index %d out of range, request has %d args", idx, len(r.Data))
+	}
+	if r.Codec == nil {
+		return perrors.New("generic args have no codec")
+	}
+	return r.Codec.Unmarshal(r.Data[idx], v)
+}
+
+// Unmarshal decodes the arguments into @v, which is either a []interface{} of
+// argument pointers, or the pointer of the only argument, like the wrapper
+// codecs unmarshal requests.
+func (r *RawArgs) Unmarshal(v interface{}) error {
+	params, ok := v.([]interface{})
+	if !ok {
+		params = []interface{}{v}
+	}
+	if len(params) != len(r.Data) {
+		return perrors.Errorf("request has %d args, but the method has %d", len(r.Data), len(params))
+	}
+	for idx, param := range params {
+		if err := r.Decode(idx, param); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Values decodes all arguments to basic types, map[string]interface{} and
+// hessian.Object values. The result is cached.
+func (r *RawArgs) Values() ([]interface{}, error) {
+	if r.values != nil {
+		return r.values, nil
+	}
+	values := make([]interface{}, len(r.Data))
+	for idx := range r.Data {
+		var v interface{}
+		if err := r.Decode(idx, &v); err != nil {
+			return nil, err
+		}
+		values[idx] = Normalize(v)
+	}
+	r.values = values
+	return values, nil
+}
@@ -0,0 +1,99 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generic
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.Nil(t, NewRequest([]string{"java.lang.String"}, []interface{}{"hello"}).Validate())
+	assert.NotNil(t, NewRequest([]string{"java.lang.String"}, nil).Validate())
+}
+
+func TestNormalize(t *testing.T) {
+	in := map[interface{}]interface{}{
+		"name": "laurence",
+		"tags": []interface{}{
+			map[interface{}]interface{}{"key": "value"},
+		},
+		"scores": map[interface{}]interface{}{int64(1): "one"},
+	}
+	out := Normalize(in)
+	assert.Equal(t, map[string]interface{}{
+		"name": "laurence",
+		"tags": []interface{}{
+			map[string]interface{}{"key": "value"},
+		},
+		"scores": map[interface{}]interface{}{int64(1): "one"},
+	}, out)
+	assert.Equal(t, "hello", Normalize("hello"))
+}
+
+func TestNormalizeCopies(t *testing.T) {
+	nested := map[interface{}]interface{}{"key": "value"}
+	list := []interface{}{nested}
+	in := map[string]interface{}{"list": list}
+
+	out := Normalize(in)
+	assert.Equal(t, map[string]interface{}{
+		"list": []interface{}{map[string]interface{}{"key": "value"}},
+	}, out)
+	// the input still holds the original values
+	assert.Equal(t, map[interface{}]interface{}{"key": "value"}, list[0])
+	assert.Equal(t, []interface{}{nested}, in["list"])
+}
+
+type stringUnmarshaler struct{}
+
+func (stringUnmarshaler) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*interface{})) = string(data)
+	return nil
+}
+
+func TestRawArgs(t *testing.T) {
+	args := &RawArgs{
+		Types: []string{"java.lang.String"},
+		Data:  [][]byte{[]byte("hello")},
+	}
+	assert.Equal(t, 1, args.Len())
+	var v interface{}
+	assert.NotNil(t, args.Decode(0, &v))
+
+	args.Codec = stringUnmarshaler{}
+	assert.NotNil(t, args.Decode(1, &v))
+	values, err := args.Values()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"hello"}, values)
+}
+
+func TestRawArgsUnmarshal(t *testing.T) {
+	args := &RawArgs{
+		Data:  [][]byte{[]byte("hello"), []byte("world")},
+		Codec: stringUnmarshaler{},
+	}
+	var hello, world interface{}
+	assert.Nil(t, args.Unmarshal([]interface{}{&hello, &world}))
+	assert.Equal(t, "hello", hello)
+	assert.Equal(t, "world", world)
+	assert.NotNil(t, args.Unmarshal(&hello))
+}
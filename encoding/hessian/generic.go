@@ -0,0 +1,436 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hessian
+
+import (
+	"io"
+	"reflect"
+)
+
+import (
+	hessian "github.com/apache/dubbo-go-hessian2"
+	perrors "github.com/pkg/errors"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/encoding/tools"
+)
+
+// ClassKey is the key of the java class name in the maps which DecodeGeneric
+// decodes objects of unregistered classes to.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+const ClassKey = "class"
+
+// DecodeGeneric decodes the hessian2 value @data with the POJO registry,
+// except that objects of java classes without a registered POJO decode to a
+// map[string]interface{} holding their java class name at ClassKey and their
+// fields by java field name, instead of failing with a *MissingClassError.
+// Objects of registered classes in such a value are assigned to their POJOs
+// by field name, and stay maps if their fields do not fit.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func DecodeGeneric(data []byte) (interface{}, error) {
+	if v, err := hessian.NewDecoder(data).Decode(); err == nil {
+		return resolveRefs(v, make(map[uintptr]bool)), nil
+	}
+	return newGenericDecoder(data).decode()
+}
+
+// DecodeGenericValues decodes the consecutive hessian2 values of @data, like
+// the body of a dubbo2 package, the way DecodeGeneric does. Later values may
+// refer to the classes, types and objects of the previous ones.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func DecodeGenericValues(data []byte) ([]interface{}, error) {
+	var values []interface{}
+	decoder := hessian.NewDecoder(data)
+	for {
+		v, err := decoder.Decode()
+		if err == io.EOF {
+			return values, nil
+		}
+		if err != nil {
+			break
+		}
+		values = append(values, resolveRefs(v, make(map[uintptr]bool)))
+	}
+
+	values = nil
+	generic := newGenericDecoder(data)
+	for generic.pos < len(data) {
+		v, err := generic.decode()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// resolveRefs replaces the reference holders left by the hessian2 decoder
+// in the lists and maps of @v with the values they refer to. @seen holds the
+// lists and maps already resolved, which values may refer to again.
+func resolveRefs(v interface{}, seen map[uintptr]bool) interface{} {
+	v, _ = hessian.EnsureInterface(v, nil)
+	switch v := v.(type) {
+	case []interface{}:
+		if len(v) == 0 || seen[reflect.ValueOf(v).Pointer()] {
+			return v
+		}
+		seen[reflect.ValueOf(v).Pointer()] = true
+		for i, e := range v {
+			v[i] = resolveRefs(e, seen)
+		}
+	case map[interface{}]interface{}:
+		if seen[reflect.ValueOf(v).Pointer()] {
+			return v
+		}
+		seen[reflect.ValueOf(v).Pointer()] = true
+		for k, e := range v {
+			v[k] = resolveRefs(e, seen)
+		}
+	}
+	return v
+}
+
+// genericClass is a class definition read by the genericDecoder.
+type genericClass struct {
+	javaName string
+	fields   []string
+	// pojo is the registered POJO type of the class, nil if there is none.
+	pojo reflect.Type
+}
+
+// pendingList stands for the variable length list at index of the refs of
+// the genericDecoder while it is decoded, as the slice is not final before.
+type pendingList struct {
+	index int
+}
+
+// genericDecoder decodes hessian2 values of the hessian2 types which refer to
+// class definitions and references itself, that is objects, lists, maps and
+// references, and leaves the other ones to the hessian2 decoder.
+type genericDecoder struct {
+	data []byte
+	// decoder buffers all of data, so that its position is known.
+	decoder *hessian.Decoder
+	pos     int
+	classes []*genericClass
+	refs    []interface{}
+	pending bool
+}
+
+func newGenericDecoder(data []byte) *genericDecoder {
+	return &genericDecoder{data: data, decoder: hessian.NewDecoderSize(data, len(data))}
+}
+
+// decode decodes the next value, the refs to variable length lists in it are
+// resolved once it is complete.
+func (d *genericDecoder) decode() (interface{}, error) {
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	if d.pending {
+		v = d.resolvePending(v, make(map[uintptr]bool))
+	}
+	return v, nil
+}
+
+// scalar decodes the next value with the hessian2 decoder.
+func (d *genericDecoder) scalar() (interface{}, error) {
+	v, err := d.decoder.Decode()
+	d.pos = len(d.data) - d.decoder.Buffered()
+	return v, err
+}
+
+func (d *genericDecoder) readInt() (int, error) {
+	v, err := d.scalar()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := v.(int32)
+	if !ok || n < 0 {
+		return 0, perrors.Errorf("hessian: expected a length or an index, got %v", v)
+	}
+	return int(n), nil
+}
+
+func (d *genericDecoder) readString() (string, error) {
+	v, err := d.scalar()
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", perrors.Errorf("hessian: expected a string, got %v", v)
+	}
+	return s, nil
+}
+
+// peek returns the tag of the next value.
+func (d *genericDecoder) peek() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return d.data[d.pos], nil
+}
+
+// skip skips the tag returned by peek.
+func (d *genericDecoder) skip() {
+	_, _ = d.decoder.ReadByte()
+	d.pos++
+}
+
+func (d *genericDecoder) value() (interface{}, error) {
+	tag, err := d.peek()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case tag == 'C':
+		if err = d.classDef(); err != nil {
+			return nil, err
+		}
+		return d.value()
+	case tag == 'O':
+		d.skip()
+		index, err := d.readInt()
+		if err != nil {
+			return nil, err
+		}
+		return d.object(index)
+	case tag >= 0x60 && tag <= 0x6f:
+		d.skip()
+		return d.object(int(tag - 0x60))
+	case tag == 'Q':
+		d.skip()
+		index, err := d.readInt()
+		if err != nil {
+			return nil, err
+		}
+		if index >= len(d.refs) {
+			return nil, perrors.Errorf("hessian: reference %d out of range", index)
+		}
+		if _, ok := d.refs[index].(*pendingList); ok {
+			d.pending = true
+		}
+		return d.refs[index], nil
+	case tag == 'U' || tag == 'V' || (tag >= 0x70 && tag <= 0x77):
+		d.skip()
+		// the element type of a typed list does not matter in generic maps
+		if _, err = d.scalar(); err != nil {
+			return nil, err
+		}
+		return d.list(tag)
+	case tag == 'W' || tag == 'X' || (tag >= 0x78 && tag <= 0x7f):
+		d.skip()
+		return d.list(tag)
+	case tag == 'M':
+		d.skip()
+		if _, err = d.scalar(); err != nil {
+			return nil, err
+		}
+		return d.hashMap()
+	case tag == 'H':
+		d.skip()
+		return d.hashMap()
+	}
+	return d.scalar()
+}
+
+// classDef reads the class definition 'C' string int string*.
+func (d *genericDecoder) classDef() error {
+	d.skip()
+	javaName, err := d.readString()
+	if err != nil {
+		return err
+	}
+	count, err := d.readInt()
+	if err != nil {
+		return err
+	}
+	if count > len(d.data)-d.pos {
+		return perrors.Errorf("hessian: class %s has %d fields in %d bytes", javaName, count, len(d.data)-d.pos)
+	}
+	class := &genericClass{javaName: javaName, fields: make([]string, count)}
+	for i := range class.fields {
+		if class.fields[i], err = d.readString(); err != nil {
+			return err
+		}
+	}
+	if pojo, _ := decodeProbe(javaName); pojo != nil {
+		class.pojo = reflect.TypeOf(pojo)
+	}
+	d.classes = append(d.classes, class)
+	return nil
+}
+
+// object decodes the fields of an object of the class at @index.
+func (d *genericDecoder) object(index int) (interface{}, error) {
+	if index >= len(d.classes) {
+		return nil, perrors.Errorf("hessian: class definition %d out of range", index)
+	}
+	class := d.classes[index]
+	m := make(map[string]interface{}, len(class.fields)+1)
+	m[ClassKey] = class.javaName
+	ref := len(d.refs)
+	d.refs = append(d.refs, m)
+	for _, field := range class.fields {
+		v, err := d.value()
+		if err != nil {
+			return nil, perrors.WithMessagef(err, "hessian: field %s of class %s", field, class.javaName)
+		}
+		m[field] = v
+	}
+	if class.pojo == nil {
+		return m, nil
+	}
+
+	pojo := reflect.New(class.pojo)
+	if err := tools.ReflectResponse(m, pojo.Interface()); err != nil {
+		return m, nil
+	}
+	d.refs[ref] = pojo.Elem().Interface()
+	return d.refs[ref], nil
+}
+
+// list decodes the elements of the list with @tag, after its type if typed.
+func (d *genericDecoder) list(tag byte) (interface{}, error) {
+	var length int
+	switch {
+	case tag == 'U' || tag == 'W':
+		return d.variableList()
+	case tag >= 0x70 && tag <= 0x77:
+		length = int(tag - 0x70)
+	case tag >= 0x78:
+		length = int(tag - 0x78)
+	default:
+		var err error
+		if length, err = d.readInt(); err != nil {
+			return nil, err
+		}
+	}
+	if length > len(d.data)-d.pos {
+		return nil, perrors.Errorf("hessian: list of %d elements in %d bytes", length, len(d.data)-d.pos)
+	}
+	list := make([]interface{}, length)
+	d.refs = append(d.refs, list)
+	for i := range list {
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+// variableList decodes the elements of a variable length list up to 'Z'.
+// Refs to the list in its elements are resolved by decode.
+func (d *genericDecoder) variableList() (interface{}, error) {
+	ref := len(d.refs)
+	d.refs = append(d.refs, &pendingList{index: ref})
+	list := []interface{}{}
+	for {
+		tag, err := d.peek()
+		if err != nil {
+			return nil, err
+		}
+		if tag == 'Z' {
+			d.skip()
+			break
+		}
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	d.refs[ref] = list
+	return list, nil
+}
+
+// hashMap decodes the entries of a map up to 'Z', after its type if typed.
+func (d *genericDecoder) hashMap() (interface{}, error) {
+	m := make(map[interface{}]interface{})
+	d.refs = append(d.refs, m)
+	for {
+		tag, err := d.peek()
+		if err != nil {
+			return nil, err
+		}
+		if tag == 'Z' {
+			d.skip()
+			return m, nil
+		}
+		k, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		if k != nil && !reflect.TypeOf(k).Comparable() {
+			return nil, perrors.Errorf("hessian: map key of type %T", k)
+		}
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+}
+
+// resolvePending replaces the pendingLists in the lists and maps of @v with
+// the lists they stand for. @seen holds the lists and maps already resolved.
+func (d *genericDecoder) resolvePending(v interface{}, seen map[uintptr]bool) interface{} {
+	if p, ok := v.(*pendingList); ok {
+		return d.refs[p.index]
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		if value.Len() == 0 || seen[value.Pointer()] {
+			return v
+		}
+		seen[value.Pointer()] = true
+	}
+	switch v := v.(type) {
+	case []interface{}:
+		for i, e := range v {
+			v[i] = d.resolvePending(e, seen)
+		}
+	case map[interface{}]interface{}:
+		for k, e := range v {
+			v[k] = d.resolvePending(e, seen)
+		}
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = d.resolvePending(e, seen)
+		}
+	}
+	return v
+}
@@ -11,11 +11,14 @@ import (
 )
 
 func init() {
-	encoding.RegisterCodec(encoding.NewPBWrapperTwoWayCodec("hessian2", NewHessianCodec(), raw_proto.NewProtobufCodec()))
+	encoding.RegisterCodec(encoding.NewPooledPBWrapperTwoWayCodec("hessian2", NewHessianCodec(), raw_proto.NewProtobufCodec()))
 }
 
 // HessianCodeC is the hessian impl of Codec interface
-type HessianCodeC struct{}
+type HessianCodeC struct {
+	// generic decodes objects of unregistered classes to maps, see DecodeGeneric.
+	generic bool
+}
 
 func (h *HessianCodeC) Name() string {
 	return "raw_hessian2"
@@ -30,10 +33,21 @@ func (h *HessianCodeC) Marshal(v interface{}) ([]byte, error) {
 	return encoder.Buffer(), nil
 }
 
-// Unmarshal deserialize @data to interface
+// Unmarshal deserialize @data to interface. Objects of unregistered java
+// classes fail with a *MissingClassError, unless the codec is generic.
 func (h *HessianCodeC) Unmarshal(data []byte, v interface{}) error {
-	decoder := hessian.NewDecoder(data)
-	val, err := decoder.Decode()
+	var (
+		val interface{}
+		err error
+	)
+	if h.generic {
+		val, err = DecodeGeneric(data)
+	} else {
+		decoder := hessian.NewDecoder(data)
+		if val, err = decoder.Decode(); err != nil {
+			return missingClassError(data, decoder, err)
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -44,3 +58,29 @@ func (h *HessianCodeC) Unmarshal(data []byte, v interface{}) error {
 func NewHessianCodec() encoding.Codec {
 	return &HessianCodeC{}
 }
+
+// NewGenericHessianCodec returns a HessianCodeC which decodes values with
+// DecodeGeneric, so that objects of java classes without registered POJOs
+// decode to maps holding their class and fields instead of failing the call
+// with a *MissingClassError.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func NewGenericHessianCodec() encoding.Codec {
+	return &HessianCodeC{generic: true}
+}
+
+// GenericCodec returns the hessian2 wrapper codec decoding generically, to be
+// used with grpc.ForceCodec, e.g.
+//  cc.GenericInvoke(ctx, interfaceName, methodName, argTypes, args,
+//      grpc.ForceCodec(hessian.GenericCodec()))
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func GenericCodec() encoding.TwoWayCodec {
+	return encoding.NewPBWrapperTwoWayCodec("hessian2", NewGenericHessianCodec(), raw_proto.NewProtobufCodec())
+}
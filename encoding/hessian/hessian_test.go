@@ -0,0 +1,218 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hessian
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	hessian "github.com/apache/dubbo-go-hessian2"
+
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/encoding/tools"
+)
+
+type Address struct {
+	City string
+}
+
+func (Address) JavaClassName() string {
+	return "org.apache.dubbo.Address"
+}
+
+type User struct {
+	Name    string
+	Age     int32
+	Tags    []string
+	Address *Address
+}
+
+func (User) JavaClassName() string {
+	return "org.apache.dubbo.User"
+}
+
+func newUser() *User {
+	return &User{Name: "laurence", Age: 28, Tags: []string{"a", "b"}, Address: &Address{City: "hangzhou"}}
+}
+
+// encodeUnregistered encodes @v with its POJOs registered, and unregisters
+// them afterwards like a provider which knows the classes the consumer does
+// not.
+func encodeUnregistered(t *testing.T, v interface{}, pojos ...hessian.POJO) []byte {
+	for _, pojo := range pojos {
+		RegisterPOJO(pojo)
+	}
+	data, err := NewHessianCodec().Marshal(v)
+	assert.Nil(t, err)
+	UnregisterPOJOs(pojos...)
+	return data
+}
+
+func TestMissingClassError(t *testing.T) {
+	data := encodeUnregistered(t, newUser(), &User{}, &Address{})
+	assert.False(t, IsRegistered("org.apache.dubbo.User"))
+
+	var reply interface{}
+	err := NewHessianCodec().Unmarshal(data, &reply)
+	missing, ok := err.(*MissingClassError)
+	assert.True(t, ok, "%v", err)
+	assert.Equal(t, "org.apache.dubbo.User", missing.JavaClassName)
+	assert.Contains(t, err.Error(), "org.apache.dubbo.User")
+
+	// registering at runtime makes the same payload decodable
+	RegisterPOJO(&User{})
+	RegisterPOJO(&Address{})
+	defer UnregisterPOJOs(&User{}, &Address{})
+	assert.True(t, IsRegistered("org.apache.dubbo.User"))
+	user := &User{}
+	assert.Nil(t, NewHessianCodec().Unmarshal(data, user))
+	assert.Equal(t, newUser(), user)
+}
+
+func TestGenericCodec(t *testing.T) {
+	data := encodeUnregistered(t, []interface{}{"before", newUser(), "after"}, &User{}, &Address{})
+
+	// objects of unregistered classes decode to maps instead of failing
+	var reply interface{}
+	assert.Nil(t, NewGenericHessianCodec().Unmarshal(data, &reply))
+	assert.Equal(t, []interface{}{"before", map[string]interface{}{
+		ClassKey: "org.apache.dubbo.User",
+		"name":   "laurence",
+		"age":    int32(28),
+		"tags":   []interface{}{"a", "b"},
+		"address": map[string]interface{}{
+			ClassKey: "org.apache.dubbo.Address",
+			"city":   "hangzhou",
+		},
+	}, "after"}, reply)
+
+	// which can still be assigned to typed replies
+	var users []interface{}
+	assert.Nil(t, NewGenericHessianCodec().Unmarshal(data, &users))
+	user := &User{}
+	assert.Nil(t, tools.ReflectResponse(users[1], user))
+	assert.Equal(t, newUser(), user)
+
+	// registered classes decode as usual, also next to unregistered ones
+	RegisterPOJO(&Address{})
+	defer UnregisterPOJOs(&Address{})
+	reply = nil
+	assert.Nil(t, NewGenericHessianCodec().Unmarshal(data, &reply))
+	assert.Equal(t, &Address{City: "hangzhou"}, reply.([]interface{})[1].(map[string]interface{})["address"])
+
+	RegisterPOJO(&User{})
+	defer UnregisterPOJOs(&User{})
+	reply = nil
+	assert.Nil(t, NewGenericHessianCodec().Unmarshal(data, &reply))
+	assert.Equal(t, []interface{}{"before", newUser(), "after"}, reply)
+}
+
+func TestDecodeGenericObjectRef(t *testing.T) {
+	// a variable length list holding an unregistered object which refers to
+	// the list: W C "Node" 1 "list" 0x60 Q 0 Z
+	data := []byte{'W', 'C', 0x04, 'N', 'o', 'd', 'e', 0x91, 0x04, 'l', 'i', 's', 't', 0x60, 'Q', 0x90, 'Z'}
+	v, err := DecodeGeneric(data)
+	assert.Nil(t, err)
+	list := v.([]interface{})
+	assert.Len(t, list, 1)
+	node := list[0].(map[string]interface{})
+	assert.Equal(t, "Node", node[ClassKey])
+	assert.Equal(t, reflect.ValueOf(list).Pointer(), reflect.ValueOf(node["list"]).Pointer())
+
+	// objects referred to twice decode to the same map
+	address := &Address{City: "hangzhou"}
+	data = encodeUnregistered(t, []interface{}{address, address}, &Address{})
+	v, err = DecodeGeneric(data)
+	assert.Nil(t, err)
+	list = v.([]interface{})
+	assert.Equal(t, map[string]interface{}{ClassKey: "org.apache.dubbo.Address", "city": "hangzhou"}, list[0])
+	assert.Equal(t, reflect.ValueOf(list[0]).Pointer(), reflect.ValueOf(list[1]).Pointer())
+
+	_, err = DecodeGeneric([]byte{'C', 0x04, 'N', 'o', 'd', 'e', 0x91, 0x04, 'l', 'i', 's', 't', 0x61})
+	assert.NotNil(t, err)
+	_, err = DecodeGeneric([]byte{'C', 0x04, 'N', 'o', 'd', 'e', 0x91, 0x04, 'l', 'i', 's', 't', 0x60, 'Q', 0x91})
+	assert.NotNil(t, err)
+}
+
+func TestDecodeGeneric(t *testing.T) {
+	date := time.Unix(1600000000, 123000000)
+	long := strings.Repeat("hessian 中文 😀 ", 5000)
+	cases := []interface{}{
+		nil, true, false,
+		int32(0), int32(-16), int32(47), int32(-2048), int32(2047), int32(-262144), int32(262143), int32(1 << 30),
+		int64(0), int64(-8), int64(15), int64(-2048), int64(2047), int64(-262144), int64(262143), int64(1 << 30), int64(1 << 40),
+		float64(0), float64(1), float64(-128), float64(32767), 12.25, 3.14159265358979,
+		"", "hello", "中文 😀", strings.Repeat("x", 1000), long,
+		[]byte{}, []byte{1, 2, 3}, make([]byte, 100000),
+		date,
+		[]interface{}{int32(1), "two", []interface{}{3.5}},
+		map[interface{}]interface{}{"key": "value", int32(1): []interface{}{"one"}},
+	}
+	for _, c := range cases {
+		encoder := hessian.NewEncoder()
+		assert.Nil(t, encoder.Encode(c))
+		v, err := DecodeGeneric(encoder.Buffer())
+		assert.Nil(t, err, "%v", c)
+		if tm, ok := v.(time.Time); ok {
+			assert.True(t, date.Equal(tm))
+			continue
+		}
+		assert.Equal(t, c, v)
+	}
+
+	_, err := DecodeGeneric([]byte{'S', 0, 5, 'a'})
+	assert.NotNil(t, err)
+	_, err = DecodeGeneric([]byte{0x60})
+	assert.NotNil(t, err)
+}
+
+func TestDecodeGenericRef(t *testing.T) {
+	encoder := hessian.NewEncoder()
+	shared := map[interface{}]interface{}{"k": "v"}
+	sharedList := []interface{}{"a", int32(1)}
+	assert.Nil(t, encoder.Encode([]interface{}{shared, shared, sharedList, sharedList}))
+	v, err := DecodeGeneric(encoder.Buffer())
+	assert.Nil(t, err)
+	list := v.([]interface{})
+	assert.Equal(t, shared, list[0])
+	assert.Equal(t, shared, list[1])
+	assert.Equal(t, sharedList, list[2])
+	assert.Equal(t, sharedList, list[3])
+}
+
+func TestDecodeGenericValues(t *testing.T) {
+	encoder := hessian.NewEncoder()
+	assert.Nil(t, encoder.Encode(hessian.RESPONSE_VALUE_WITH_ATTACHMENTS))
+	assert.Nil(t, encoder.Encode([]interface{}{"a", int32(1)}))
+	assert.Nil(t, encoder.Encode(map[string]string{"dubbo": "2.0.2"}))
+
+	values, err := DecodeGenericValues(encoder.Buffer())
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{
+		hessian.RESPONSE_VALUE_WITH_ATTACHMENTS,
+		[]interface{}{"a", int32(1)},
+		map[interface{}]interface{}{"dubbo": "2.0.2"},
+	}, values)
+}
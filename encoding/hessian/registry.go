@@ -0,0 +1,151 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hessian
+
+import (
+	"fmt"
+	"reflect"
+)
+
+import (
+	hessian "github.com/apache/dubbo-go-hessian2"
+)
+
+// RegisterPOJO registers @o at runtime, so that objects of its java class
+// decode to @o's type. It can be called any time before the first call
+// returning the class, and is a no-op if the class is already registered.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func RegisterPOJO(o hessian.POJO) {
+	hessian.RegisterPOJO(o)
+}
+
+// RegisterPOJOMapping is RegisterPOJO for types which do not implement
+// hessian.POJO, @javaClassName is the java class name of @o.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func RegisterPOJOMapping(javaClassName string, o interface{}) {
+	hessian.RegisterPOJOMapping(javaClassName, o)
+}
+
+// UnregisterPOJOs unregisters @os, objects of their java classes fail to
+// decode with a *MissingClassError afterwards, unless decoded generically.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func UnregisterPOJOs(os ...hessian.POJO) {
+	hessian.UnRegisterPOJOs(os...)
+}
+
+// IsRegistered reports whether objects of @javaClassName can be decoded to a
+// go type, because a POJO is registered for it or it is a known exception.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func IsRegistered(javaClassName string) bool {
+	_, registered := decodeProbe(javaClassName)
+	return registered
+}
+
+// decodeProbe decodes an object of @javaClassName without fields, which fails
+// only if the registry has no go type for it. It returns the decoded POJO, or
+// nil if a custom serializer failed to decode the empty object.
+func decodeProbe(javaClassName string) (pojo interface{}, registered bool) {
+	// custom serializers may panic on the empty object, after the lookup
+	defer func() {
+		if recover() != nil {
+			pojo, registered = nil, true
+		}
+	}()
+	encoder := hessian.NewEncoder()
+	if err := encoder.Encode(javaClassName); err != nil {
+		return nil, false
+	}
+	probe := append([]byte{'C'}, encoder.Buffer()...)
+	probe = append(probe, 0x90, 0x60)
+	pojo, err := hessian.NewDecoder(probe).Decode()
+	if err != nil {
+		return nil, false
+	}
+	return pojo, true
+}
+
+// MissingClassError is returned when a hessian2 value holds an object whose
+// java class is not registered, see RegisterPOJO and DecodeGeneric.
+//
+// Experimental
+//
+// Notice: This type is EXPERIMENTAL and may be changed or removed in a
+// later release.
+type MissingClassError struct {
+	// JavaClassName is the java class name of the object.
+	JavaClassName string
+	// Err is the error of the hessian2 decoder.
+	Err error
+}
+
+func (e *MissingClassError) Error() string {
+	return fmt.Sprintf("hessian: java class %s is not registered, register a POJO for it or decode it generically", e.JavaClassName)
+}
+
+// Unwrap returns the error of the hessian2 decoder.
+func (e *MissingClassError) Unwrap() error {
+	return e.Err
+}
+
+// missingClassError returns a *MissingClassError if @decoder failed to decode
+// @data with @err because of a java class without a registered POJO, which is
+// the case if decoding @data again skipping such classes succeeds. The class
+// is the first one defined by @decoder which is not registered.
+func missingClassError(data []byte, decoder *hessian.Decoder, err error) error {
+	if _, skipErr := hessian.NewDecoderWithSkip(data).Decode(); skipErr != nil {
+		return err
+	}
+	for _, name := range definedClasses(decoder) {
+		if !IsRegistered(name) {
+			return &MissingClassError{JavaClassName: name, Err: err}
+		}
+	}
+	return err
+}
+
+// definedClasses returns the java class names of the class definitions read
+// by @decoder, which the hessian2 decoder does not export.
+func definedClasses(decoder *hessian.Decoder) []string {
+	classes := reflect.ValueOf(decoder).Elem().FieldByName("classInfoList")
+	if classes.Kind() != reflect.Slice {
+		return nil
+	}
+	names := make([]string, 0, classes.Len())
+	for i := 0; i < classes.Len(); i++ {
+		if name := reflect.Indirect(classes.Index(i)).FieldByName("javaName"); name.Kind() == reflect.String {
+			names = append(names, name.String())
+		}
+	}
+	return names
+}
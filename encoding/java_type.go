@@ -24,13 +24,89 @@ import (
 
 import (
 	hessian "github.com/apache/dubbo-go-hessian2"
+	"github.com/apache/dubbo-go-hessian2/java8_time"
+
+	big "github.com/dubbogo/gost/math/big"
 )
 
+var registeredJavaTypes = map[reflect.Type]string{
+	// boxed primitives
+	reflect.TypeOf((*bool)(nil)):    "java.lang.Boolean",
+	reflect.TypeOf((*int8)(nil)):    "java.lang.Byte",
+	reflect.TypeOf((*int16)(nil)):   "java.lang.Short",
+	reflect.TypeOf((*uint16)(nil)):  "java.lang.Character",
+	reflect.TypeOf((*int32)(nil)):   "java.lang.Integer",
+	reflect.TypeOf((*int)(nil)):     "java.lang.Long",
+	reflect.TypeOf((*int64)(nil)):   "java.lang.Long",
+	reflect.TypeOf((*float32)(nil)): "java.lang.Float",
+	reflect.TypeOf((*float64)(nil)): "java.lang.Double",
+	reflect.TypeOf((*string)(nil)):  "java.lang.String",
+	// java.math and java.time
+	reflect.TypeOf(big.Decimal{}):                  "java.math.BigDecimal",
+	reflect.TypeOf(&big.Decimal{}):                 "java.math.BigDecimal",
+	reflect.TypeOf(big.Integer{}):                  "java.math.BigInteger",
+	reflect.TypeOf(&big.Integer{}):                 "java.math.BigInteger",
+	reflect.TypeOf(java8_time.LocalDateTime{}):     "java.time.LocalDateTime",
+	reflect.TypeOf(&java8_time.LocalDateTime{}):    "java.time.LocalDateTime",
+	reflect.TypeOf(java8_time.LocalDate{}):         "java.time.LocalDate",
+	reflect.TypeOf(&java8_time.LocalDate{}):        "java.time.LocalDate",
+	reflect.TypeOf(java8_time.LocalTime{}):         "java.time.LocalTime",
+	reflect.TypeOf(&java8_time.LocalTime{}):        "java.time.LocalTime",
+	reflect.TypeOf([]interface{}{}):                "java.util.List",
+	reflect.TypeOf(map[string]interface{}{}):       "java.util.Map",
+	reflect.TypeOf(map[interface{}]interface{}{}):  "java.util.Map",
+	reflect.TypeOf(&map[string]interface{}{}):      "java.util.Map",
+	reflect.TypeOf(&map[interface{}]interface{}{}): "java.util.Map",
+}
+
+// RegisterJavaType registers @javaName as the java class name of arguments
+// of @goType, taking precedence over the built-in mapping of GetArgType.
+// Generics are erased in java signatures, so collections should be registered
+// as e.g. "java.util.List" or "java.util.Map".
+//
+// NOTE: this function must only be called during initialization time (i.e. in
+// an init() function), and is not thread-safe.
+func RegisterJavaType(goType reflect.Type, javaName string) {
+	if goType == nil {
+		panic("cannot register java type of a nil go type")
+	}
+	registeredJavaTypes[goType] = javaName
+}
+
+// JavaTypedArg is an argument whose java class name is given explicitly,
+// overriding both GetArgType and RegisterJavaType for a single call.
+type JavaTypedArg struct {
+	Value    interface{}
+	JavaType string
+}
+
+// WithJavaType wraps argument @v so that it is sent with java class name @javaType.
+func WithJavaType(v interface{}, javaType string) *JavaTypedArg {
+	return &JavaTypedArg{
+		Value:    v,
+		JavaType: javaType,
+	}
+}
+
+// unwrapArg returns the value of @v to be serialized.
+func unwrapArg(v interface{}) interface{} {
+	if typed, ok := v.(*JavaTypedArg); ok {
+		return typed.Value
+	}
+	return v
+}
+
 // GetArgType is copied from hessian, to get java class type of interface
 func GetArgType(v interface{}) string {
 	if v == nil {
 		return "V"
 	}
+	if typed, ok := v.(*JavaTypedArg); ok {
+		return typed.JavaType
+	}
+	if javaName, ok := registeredJavaTypes[reflect.TypeOf(v)]; ok {
+		return javaName
+	}
 
 	switch v.(type) {
 	// Serialized tags for base types
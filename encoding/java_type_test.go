@@ -0,0 +1,63 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package encoding
+
+import (
+	"reflect"
+	"testing"
+)
+
+import (
+	"github.com/apache/dubbo-go-hessian2/java8_time"
+
+	big "github.com/dubbogo/gost/math/big"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	Name string
+}
+
+func TestGetArgType(t *testing.T) {
+	i32 := int32(1)
+	b := true
+	assert.Equal(t, "int", GetArgType(int32(1)))
+	assert.Equal(t, "java.lang.Integer", GetArgType(&i32))
+	assert.Equal(t, "java.lang.Boolean", GetArgType(&b))
+	assert.Equal(t, "java.math.BigDecimal", GetArgType(&big.Decimal{}))
+	assert.Equal(t, "java.math.BigInteger", GetArgType(&big.Integer{}))
+	assert.Equal(t, "java.time.LocalDateTime", GetArgType(&java8_time.LocalDateTime{}))
+	assert.Equal(t, "java.util.List", GetArgType([]interface{}{"a"}))
+	assert.Equal(t, "java.util.Map", GetArgType(map[string]interface{}{}))
+}
+
+func TestRegisterJavaType(t *testing.T) {
+	assert.Equal(t, "[Ljava.lang.Object;", GetArgType([]user{}))
+
+	RegisterJavaType(reflect.TypeOf([]user{}), "java.util.List")
+	defer delete(registeredJavaTypes, reflect.TypeOf([]user{}))
+	assert.Equal(t, "java.util.List", GetArgType([]user{}))
+}
+
+func TestWithJavaType(t *testing.T) {
+	arg := WithJavaType(int64(1), "java.lang.Long")
+	assert.Equal(t, "java.lang.Long", GetArgType(arg))
+	assert.Equal(t, int64(1), unwrapArg(arg))
+	assert.Equal(t, "hello", unwrapArg("hello"))
+}
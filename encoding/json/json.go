@@ -0,0 +1,116 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package json defines the json codec used by triple wrapper traffic.
+// Importing this package will register the codecs.
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/encoding"
+	"github.com/dubbogo/grpc-go/encoding/raw_proto"
+)
+
+// Name is the name registered for the json codec.
+const Name = "json"
+
+// MessageName is the name of JSONCodec, which encodes the single messages
+// wrapped by the json codec, as raw_hessian2 does for hessian2.
+const MessageName = "raw_json_message"
+
+func init() {
+	encoding.RegisterCodec(encoding.NewPooledPBWrapperTwoWayCodec(Name, NewJSONCodec(), raw_proto.NewProtobufCodec()))
+	encoding.RegisterCodec(NewRawJSONTwoWayCodec())
+}
+
+// JSONCodec is the json impl of Codec interface
+type JSONCodec struct {
+	protoJSON bool
+}
+
+func (j *JSONCodec) Name() string {
+	return MessageName
+}
+
+// Marshal serialize interface @v to bytes
+func (j *JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := j.protoMessage(v); ok {
+		return protojson.Marshal(proto.MessageV2(m))
+	}
+	return json.Marshal(v)
+}
+
+// MarshalTo serialize interface @v into @buf
+func (j *JSONCodec) MarshalTo(buf *bytes.Buffer, v interface{}) error {
+	if m, ok := j.protoMessage(v); ok {
+		data, err := protojson.Marshal(proto.MessageV2(m))
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		return nil
+	}
+	start := buf.Len()
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		buf.Truncate(start)
+		return err
+	}
+	// drop the newline written by Encode, to match json.Marshal
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// Unmarshal deserialize @data to interface
+func (j *JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := j.protoMessage(v); ok {
+		return protojson.Unmarshal(data, proto.MessageV2(m))
+	}
+	return json.Unmarshal(data, v)
+}
+
+// protoMessage returns @v as a proto.Message if protojson mode is enabled
+// and @v is a generated protobuf message.
+func (j *JSONCodec) protoMessage(v interface{}) (proto.Message, bool) {
+	if !j.protoJSON {
+		return nil, false
+	}
+	m, ok := v.(proto.Message)
+	return m, ok
+}
+
+// NewJSONCodec returns new JSONCodec. proto.Message arguments are encoded
+// with protojson so that well-known types and field names follow the
+// canonical protobuf JSON mapping, other values use encoding/json.
+func NewJSONCodec() encoding.Codec {
+	return &JSONCodec{protoJSON: true}
+}
+
+// NewPlainJSONCodec returns new JSONCodec which always uses encoding/json,
+// even for proto.Message arguments.
+func NewPlainJSONCodec() encoding.Codec {
+	return &JSONCodec{}
+}
@@ -0,0 +1,108 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package json
+
+import (
+	"bytes"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/encoding"
+	"github.com/dubbogo/grpc-go/test/codec_perf"
+)
+
+type user struct {
+	Name string `json:"name"`
+	Age  int32  `json:"age"`
+}
+
+func TestRegistered(t *testing.T) {
+	codec := encoding.GetCodec(Name)
+	assert.NotNil(t, codec)
+	assert.Equal(t, Name, codec.Name())
+}
+
+func TestCodecNames(t *testing.T) {
+	names := map[string]bool{}
+	for _, name := range []string{Name, RawName, NewJSONCodec().Name(), NewPlainJSONCodec().Name()} {
+		names[name] = true
+	}
+	// the plain codec shares the name of JSONCodec
+	assert.Len(t, names, 3)
+	assert.Equal(t, MessageName, NewJSONCodec().Name())
+}
+
+func TestWrapperRequest(t *testing.T) {
+	codec := encoding.GetCodec(Name)
+	data, err := codec.MarshalRequest([]interface{}{"hello", &user{Name: "laurence", Age: 18}})
+	assert.Nil(t, err)
+
+	var greeting string
+	u := &user{}
+	assert.Nil(t, codec.UnmarshalRequest(data, []interface{}{&greeting, u}))
+	assert.Equal(t, "hello", greeting)
+	assert.Equal(t, &user{Name: "laurence", Age: 18}, u)
+}
+
+func TestWrapperResponse(t *testing.T) {
+	codec := encoding.GetCodec(Name)
+	data, err := codec.MarshalResponse(map[string]interface{}{"name": "laurence"})
+	assert.Nil(t, err)
+
+	rsp := make(map[string]interface{})
+	assert.Nil(t, codec.UnmarshalResponse(data, &rsp))
+	assert.Equal(t, "laurence", rsp["name"])
+}
+
+func TestMarshalTo(t *testing.T) {
+	codec := NewJSONCodec().(encoding.BufferedCodec)
+	u := &user{Name: "<laurence>", Age: 18}
+	want, err := codec.Marshal(u)
+	assert.Nil(t, err)
+
+	buf := bytes.NewBufferString("prefix")
+	assert.Nil(t, codec.MarshalTo(buf, u))
+	assert.Equal(t, "prefix"+string(want), buf.String())
+}
+
+func TestProtoJSON(t *testing.T) {
+	codec := NewJSONCodec()
+	data, err := codec.Marshal(&codec_perf.Buffer{Body: []byte("body")})
+	assert.Nil(t, err)
+	// protojson encodes bytes fields as base64 strings
+	assert.Contains(t, string(data), `"Ym9keQ=="`)
+
+	out := &codec_perf.Buffer{}
+	assert.Nil(t, codec.Unmarshal(data, out))
+	assert.Equal(t, []byte("body"), out.GetBody())
+}
+
+func TestWrapperStreamMessage(t *testing.T) {
+	codec := encoding.GetCodec(Name)
+	data, err := codec.MarshalRequest(&user{Name: "laurence", Age: 18})
+	assert.Nil(t, err)
+
+	u := &user{}
+	assert.Nil(t, codec.UnmarshalRequest(data, u))
+	assert.Equal(t, &user{Name: "laurence", Age: 18}, u)
+}
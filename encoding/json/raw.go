@@ -0,0 +1,154 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package json
+
+import (
+	"encoding/json"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/encoding"
+	"github.com/dubbogo/grpc-go/encoding/generic"
+)
+
+// RawName is the name registered for the raw json codec. Its messages are
+// plain json rather than triple wrappers, it is used by the HTTP/1.1 JSON
+// gateway of Server.ServeHTTP.
+const RawName = "raw_json"
+
+// RawJSONTwoWayCodec is the TwoWayCodec of plain json messages. An argument
+// list is encoded as a json array of the arguments, a list of a single
+// argument may also be decoded from the argument itself.
+type RawJSONTwoWayCodec struct {
+	codec encoding.Codec
+}
+
+// NewRawJSONTwoWayCodec returns new RawJSONTwoWayCodec.
+func NewRawJSONTwoWayCodec() encoding.TwoWayCodec {
+	return &RawJSONTwoWayCodec{
+		codec: NewJSONCodec(),
+	}
+}
+
+func (h *RawJSONTwoWayCodec) Name() string {
+	return RawName
+}
+
+// MarshalRequest marshal interface @v to []byte
+// @v is either a []interface{} argument list, a *generic.Request or a
+// single message.
+func (h *RawJSONTwoWayCodec) MarshalRequest(v interface{}) ([]byte, error) {
+	var args []interface{}
+	switch req := v.(type) {
+	case *generic.Request:
+		args = req.Args
+	case []interface{}:
+		args = req
+	default:
+		return h.codec.Marshal(v)
+	}
+	rawArgs := make([]json.RawMessage, 0, len(args))
+	for _, arg := range args {
+		data, err := h.codec.Marshal(arg)
+		if err != nil {
+			return nil, err
+		}
+		rawArgs = append(rawArgs, data)
+	}
+	return json.Marshal(rawArgs)
+}
+
+// UnmarshalRequest unmarshal bytes @data to interface
+// @v is either a []interface{} of argument pointers, a *generic.RawArgs
+// or a single message pointer.
+func (h *RawJSONTwoWayCodec) UnmarshalRequest(data []byte, v interface{}) error {
+	switch req := v.(type) {
+	case *generic.RawArgs:
+		args, err := h.splitArgs(data)
+		if err != nil {
+			return err
+		}
+		req.SerializeType = Name
+		req.Types = nil
+		req.Data = args
+		req.Codec = h.codec
+		return nil
+	case []interface{}:
+		if len(req) == 1 {
+			return h.unmarshalArg(data, req[0])
+		}
+		args, err := h.splitArgs(data)
+		if err != nil {
+			return err
+		}
+		if len(args) != len(req) {
+			return perrors.Errorf("error ,request params len is %d, but exported method has %d", len(args), len(req))
+		}
+		for idx, arg := range args {
+			if err := h.codec.Unmarshal(arg, req[idx]); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return h.codec.Unmarshal(data, v)
+	}
+}
+
+// unmarshalArg unmarshals the only argument @v of a method from @data, which
+// is either a json array of the argument or the argument itself. An array of
+// one element is read as the former if the element fits @v, e.g. [[1,2]] for
+// a list parameter, and as the argument itself otherwise, e.g. [1,2] or [1].
+func (h *RawJSONTwoWayCodec) unmarshalArg(data []byte, v interface{}) error {
+	if args, err := h.splitArgs(data); err == nil && len(args) == 1 {
+		if err := h.codec.Unmarshal(args[0], v); err == nil {
+			return nil
+		}
+	}
+	return h.codec.Unmarshal(data, v)
+}
+
+// splitArgs splits the json array @data into its elements.
+func (h *RawJSONTwoWayCodec) splitArgs(data []byte) ([][]byte, error) {
+	var rawArgs []json.RawMessage
+	if err := json.Unmarshal(data, &rawArgs); err != nil {
+		return nil, perrors.Errorf("request arguments must be a json array: %v", err)
+	}
+	args := make([][]byte, 0, len(rawArgs))
+	for _, arg := range rawArgs {
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// MarshalResponse marshal interface @v to []byte
+func (h *RawJSONTwoWayCodec) MarshalResponse(v interface{}) ([]byte, error) {
+	return h.codec.Marshal(v)
+}
+
+// UnmarshalResponse unmarshal bytes @data to interface
+func (h *RawJSONTwoWayCodec) UnmarshalResponse(data []byte, v interface{}) error {
+	if v == nil { // empty response
+		return nil
+	}
+	return h.codec.Unmarshal(data, v)
+}
@@ -0,0 +1,98 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package json
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/encoding"
+	"github.com/dubbogo/grpc-go/encoding/generic"
+)
+
+func TestRawRegistered(t *testing.T) {
+	codec := encoding.GetCodec(RawName)
+	assert.NotNil(t, codec)
+	assert.Equal(t, RawName, codec.Name())
+}
+
+func TestRawRequest(t *testing.T) {
+	codec := NewRawJSONTwoWayCodec()
+	data, err := codec.MarshalRequest([]interface{}{"hello", &user{Name: "laurence", Age: 18}})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `["hello",{"name":"laurence","age":18}]`, string(data))
+
+	var greeting string
+	u := &user{}
+	assert.Nil(t, codec.UnmarshalRequest(data, []interface{}{&greeting, u}))
+	assert.Equal(t, "hello", greeting)
+	assert.Equal(t, &user{Name: "laurence", Age: 18}, u)
+
+	// a single argument is sent as is
+	u = &user{}
+	assert.Nil(t, codec.UnmarshalRequest([]byte(`{"name":"laurence"}`), []interface{}{u}))
+	assert.Equal(t, "laurence", u.Name)
+
+	assert.NotNil(t, codec.UnmarshalRequest([]byte(`["hello"]`), []interface{}{&greeting, u}))
+}
+
+func TestRawRequestListArg(t *testing.T) {
+	codec := NewRawJSONTwoWayCodec()
+	data, err := codec.MarshalRequest([]interface{}{[]int{1}})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `[[1]]`, string(data))
+
+	// the only parameter is a list, sent in an argument array or as is
+	for _, data := range []string{`[[1]]`, `[1]`, ` [1] `} {
+		var list []int
+		assert.Nil(t, codec.UnmarshalRequest([]byte(data), []interface{}{&list}), data)
+		assert.Equal(t, []int{1}, list, data)
+	}
+	for _, data := range []string{`[[1,2]]`, `[1,2]`} {
+		var list []int
+		assert.Nil(t, codec.UnmarshalRequest([]byte(data), []interface{}{&list}), data)
+		assert.Equal(t, []int{1, 2}, list, data)
+	}
+	var lists [][]int
+	assert.Nil(t, codec.UnmarshalRequest([]byte(`[[1]]`), []interface{}{&lists}))
+	assert.Equal(t, [][]int{{1}}, lists)
+
+	// other single parameters too
+	var greeting string
+	for _, data := range []string{`["hello"]`, `"hello"`} {
+		assert.Nil(t, codec.UnmarshalRequest([]byte(data), []interface{}{&greeting}), data)
+		assert.Equal(t, "hello", greeting, data)
+	}
+	assert.NotNil(t, codec.UnmarshalRequest([]byte(`["hello", "world"]`), []interface{}{&greeting}))
+}
+
+func TestRawGenericArgs(t *testing.T) {
+	codec := NewRawJSONTwoWayCodec()
+	args := &generic.RawArgs{}
+	assert.Nil(t, codec.UnmarshalRequest([]byte(`["hello", 18]`), args))
+	assert.Equal(t, [][]byte{[]byte(`"hello"`), []byte(`18`)}, args.Data)
+
+	values, err := args.Values()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"hello", float64(18)}, values)
+}
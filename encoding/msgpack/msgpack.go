@@ -1,5 +1,9 @@
 package msgpack
 
+import (
+	"bytes"
+)
+
 import (
 	mp "github.com/ugorji/go/codec"
 )
@@ -10,7 +14,7 @@ import (
 )
 
 func init() {
-	encoding.RegisterCodec(encoding.NewPBWrapperTwoWayCodec("msgpack", NewMsgPackCodec(), raw_proto.NewProtobufCodec()))
+	encoding.RegisterCodec(encoding.NewPooledPBWrapperTwoWayCodec("msgpack", NewMsgPackCodec(), raw_proto.NewProtobufCodec()))
 }
 
 // MsgPackCodec is the msgpack impl of common.Codec interface
@@ -27,6 +31,11 @@ func (p *MsgPackCodec) Marshal(v interface{}) ([]byte, error) {
 	return out, encoder.Encode(v)
 }
 
+// MarshalTo serialize interface @v into @buf
+func (p *MsgPackCodec) MarshalTo(buf *bytes.Buffer, v interface{}) error {
+	return mp.NewEncoder(buf, new(mp.MsgpackHandle)).Encode(v)
+}
+
 // Unmarshal deserialize @data to interface
 func (p *MsgPackCodec) Unmarshal(data []byte, v interface{}) error {
 	dec := mp.NewDecoderBytes(data, new(mp.MsgpackHandle))
@@ -5,6 +5,7 @@ import (
 )
 
 import (
+	"github.com/dubbogo/grpc-go/encoding/generic"
 	"github.com/dubbogo/grpc-go/encoding/proto_wrapper_api"
 )
 
@@ -29,19 +30,54 @@ func (h *PBWrapperTwoWayCodec) Name() string {
 }
 
 // MarshalRequest marshal interface @v to []byte
+// @v is either a []interface{} argument list, whose java types are got by GetArgType,
+// a *generic.Request with explicit java types, or a single message of a stream.
 func (h *PBWrapperTwoWayCodec) MarshalRequest(v interface{}) ([]byte, error) {
-	reqList := v.([]interface{})
-	argsBytes := make([][]byte, 0, len(reqList))
+	args, argsTypes, err := requestArgs(v)
+	if err != nil {
+		return nil, err
+	}
+	argsBytes, err := h.marshalArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return h.marshalWrapperRequest(argsBytes, argsTypes)
+}
+
+// requestArgs returns the arguments of request @v and their java types.
+func requestArgs(v interface{}) ([]interface{}, []string, error) {
+	if genericReq, ok := v.(*generic.Request); ok {
+		if err := genericReq.Validate(); err != nil {
+			return nil, nil, err
+		}
+		return genericReq.Args, genericReq.ArgTypes, nil
+	}
+
+	reqList, ok := v.([]interface{})
+	if !ok {
+		// a single message of a stream
+		reqList = []interface{}{v}
+	}
 	argsTypes := make([]string, 0, len(reqList))
 	for _, value := range reqList {
-		data, err := h.codec.Marshal(value)
+		argsTypes = append(argsTypes, GetArgType(value))
+	}
+	return reqList, argsTypes, nil
+}
+
+func (h *PBWrapperTwoWayCodec) marshalArgs(args []interface{}) ([][]byte, error) {
+	argsBytes := make([][]byte, 0, len(args))
+	for _, value := range args {
+		data, err := h.codec.Marshal(unwrapArg(value))
 		if err != nil {
 			return nil, err
 		}
 		argsBytes = append(argsBytes, data)
-		argsTypes = append(argsTypes, GetArgType(value))
 	}
+	return argsBytes, nil
+}
 
+func (h *PBWrapperTwoWayCodec) marshalWrapperRequest(argsBytes [][]byte, argsTypes []string) ([]byte, error) {
 	wrapperRequest := &proto_wrapper_api.TripleRequestWrapper{
 		SerializeType: h.Name(),
 		Args:          argsBytes,
@@ -51,6 +87,8 @@ func (h *PBWrapperTwoWayCodec) MarshalRequest(v interface{}) ([]byte, error) {
 }
 
 // UnmarshalRequest unmarshal bytes @data to interface
+// @v is either a []interface{} of argument pointers, a *generic.RawArgs
+// whose arguments are left to be decoded later, or a single message pointer of a stream.
 func (h *PBWrapperTwoWayCodec) UnmarshalRequest(data []byte, v interface{}) error {
 	wrapperRequest := proto_wrapper_api.TripleRequestWrapper{}
 	err := h.pbCodec.Unmarshal(data, &wrapperRequest)
@@ -58,7 +96,19 @@ func (h *PBWrapperTwoWayCodec) UnmarshalRequest(data []byte, v interface{}) erro
 		return err
 	}
 
-	paramsInterfaces := v.([]interface{})
+	if rawArgs, ok := v.(*generic.RawArgs); ok {
+		rawArgs.SerializeType = wrapperRequest.SerializeType
+		rawArgs.Types = wrapperRequest.ArgTypes
+		rawArgs.Data = wrapperRequest.Args
+		rawArgs.Codec = h.codec
+		return nil
+	}
+
+	paramsInterfaces, ok := v.([]interface{})
+	if !ok {
+		// a single message of a stream
+		paramsInterfaces = []interface{}{v}
+	}
 	if len(paramsInterfaces) != len(wrapperRequest.Args) {
 		return perrors.Errorf("error ,request params len is %d, but exported method has %d", len(wrapperRequest.Args), len(paramsInterfaces))
 	}
@@ -0,0 +1,164 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package encoding
+
+import (
+	"bytes"
+	"math"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// field numbers of TripleRequestWrapper and TripleResponseWrapper
+const (
+	wrapperSerializeTypeField protowire.Number = 1
+	wrapperArgsField          protowire.Number = 2
+	wrapperArgTypesField      protowire.Number = 3
+
+	wrapperDataField protowire.Number = 2
+	wrapperTypeField protowire.Number = 3
+)
+
+// paddedVarintLen is the size reserved for the length of a bytes field whose
+// content is written before its length is known. Protobuf parsers accept
+// varints padded with continuation bytes, 5 bytes hold any length < 2^35.
+const paddedVarintLen = 5
+
+// PooledPBWrapperTwoWayCodec is a PBWrapperTwoWayCodec which writes the wrapper
+// into a pooled buffer instead of marshalling the wrapper proto: every argument
+// is written right behind its field tag and its length is filled in afterwards.
+// Inner codecs implementing BufferedCodec, e.g. msgpack and json, serialize the
+// arguments straight into the buffer, others still serialize every argument to
+// its own []byte, which is then copied. Unmarshalling is the same as
+// PBWrapperTwoWayCodec.
+type PooledPBWrapperTwoWayCodec struct {
+	PBWrapperTwoWayCodec
+}
+
+// NewPooledPBWrapperTwoWayCodec returns a PooledPBWrapperTwoWayCodec named
+// @name with @innerCodec serializing the arguments. @innerCodec writes
+// directly into the buffer if it implements BufferedCodec.
+func NewPooledPBWrapperTwoWayCodec(name string, innerCodec, pbCodec Codec) TwoWayCodec {
+	return &PooledPBWrapperTwoWayCodec{
+		PBWrapperTwoWayCodec: PBWrapperTwoWayCodec{
+			codec:   innerCodec,
+			name:    name,
+			pbCodec: pbCodec,
+		},
+	}
+}
+
+// MarshalRequest marshal interface @v to []byte
+func (h *PooledPBWrapperTwoWayCodec) MarshalRequest(v interface{}) ([]byte, error) {
+	return h.marshal(v, h.MarshalRequestTo)
+}
+
+// MarshalResponse marshal interface @v to []byte
+func (h *PooledPBWrapperTwoWayCodec) MarshalResponse(v interface{}) ([]byte, error) {
+	return h.marshal(v, h.MarshalResponseTo)
+}
+
+func (h *PooledPBWrapperTwoWayCodec) marshal(v interface{}, marshalTo func(*bytes.Buffer, interface{}) error) ([]byte, error) {
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+	if err := marshalTo(buf, v); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+// MarshalRequestTo appends TripleRequestWrapper of request @v to @buf
+func (h *PooledPBWrapperTwoWayCodec) MarshalRequestTo(buf *bytes.Buffer, v interface{}) error {
+	args, argsTypes, err := requestArgs(v)
+	if err != nil {
+		return err
+	}
+
+	writeStringField(buf, wrapperSerializeTypeField, h.name)
+	for _, arg := range args {
+		if err := h.writeValueField(buf, wrapperArgsField, unwrapArg(arg)); err != nil {
+			return err
+		}
+	}
+	for _, argType := range argsTypes {
+		writeStringField(buf, wrapperArgTypesField, argType)
+	}
+	return nil
+}
+
+// MarshalResponseTo appends TripleResponseWrapper of response @v to @buf
+func (h *PooledPBWrapperTwoWayCodec) MarshalResponseTo(buf *bytes.Buffer, v interface{}) error {
+	writeStringField(buf, wrapperSerializeTypeField, h.name)
+	if err := h.writeValueField(buf, wrapperDataField, v); err != nil {
+		return err
+	}
+	writeStringField(buf, wrapperTypeField, GetArgType(v))
+	return nil
+}
+
+// writeValueField writes @v serialized by the inner codec as bytes field @num.
+func (h *PooledPBWrapperTwoWayCodec) writeValueField(buf *bytes.Buffer, num protowire.Number, v interface{}) error {
+	var scratch [binaryVarintMaxLen]byte
+	buf.Write(protowire.AppendTag(scratch[:0], num, protowire.BytesType))
+
+	lenPos := buf.Len()
+	buf.Write(scratch[:paddedVarintLen])
+	if bc, ok := h.codec.(BufferedCodec); ok {
+		if err := bc.MarshalTo(buf, v); err != nil {
+			return err
+		}
+	} else {
+		data, err := h.codec.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+
+	size := buf.Len() - lenPos - paddedVarintLen
+	if uint64(size) > math.MaxUint32 {
+		return perrors.Errorf("wrapper field %d too large (%d bytes)", num, size)
+	}
+	putPaddedVarint(buf.Bytes()[lenPos:lenPos+paddedVarintLen], uint64(size))
+	return nil
+}
+
+// binaryVarintMaxLen is the max size of an encoded varint.
+const binaryVarintMaxLen = 10
+
+func writeStringField(buf *bytes.Buffer, num protowire.Number, s string) {
+	var scratch [2 * binaryVarintMaxLen]byte
+	b := protowire.AppendTag(scratch[:0], num, protowire.BytesType)
+	b = protowire.AppendVarint(b, uint64(len(s)))
+	buf.Write(b)
+	buf.WriteString(s)
+}
+
+// putPaddedVarint writes @v to @b as a varint of exactly len(@b) bytes.
+func putPaddedVarint(b []byte, v uint64) {
+	last := len(b) - 1
+	for i := 0; i < last; i++ {
+		b[i] = byte(v&0x7f) | 0x80
+		v >>= 7
+	}
+	b[last] = byte(v)
+}
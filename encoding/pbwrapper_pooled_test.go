@@ -0,0 +1,103 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package encoding
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/encoding/proto_wrapper_api"
+)
+
+// stringCodec serializes strings as is.
+type stringCodec struct{}
+
+func (stringCodec) Name() string { return "string" }
+
+func (stringCodec) Marshal(v interface{}) ([]byte, error) { return []byte(v.(string)), nil }
+
+func (stringCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*string)) = string(data)
+	return nil
+}
+
+// bufferedStringCodec writes strings into the buffer.
+type bufferedStringCodec struct {
+	stringCodec
+}
+
+func (bufferedStringCodec) MarshalTo(buf *bytes.Buffer, v interface{}) error {
+	buf.WriteString(v.(string))
+	return nil
+}
+
+type protoCodec struct{}
+
+func (protoCodec) Name() string { return "proto" }
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) { return proto.Marshal(v.(proto.Message)) }
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	return proto.Unmarshal(data, v.(proto.Message))
+}
+
+func TestPooledPBWrapperTwoWayCodec(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	for _, inner := range []Codec{stringCodec{}, bufferedStringCodec{}} {
+		pooled := NewPooledPBWrapperTwoWayCodec("string", inner, protoCodec{})
+		plain := NewPBWrapperTwoWayCodec("string", inner, protoCodec{})
+
+		data, err := pooled.MarshalRequest([]interface{}{"hello", long})
+		assert.Nil(t, err)
+		req := &proto_wrapper_api.TripleRequestWrapper{}
+		assert.Nil(t, proto.Unmarshal(data, req))
+		assert.Equal(t, "string", req.SerializeType)
+		assert.Equal(t, [][]byte{[]byte("hello"), []byte(long)}, req.Args)
+		assert.Equal(t, []string{"java.lang.String", "java.lang.String"}, req.ArgTypes)
+
+		var hello, world string
+		assert.Nil(t, plain.UnmarshalRequest(data, []interface{}{&hello, &world}))
+		assert.Equal(t, "hello", hello)
+		assert.Equal(t, long, world)
+
+		data, err = pooled.MarshalResponse("hello")
+		assert.Nil(t, err)
+		var rsp string
+		assert.Nil(t, pooled.UnmarshalResponse(data, &rsp))
+		assert.Equal(t, "hello", rsp)
+	}
+}
+
+func TestPutPaddedVarint(t *testing.T) {
+	for _, v := range []uint64{0, 1, 127, 128, 300, 1 << 28, 1<<32 - 1} {
+		b := make([]byte, paddedVarintLen)
+		putPaddedVarint(b, v)
+		got, n := proto.DecodeVarint(b)
+		assert.Equal(t, paddedVarintLen, n)
+		assert.Equal(t, v, got)
+	}
+}
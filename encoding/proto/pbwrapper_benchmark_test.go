@@ -0,0 +1,68 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proto
+
+import (
+	"fmt"
+	"testing"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/encoding"
+	"github.com/dubbogo/grpc-go/encoding/hessian"
+	"github.com/dubbogo/grpc-go/encoding/msgpack"
+	"github.com/dubbogo/grpc-go/encoding/raw_proto"
+)
+
+func setupBenchmarkWrapperInputs(payloadBaseSize int) []interface{} {
+	payload := make([]byte, payloadBaseSize)
+	for i := range payload {
+		payload[i] = 'a' + byte(i%26)
+	}
+	return []interface{}{string(payload), int64(payloadBaseSize), map[string]string{"key": string(payload)}}
+}
+
+// BenchmarkPBWrapperCodec compares the wrapper codec, which serializes every
+// argument to its own []byte and then marshals the wrapper proto, with the
+// pooled single-pass one.
+// Example run: go test -v -run=^$ -bench=BenchmarkPBWrapperCodec -benchmem
+func BenchmarkPBWrapperCodec(b *testing.B) {
+	innerCodecs := map[string]encoding.Codec{
+		"hessian2": hessian.NewHessianCodec(),
+		"msgpack":  msgpack.NewMsgPackCodec(),
+	}
+	for name, inner := range innerCodecs {
+		codecs := map[string]encoding.TwoWayCodec{
+			"Wrapper":       encoding.NewPBWrapperTwoWayCodec(name, inner, raw_proto.NewProtobufCodec()),
+			"PooledWrapper": encoding.NewPooledPBWrapperTwoWayCodec(name, inner, raw_proto.NewProtobufCodec()),
+		}
+		for i := 4; i <= 12; i += 4 {
+			args := setupBenchmarkWrapperInputs(1 << i)
+			for codecName, codec := range codecs {
+				b.Run(fmt.Sprintf("%s/%s/MinPayloadSize:%v", name, codecName, 1<<i), func(b *testing.B) {
+					b.ReportAllocs()
+					for n := 0; n < b.N; n++ {
+						if _, err := codec.MarshalRequest(args); err != nil {
+							b.Fatalf("codec.MarshalRequest(_) returned an error: %v", err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
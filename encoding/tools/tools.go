@@ -19,6 +19,7 @@ package tools
 
 import (
 	"reflect"
+	"strings"
 )
 
 import (
@@ -27,8 +28,12 @@ import (
 	perrors "github.com/pkg/errors"
 )
 
-// ReflectResponse reflect return value
-// TODO response object should not be copied again to another object, it should be the exact type of the object
+// ReflectResponse assigns the decoded value @in to the typed pointer @out.
+// @in is assigned as is when its type matches, so that the usual case of
+// registered POJOs and basic types does not copy. Otherwise it is converted
+// recursively: []interface{} into typed slices such as []*User, generic maps
+// into typed maps, maps into structs by hessian field name, and numbers
+// between numeric kinds.
 func ReflectResponse(in interface{}, out interface{}) error {
 	if in == nil {
 		return perrors.Errorf("@in is nil")
@@ -43,26 +48,15 @@ func ReflectResponse(in interface{}, out interface{}) error {
 
 	inValue := hessian.EnsurePackValue(in)
 	outValue := hessian.EnsurePackValue(out)
-
-	outType := outValue.Type().String()
-	if outType == "interface {}" || outType == "*interface {}" {
-		hessian.SetValue(outValue, inValue)
-		return nil
-	}
-
-	switch inValue.Type().Kind() {
-	case reflect.Slice, reflect.Array:
-		return CopySlice(inValue, outValue)
-	case reflect.Map:
-		return CopyMap(inValue, outValue)
-	default:
-		hessian.SetValue(outValue, inValue)
+	if outValue.IsNil() {
+		return perrors.Errorf("@out is a nil pointer")
 	}
 
-	return nil
+	return assign(inValue, outValue.Elem())
 }
 
-// CopySlice copy from inSlice to outSlice
+// CopySlice copy from inSlice to outSlice, converting the elements to the
+// element type of outSlice.
 func CopySlice(inSlice, outSlice reflect.Value) error {
 	if inSlice.IsNil() {
 		return perrors.New("@in is nil")
@@ -74,23 +68,11 @@ func CopySlice(inSlice, outSlice reflect.Value) error {
 	for outSlice.Kind() == reflect.Ptr {
 		outSlice = outSlice.Elem()
 	}
-
-	size := inSlice.Len()
-	outSlice.Set(reflect.MakeSlice(outSlice.Type(), size, size))
-
-	for i := 0; i < size; i++ {
-		inSliceValue := inSlice.Index(i)
-		if !inSliceValue.Type().AssignableTo(outSlice.Index(i).Type()) {
-			return perrors.Errorf("in element type [%s] can not assign to out element type [%s]",
-				inSliceValue.Type().String(), outSlice.Type().String())
-		}
-		outSlice.Index(i).Set(inSliceValue)
-	}
-
-	return nil
+	return assignSlice(inSlice, outSlice)
 }
 
-// CopyMap copy from in map to out map
+// CopyMap copy from in map to out map, converting the keys and values to the
+// key and value types of out map.
 func CopyMap(inMapValue, outMapValue reflect.Value) error {
 	if inMapValue.IsNil() {
 		return perrors.New("@in is nil")
@@ -102,27 +84,210 @@ func CopyMap(inMapValue, outMapValue reflect.Value) error {
 		return perrors.Errorf("@in is not map, but %v", inMapValue.Kind())
 	}
 
-	outMapType := hessian.UnpackPtrType(outMapValue.Type())
-	hessian.SetValue(outMapValue, reflect.MakeMap(outMapType))
+	for outMapValue.Kind() == reflect.Ptr {
+		if outMapValue.IsNil() {
+			outMapValue.Set(reflect.New(outMapValue.Type().Elem()))
+		}
+		outMapValue = outMapValue.Elem()
+	}
+	return assignMap(inMapValue, outMapValue)
+}
 
-	outKeyType := outMapType.Key()
+// assign sets settable @out to @in, converting @in recursively if needed.
+func assign(in, out reflect.Value) error {
+	// unwrap interfaces holding the decoded values
+	for in.IsValid() && in.Kind() == reflect.Interface {
+		in = in.Elem()
+	}
+	if !in.IsValid() {
+		out.Set(reflect.Zero(out.Type()))
+		return nil
+	}
 
-	outMapValue = hessian.UnpackPtrValue(outMapValue)
-	outValueType := outMapValue.Type().Elem()
+	inType, outType := in.Type(), out.Type()
+	// fast path, no conversion at all
+	if inType.AssignableTo(outType) {
+		out.Set(in)
+		return nil
+	}
+
+	switch outType.Kind() {
+	case reflect.Ptr:
+		if in.Kind() == reflect.Ptr {
+			if in.IsNil() {
+				out.Set(reflect.Zero(outType))
+				return nil
+			}
+			in = in.Elem()
+			if in.Type().AssignableTo(outType.Elem()) && in.CanAddr() {
+				out.Set(in.Addr())
+				return nil
+			}
+		}
+		elem := reflect.New(outType.Elem())
+		if err := assign(in, elem.Elem()); err != nil {
+			return err
+		}
+		out.Set(elem)
+		return nil
+	}
 
-	for _, inKey := range inMapValue.MapKeys() {
-		inValue := inMapValue.MapIndex(inKey)
+	if in.Kind() == reflect.Ptr {
+		if in.IsNil() {
+			out.Set(reflect.Zero(outType))
+			return nil
+		}
+		return assign(in.Elem(), out)
+	}
 
-		if !inKey.Type().AssignableTo(outKeyType) {
-			return perrors.Errorf("in Key:{type:%s, value:%#v} can not assign to out Key:{type:%s} ",
-				inKey.Type().String(), inKey, outKeyType.String())
+	switch outType.Kind() {
+	case reflect.Slice:
+		if in.Kind() != reflect.Slice && in.Kind() != reflect.Array {
+			break
+		}
+		return assignSlice(in, out)
+	case reflect.Array:
+		if in.Kind() != reflect.Slice && in.Kind() != reflect.Array {
+			break
+		}
+		if in.Len() > out.Len() {
+			return perrors.Errorf("in length %d exceeds out array length %d", in.Len(), out.Len())
 		}
-		if !inValue.Type().AssignableTo(outValueType) {
-			return perrors.Errorf("in Value:{type:%s, value:%#v} can not assign to out value:{type:%s}",
-				inValue.Type().String(), inValue, outValueType.String())
+		for i := 0; i < in.Len(); i++ {
+			if err := assign(in.Index(i), out.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if in.Kind() != reflect.Map {
+			break
+		}
+		return assignMap(in, out)
+	case reflect.Struct:
+		if in.Kind() != reflect.Map {
+			break
+		}
+		return assignStruct(in, out)
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if in.Kind() == outType.Kind() || (isNumber(in.Kind()) && isNumber(outType.Kind())) {
+			out.Set(in.Convert(outType))
+			return nil
 		}
-		outMapValue.SetMapIndex(inKey, inValue)
 	}
 
+	return perrors.Errorf("in type [%s] can not assign to out type [%s]", inType.String(), outType.String())
+}
+
+func assignSlice(in, out reflect.Value) error {
+	if in.Kind() == reflect.Slice && in.IsNil() {
+		out.Set(reflect.Zero(out.Type()))
+		return nil
+	}
+	if in.Type().AssignableTo(out.Type()) {
+		out.Set(in)
+		return nil
+	}
+
+	size := in.Len()
+	slice := reflect.MakeSlice(out.Type(), size, size)
+	for i := 0; i < size; i++ {
+		if err := assign(in.Index(i), slice.Index(i)); err != nil {
+			return perrors.WithMessagef(err, "slice index %d", i)
+		}
+	}
+	out.Set(slice)
+	return nil
+}
+
+func assignMap(in, out reflect.Value) error {
+	if in.IsNil() {
+		out.Set(reflect.Zero(out.Type()))
+		return nil
+	}
+	if in.Type().AssignableTo(out.Type()) {
+		out.Set(in)
+		return nil
+	}
+
+	outType := out.Type()
+	m := reflect.MakeMapWithSize(outType, in.Len())
+	iter := in.MapRange()
+	for iter.Next() {
+		key := reflect.New(outType.Key()).Elem()
+		if err := assign(iter.Key(), key); err != nil {
+			return perrors.WithMessagef(err, "map key %v", iter.Key())
+		}
+		value := reflect.New(outType.Elem()).Elem()
+		if err := assign(iter.Value(), value); err != nil {
+			return perrors.WithMessagef(err, "map value of key %v", iter.Key())
+		}
+		m.SetMapIndex(key, value)
+	}
+	out.Set(m)
+	return nil
+}
+
+// assignStruct sets the fields of struct @out from map @in, fields are matched
+// by their hessian tag, their lower camel case name or their name.
+func assignStruct(in, out reflect.Value) error {
+	outType := out.Type()
+	for i := 0; i < outType.NumField(); i++ {
+		field := outType.Field(i)
+		if field.PkgPath != "" { // unexported
+			continue
+		}
+		value := mapIndexByName(in, fieldNames(field)...)
+		if !value.IsValid() {
+			continue
+		}
+		if err := assign(value, out.Field(i)); err != nil {
+			return perrors.WithMessagef(err, "field %s", field.Name)
+		}
+	}
 	return nil
 }
+
+func fieldNames(field reflect.StructField) []string {
+	if tag := field.Tag.Get("hessian"); tag != "" && tag != "-" {
+		return []string{tag}
+	}
+	return []string{lowerCamelCase(field.Name), field.Name}
+}
+
+func mapIndexByName(m reflect.Value, names ...string) reflect.Value {
+	keyType := m.Type().Key()
+	for _, name := range names {
+		key := reflect.ValueOf(name)
+		if !key.Type().AssignableTo(keyType) {
+			if !key.Type().ConvertibleTo(keyType) {
+				return reflect.Value{}
+			}
+			key = key.Convert(keyType)
+		}
+		if v := m.MapIndex(key); v.IsValid() {
+			return v
+		}
+	}
+	return reflect.Value{}
+}
+
+func lowerCamelCase(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -22,6 +22,8 @@ import (
 )
 
 import (
+	hessian "github.com/apache/dubbo-go-hessian2"
+
 	"github.com/dubbogo/triple/pkg/common/constant"
 	"github.com/dubbogo/triple/pkg/config"
 
@@ -37,3 +39,122 @@ func TestValidate(t *testing.T) {
 	assert.Equal(t, constant.TRIPLE, opt.Protocol)
 	assert.Equal(t, constant.PBCodecName, opt.CodecType)
 }
+
+type User struct {
+	Name string
+	Age  int32
+	Tags []string
+}
+
+func (User) JavaClassName() string {
+	return "org.apache.dubbo.User"
+}
+
+func init() {
+	hessian.RegisterPOJO(&User{})
+}
+
+func TestReflectResponseAssignable(t *testing.T) {
+	in := &User{Name: "laurence"}
+	var out *User
+	assert.Nil(t, ReflectResponse(in, &out))
+	// no copy for values of the exact type
+	assert.True(t, in == out)
+
+	var any interface{}
+	assert.Nil(t, ReflectResponse(in, &any))
+	assert.Equal(t, in, any)
+}
+
+func TestReflectResponseSlice(t *testing.T) {
+	in := []interface{}{&User{Name: "a"}, &User{Name: "b"}}
+	var out []*User
+	assert.Nil(t, ReflectResponse(in, &out))
+	assert.Equal(t, []*User{{Name: "a"}, {Name: "b"}}, out)
+
+	var values []User
+	assert.Nil(t, ReflectResponse(in, &values))
+	assert.Equal(t, []User{{Name: "a"}, {Name: "b"}}, values)
+
+	var ints []int32
+	assert.Nil(t, ReflectResponse([]interface{}{int64(1), int32(2)}, &ints))
+	assert.Equal(t, []int32{1, 2}, ints)
+
+	assert.NotNil(t, ReflectResponse([]interface{}{"a"}, &ints))
+}
+
+func TestReflectResponseMap(t *testing.T) {
+	in := map[interface{}]interface{}{
+		"a": []interface{}{&User{Name: "a"}},
+	}
+	var out map[string][]*User
+	assert.Nil(t, ReflectResponse(in, &out))
+	assert.Equal(t, map[string][]*User{"a": {{Name: "a"}}}, out)
+}
+
+func TestReflectResponseStruct(t *testing.T) {
+	in := map[interface{}]interface{}{
+		"name": "laurence",
+		"age":  int64(18),
+		"tags": []interface{}{"a", "b"},
+	}
+	out := &User{}
+	assert.Nil(t, ReflectResponse(in, out))
+	assert.Equal(t, &User{Name: "laurence", Age: 18, Tags: []string{"a", "b"}}, out)
+}
+
+func decodeHessian(b *testing.B, v interface{}) interface{} {
+	encoder := hessian.NewEncoder()
+	if err := encoder.Encode(v); err != nil {
+		b.Fatal(err)
+	}
+	in, err := hessian.NewDecoder(encoder.Buffer()).Decode()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return in
+}
+
+func BenchmarkReflectResponsePOJO(b *testing.B) {
+	in := decodeHessian(b, &User{Name: "laurence", Age: 18, Tags: []string{"a", "b"}})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var out *User
+		if err := ReflectResponse(in, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReflectResponseTypedSlice(b *testing.B) {
+	users := make([]*User, 0, 100)
+	for i := 0; i < 100; i++ {
+		users = append(users, &User{Name: "laurence", Age: int32(i)})
+	}
+	in := decodeHessian(b, users)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var out []*User
+		if err := ReflectResponse(in, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReflectResponseMap(b *testing.B) {
+	m := make(map[string]int64, 100)
+	for i := 0; i < 100; i++ {
+		m[string(rune('a'+i%26))+string(rune('a'+i/26))] = int64(i)
+	}
+	in := decodeHessian(b, m)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var out map[string]int64
+		if err := ReflectResponse(in, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"strings"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/encoding"
+	"github.com/dubbogo/grpc-go/encoding/generic"
+	// hessian2 is the default serialization of generic invocation.
+	_ "github.com/dubbogo/grpc-go/encoding/hessian"
+	"github.com/dubbogo/grpc-go/metadata"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+// defaultGenericContentSubtype is the content-subtype used by GenericInvoke
+// unless overridden by a CallContentSubtype or ForceCodec call option.
+const defaultGenericContentSubtype = "hessian2"
+
+// GenericInvoke calls @methodName of the triple service @interfaceName without
+// generated stubs. @argTypes are the java class names of @args and are sent in
+// TripleRequestWrapper as is. The reply is decoded to basic types,
+// map[string]interface{} and hessian.Object values, and returned along with
+// the response trailer.
+//
+// Requests are serialized with hessian2 by default, use CallContentSubtype to
+// select another registered wrapper codec such as msgpack or json.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func (cc *ClientConn) GenericInvoke(ctx context.Context, interfaceName, methodName string, argTypes []string, args []interface{}, opts ...CallOption) (interface{}, metadata.MD, error) {
+	req := generic.NewRequest(argTypes, args)
+	if err := req.Validate(); err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !hasCodecCallOption(Combine(cc.dopts.callOptions, opts)) {
+		opts = Combine([]CallOption{CallContentSubtype(defaultGenericContentSubtype)}, opts)
+	}
+
+	var reply interface{}
+	trailer, err := cc.Invoke(ctx, "/"+interfaceName+"/"+methodName, req, &reply, opts...)
+	if err != nil {
+		return nil, trailer, err
+	}
+	return generic.Normalize(reply), trailer, nil
+}
+
+// hasCodecCallOption reports whether @opts select a codec or content-subtype.
+func hasCodecCallOption(opts []CallOption) bool {
+	for _, o := range opts {
+		switch o.(type) {
+		case ContentSubtypeCallOption, ForceCodecCallOption, CustomCodecCallOption:
+			return true
+		}
+	}
+	return false
+}
+
+// GenericHandler handles triple requests of services and methods which are
+// not registered on the server, see GenericServiceHandler.
+//
+// Experimental
+//
+// Notice: This type is EXPERIMENTAL and may be changed or removed in a
+// later release.
+type GenericHandler interface {
+	// Handle serves the call of @methodName on @interfaceName. @args holds the
+	// java arg types and the still serialized args of the request. The result
+	// may implement OuterResult to return attachments.
+	Handle(ctx context.Context, interfaceName, methodName string, args *generic.RawArgs) (interface{}, error)
+}
+
+// GenericHandlerFunc is an adapter to allow the use of ordinary functions as
+// GenericHandler.
+type GenericHandlerFunc func(ctx context.Context, interfaceName, methodName string, args *generic.RawArgs) (interface{}, error)
+
+// Handle calls f(ctx, interfaceName, methodName, args).
+func (f GenericHandlerFunc) Handle(ctx context.Context, interfaceName, methodName string, args *generic.RawArgs) (interface{}, error) {
+	return f(ctx, interfaceName, methodName, args)
+}
+
+// GenericServiceHandler returns a ServerOption that makes @handler serve the
+// unary triple requests of unknown services and methods which are not routed
+// to a proxy service, ahead of UnknownServiceHandler. Only requests encoded by
+// a wrapper codec, e.g. hessian2, msgpack or json, are handled generically,
+// and streaming methods of registered services are never handled as unary.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func GenericServiceHandler(handler GenericHandler) ServerOption {
+	return newFuncServerOption(func(o *serverOptions) {
+		o.genericHandler = handler
+	})
+}
+
+// genericMethodDesc returns a MethodDesc which dispatches unary requests
+// of @interfaceName to @handler through the unary interceptors.
+func genericMethodDesc(handler GenericHandler, interfaceName, methodName string) *MethodDesc {
+	fullMethod := "/" + interfaceName + "/" + methodName
+	return &MethodDesc{
+		MethodName: methodName,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor UnaryServerInterceptor) (interface{}, error) {
+			args := &generic.RawArgs{}
+			if err := dec(args); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return handler.Handle(ctx, interfaceName, methodName, args)
+			}
+			info := &UnaryServerInfo{
+				Server:     srv,
+				FullMethod: fullMethod,
+			}
+			return interceptor(ctx, args, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return handler.Handle(ctx, interfaceName, methodName, req.(*generic.RawArgs))
+			})
+		},
+	}
+}
+
+// hasStreamMethod reports whether @srv has a streaming method named @method,
+// ignoring case.
+func hasStreamMethod(srv *serviceInfo, method string) bool {
+	for name := range srv.streams {
+		if strings.EqualFold(name, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWrapperCodec reports whether @codec wraps requests in TripleRequestWrapper.
+func isWrapperCodec(codec encoding.TwoWayCodec) bool {
+	switch codec.(type) {
+	case *encoding.PBWrapperTwoWayCodec, *encoding.PooledPBWrapperTwoWayCodec:
+		return true
+	default:
+		return false
+	}
+}
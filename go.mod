@@ -7,6 +7,7 @@ require (
 	github.com/cespare/xxhash/v2 v2.1.2
 	github.com/cncf/udpa/go v0.0.0-20210930031921-04548b0d99d4
 	github.com/cncf/xds/go v0.0.0-20211011173535-cb28da3451f1
+	github.com/dubbogo/gost v1.11.18
 	github.com/dubbogo/triple v1.0.9
 	github.com/envoyproxy/go-control-plane v0.10.0
 	github.com/golang/glog v1.0.0
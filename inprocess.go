@@ -0,0 +1,310 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"time"
+)
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/encoding"
+	"github.com/dubbogo/grpc-go/internal/grpcsync"
+	"github.com/dubbogo/grpc-go/internal/transport"
+	"github.com/dubbogo/grpc-go/stats"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+var errInProcessListenerClosed = errors.New("grpc: the in-process listener is closed")
+
+// InProcessListener is a net.Listener whose connections are made by the
+// ClientConns dialed WithInProcessDialer in the same process. A Server
+// serving it hands the messages of their streams to the handlers as
+// objects: codecs, compressors and framing are skipped, while deadlines,
+// metadata, interceptors, stats handlers and statuses work as they do over
+// the network. Messages are shared by the sender and the receiver, unless
+// the listener is created with InProcessDeepCopy; protobuf messages received
+// into messages of the receiver are merged into them, so only the fields of
+// other messages are shared then. Message size limits do not apply.
+//
+// Experimental
+//
+// Notice: This type is EXPERIMENTAL and may be changed or removed in a
+// later release.
+type InProcessListener struct {
+	deepCopy bool
+	conns    chan *transport.InProcessConn
+	closed   *grpcsync.Event
+}
+
+// InProcessOption configures an InProcessListener.
+//
+// Experimental
+//
+// Notice: This type is EXPERIMENTAL and may be changed or removed in a
+// later release.
+type InProcessOption func(*InProcessListener)
+
+// InProcessDeepCopy returns an InProcessOption which deep copies messages
+// by encoding them with the codec of the stream and decoding them on the
+// receiving side, so that handlers and callers can not modify each other's
+// messages. It costs a serialization, but no framing.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func InProcessDeepCopy() InProcessOption {
+	return func(l *InProcessListener) {
+		l.deepCopy = true
+	}
+}
+
+// NewInProcessListener creates an InProcessListener, to be served by
+// Server.Serve and dialed WithInProcessDialer.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func NewInProcessListener(opts ...InProcessOption) *InProcessListener {
+	l := &InProcessListener{
+		conns:  make(chan *transport.InProcessConn),
+		closed: grpcsync.NewEvent(),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Accept waits for and returns the next connection dialed to the listener.
+func (l *InProcessListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed.Done():
+		return nil, errInProcessListenerClosed
+	}
+}
+
+// Close closes the listener, the ClientConns dialing it fail to connect.
+// The connections already accepted are not closed.
+func (l *InProcessListener) Close() error {
+	l.closed.Fire()
+	return nil
+}
+
+// Addr returns the address of the listener, which is the one of all
+// in-process connections.
+func (l *InProcessListener) Addr() net.Addr {
+	return transport.InProcessAddr
+}
+
+// dial makes a connection to the listener, once it is accepted.
+func (l *InProcessListener) dial(ctx context.Context) (*transport.InProcessConn, error) {
+	conn := transport.NewInProcessConn(l.deepCopy)
+	select {
+	case l.conns <- conn:
+		return conn, nil
+	case <-l.closed.Done():
+		return nil, errInProcessListenerClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// newInProcessTransport sets up an in-process transport (using the
+// in-process server transport in transport/inprocess.go).
+func (s *Server) newInProcessTransport(c net.Conn) transport.ServerTransport {
+	config := &transport.ServerConfig{
+		InTapHandle:      s.opts.inTapHandle,
+		StatsHandler:     s.opts.statsHandler,
+		ChannelzParentID: s.channelzID,
+	}
+	st, err := transport.NewInProcessServerTransport(c.(*transport.InProcessConn), config)
+	if err != nil {
+		s.mu.Lock()
+		s.errorf("NewInProcessServerTransport failed: %v", err)
+		s.mu.Unlock()
+		c.Close()
+		return nil
+	}
+
+	return s.withStackPolicy(st)
+}
+
+// encodedMessage is a message deep copied by an in-process transport, it is
+// encoded by the codec of the sender.
+type encodedMessage []byte
+
+// prepareObject returns the object sent by in-process streams for message
+// @m: @m itself, or its encoding if @deepCopy is set. The encoding is also
+// returned for stats.
+func prepareObject(msgType string, m interface{}, codec encoding.TwoWayCodec, deepCopy bool) (interface{}, []byte, error) {
+	if pm, ok := m.(*PreparedMsg); ok {
+		// already encoded
+		return encodedMessage(pm.encodedData), pm.encodedData, nil
+	}
+	if !deepCopy {
+		return m, nil, nil
+	}
+	data, err := encode(msgType, codec, m)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodedMessage(data), data, nil
+}
+
+// recvObject receives the next message object of the in-process stream @s
+// into @m. It is recv for in-process streams.
+func recvObject(recvType string, c encoding.TwoWayCodec, s *transport.Stream, m interface{}, payInfo *payloadInfo) error {
+	obj, err := s.RecvObject()
+	if err != nil {
+		return err
+	}
+	data, err := assignObject(recvType, c, obj, m)
+	if err != nil {
+		return err
+	}
+	if payInfo != nil {
+		payInfo.uncompressedBytes = data
+	}
+	return nil
+}
+
+// assignObject assigns the message object @obj received by an in-process
+// stream to @m, as codec c would unmarshal it. Objects which can not be
+// assigned, e.g. to a *generic.RawArgs, are encoded and decoded by @c. The
+// encoding, if any, is returned.
+func assignObject(recvType string, c encoding.TwoWayCodec, obj, m interface{}) ([]byte, error) {
+	data, ok := obj.(encodedMessage)
+	if !ok {
+		if assign := objectAssigner(obj, m); assign != nil {
+			assign()
+			return nil, nil
+		}
+		var err error
+		if data, err = encode(recvType, c, obj); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if recvType == "req" {
+		err = c.UnmarshalRequest(data, m)
+	} else {
+		err = c.UnmarshalResponse(data, m)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "grpc: failed to unmarshal the received message %v", err)
+	}
+	return data, nil
+}
+
+// objectAssigner returns the function assigning message @obj to @m, or nil
+// if it can not be assigned. Argument lists are assigned element by element,
+// to the argument pointers of @m, like the request codecs do.
+func objectAssigner(obj, m interface{}) func() {
+	if m == nil {
+		// an empty response
+		return func() {}
+	}
+	dsts, ok := m.([]interface{})
+	if !ok {
+		return valueAssigner(obj, m)
+	}
+	srcs, ok := obj.([]interface{})
+	if !ok || len(srcs) != len(dsts) {
+		return nil
+	}
+	assigns := make([]func(), 0, len(srcs))
+	for i := range srcs {
+		assign := valueAssigner(srcs[i], dsts[i])
+		if assign == nil {
+			return nil
+		}
+		assigns = append(assigns, assign)
+	}
+	return func() {
+		for _, assign := range assigns {
+			assign()
+		}
+	}
+}
+
+// valueAssigner returns the function storing @src, or the value it points
+// to, in the pointer @dst, or nil if the types do not match. Protobuf
+// messages pointed to are deep copied.
+func valueAssigner(src, dst interface{}) func() {
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return nil
+	}
+	target := dv.Elem()
+	if src == nil {
+		return func() {
+			target.Set(reflect.Zero(target.Type()))
+		}
+	}
+	sv := reflect.ValueOf(src)
+	if sv.Type().AssignableTo(target.Type()) {
+		return func() {
+			target.Set(sv)
+		}
+	}
+	if sv.Kind() == reflect.Ptr && !sv.IsNil() && sv.Elem().Type().AssignableTo(target.Type()) {
+		if sv.Pointer() == dv.Pointer() {
+			// the message itself
+			return func() {}
+		}
+		srcMsg, srcOK := src.(proto.Message)
+		dstMsg, dstOK := dst.(proto.Message)
+		if srcOK && dstOK {
+			// copying the struct would share the internal state, slices and
+			// maps of the message, merge a deep copy into @dst instead
+			return func() {
+				dstMsg.Reset()
+				proto.Merge(dstMsg, srcMsg)
+			}
+		}
+		return func() {
+			target.Set(sv.Elem())
+		}
+	}
+	return nil
+}
+
+// objectPayload returns the OutPayload stats of message @msg sent by an
+// in-process stream, whose encoding is @data if it was deep copied.
+func objectPayload(client bool, msg interface{}, data []byte, t time.Time) *stats.OutPayload {
+	return &stats.OutPayload{
+		Client:   client,
+		Payload:  msg,
+		Data:     data,
+		Length:   len(data),
+		SentTime: t,
+	}
+}
@@ -0,0 +1,57 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/golang/protobuf/proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	wrapperspb "github.com/golang/protobuf/ptypes/wrappers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (s) TestValueAssigner(t *testing.T) {
+	// protobuf messages are deep copied, not copied by struct
+	src := &structpb.ListValue{Values: []*structpb.Value{{Kind: &structpb.Value_StringValue{StringValue: "a"}}}}
+	proto.Size(src)
+	dst := &structpb.ListValue{Values: []*structpb.Value{{}, {}}}
+	valueAssigner(src, dst)()
+	assert.True(t, proto.Equal(src, dst))
+	src.Values[0].Kind = &structpb.Value_StringValue{StringValue: "b"}
+	src.Values = append(src.Values, &structpb.Value{})
+	assert.Equal(t, "a", dst.Values[0].GetStringValue())
+	assert.Len(t, dst.Values, 1)
+
+	// pointers to messages are shared
+	msg := &wrapperspb.StringValue{Value: "a"}
+	var dstPtr *wrapperspb.StringValue
+	valueAssigner(msg, &dstPtr)()
+	assert.Same(t, msg, dstPtr)
+
+	// other values are copied by reflection
+	type pojo struct{ Name string }
+	var dstPojo pojo
+	valueAssigner(&pojo{Name: "a"}, &dstPojo)()
+	assert.Equal(t, pojo{Name: "a"}, dstPojo)
+
+	assert.Nil(t, valueAssigner(&pojo{}, &wrapperspb.StringValue{}))
+}
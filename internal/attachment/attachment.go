@@ -0,0 +1,230 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package attachment converts dubbo attachments to and from triple metadata.
+//
+// The mapping is:
+//  - string and []string values are sent as is;
+//  - []byte values are sent under the key with a "-bin" suffix, which the
+//    transport base64 encodes;
+//  - bool, integer and float values are sent as their textual form, along with
+//    a "<key>-tri-type" entry naming the go type, so that they can be decoded
+//    to the same type while java peers still read a plain string.
+//
+// Other values can not be mapped and are reported to the caller.
+package attachment
+
+import (
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/metadata"
+)
+
+const (
+	// binSuffix is the suffix of binary metadata keys.
+	binSuffix = "-bin"
+	// TypeSuffix is the suffix of the key carrying the type of a typed value.
+	TypeSuffix = "-tri-type"
+)
+
+// Type names of typed values.
+const (
+	typeBool    = "bool"
+	typeInt     = "int"
+	typeInt8    = "int8"
+	typeInt16   = "int16"
+	typeInt32   = "int32"
+	typeInt64   = "int64"
+	typeUint    = "uint"
+	typeUint8   = "uint8"
+	typeUint16  = "uint16"
+	typeUint32  = "uint32"
+	typeUint64  = "uint64"
+	typeFloat32 = "float32"
+	typeFloat64 = "float64"
+)
+
+// ToMetadata converts @attachments to metadata. The keys of the values which
+// can not be mapped are returned as @unsupported, those values are not sent.
+func ToMetadata(attachments map[string]interface{}) (md metadata.MD, unsupported []string) {
+	md = make(metadata.MD, len(attachments))
+	for k, v := range attachments {
+		if !Append(md, k, v) {
+			unsupported = append(unsupported, k)
+		}
+	}
+	return md, unsupported
+}
+
+// Append adds the attachment @key=@value to @md, with @key lowercased. It
+// returns false if @value can not be mapped.
+func Append(md metadata.MD, key string, value interface{}) bool {
+	// http2 header names must be lowercase
+	key = strings.ToLower(key)
+	switch v := value.(type) {
+	case string:
+		md[key] = append(md[key], v)
+	case []string:
+		md[key] = append(md[key], v...)
+	case []byte:
+		if !strings.HasSuffix(key, binSuffix) {
+			key += binSuffix
+		}
+		md[key] = append(md[key], string(v))
+	default:
+		text, typ, ok := formatTyped(value)
+		if !ok {
+			return false
+		}
+		md[key] = append(md[key], text)
+		md[key+TypeSuffix] = []string{typ}
+	}
+	return true
+}
+
+func formatTyped(value interface{}) (text, typ string, ok bool) {
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v), typeBool, true
+	case int:
+		return strconv.FormatInt(int64(v), 10), typeInt, true
+	case int8:
+		return strconv.FormatInt(int64(v), 10), typeInt8, true
+	case int16:
+		return strconv.FormatInt(int64(v), 10), typeInt16, true
+	case int32:
+		return strconv.FormatInt(int64(v), 10), typeInt32, true
+	case int64:
+		return strconv.FormatInt(v, 10), typeInt64, true
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), typeUint, true
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), typeUint8, true
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), typeUint16, true
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), typeUint32, true
+	case uint64:
+		return strconv.FormatUint(v, 10), typeUint64, true
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), typeFloat32, true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), typeFloat64, true
+	default:
+		return "", "", false
+	}
+}
+
+// FromMetadata converts @md back to attachments. Single values are returned
+// as string, []byte or their typed value, multiple values as []string,
+// [][]byte or a slice of the typed values. Binary keys lose their "-bin"
+// suffix, and values whose type entry can not be parsed are kept as strings.
+func FromMetadata(md metadata.MD) map[string]interface{} {
+	attachments := make(map[string]interface{}, len(md))
+	for k, vals := range md {
+		if strings.HasSuffix(k, TypeSuffix) {
+			continue
+		}
+		if strings.HasSuffix(k, binSuffix) {
+			attachments[strings.TrimSuffix(k, binSuffix)] = parseBinary(vals)
+			continue
+		}
+		if types := md[k+TypeSuffix]; len(types) > 0 {
+			if v, ok := parseTyped(types[0], vals); ok {
+				attachments[k] = v
+				continue
+			}
+		}
+		if len(vals) == 1 {
+			attachments[k] = vals[0]
+		} else {
+			attachments[k] = vals
+		}
+	}
+	return attachments
+}
+
+func parseBinary(vals []string) interface{} {
+	if len(vals) == 1 {
+		return []byte(vals[0])
+	}
+	bins := make([][]byte, 0, len(vals))
+	for _, v := range vals {
+		bins = append(bins, []byte(v))
+	}
+	return bins
+}
+
+func parseTyped(typ string, vals []string) (interface{}, bool) {
+	parsed := make([]interface{}, 0, len(vals))
+	for _, text := range vals {
+		v, err := parseTypedValue(typ, text)
+		if err != nil {
+			return nil, false
+		}
+		parsed = append(parsed, v)
+	}
+	if len(parsed) == 1 {
+		return parsed[0], true
+	}
+	return parsed, true
+}
+
+func parseTypedValue(typ, text string) (interface{}, error) {
+	switch typ {
+	case typeBool:
+		return strconv.ParseBool(text)
+	case typeInt:
+		v, err := strconv.ParseInt(text, 10, strconv.IntSize)
+		return int(v), err
+	case typeInt8:
+		v, err := strconv.ParseInt(text, 10, 8)
+		return int8(v), err
+	case typeInt16:
+		v, err := strconv.ParseInt(text, 10, 16)
+		return int16(v), err
+	case typeInt32:
+		v, err := strconv.ParseInt(text, 10, 32)
+		return int32(v), err
+	case typeInt64:
+		return strconv.ParseInt(text, 10, 64)
+	case typeUint:
+		v, err := strconv.ParseUint(text, 10, strconv.IntSize)
+		return uint(v), err
+	case typeUint8:
+		v, err := strconv.ParseUint(text, 10, 8)
+		return uint8(v), err
+	case typeUint16:
+		v, err := strconv.ParseUint(text, 10, 16)
+		return uint16(v), err
+	case typeUint32:
+		v, err := strconv.ParseUint(text, 10, 32)
+		return uint32(v), err
+	case typeUint64:
+		return strconv.ParseUint(text, 10, 64)
+	case typeFloat32:
+		v, err := strconv.ParseFloat(text, 32)
+		return float32(v), err
+	case typeFloat64:
+		return strconv.ParseFloat(text, 64)
+	default:
+		return nil, strconv.ErrSyntax
+	}
+}
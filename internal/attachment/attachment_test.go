@@ -0,0 +1,74 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package attachment
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/metadata"
+)
+
+func TestToMetadata(t *testing.T) {
+	md, unsupported := ToMetadata(map[string]interface{}{
+		"traceId": "abc",
+		"tags":    []string{"a", "b"},
+		"payload": []byte{0, 1},
+		"retries": int32(3),
+		"gray":    true,
+		"pojo":    struct{}{},
+	})
+	assert.Equal(t, []string{"pojo"}, unsupported)
+	assert.Equal(t, metadata.MD{
+		"traceid":          {"abc"},
+		"tags":             {"a", "b"},
+		"payload-bin":      {string([]byte{0, 1})},
+		"retries":          {"3"},
+		"retries-tri-type": {"int32"},
+		"gray":             {"true"},
+		"gray-tri-type":    {"bool"},
+	}, md)
+}
+
+func TestRoundTrip(t *testing.T) {
+	attachments := map[string]interface{}{
+		"traceid": "abc",
+		"tags":    []string{"a", "b"},
+		"payload": []byte{0, 1},
+		"retries": int32(3),
+		"timeout": int64(3000),
+		"weight":  float64(0.5),
+		"gray":    true,
+	}
+	md, unsupported := ToMetadata(attachments)
+	assert.Empty(t, unsupported)
+	assert.Equal(t, attachments, FromMetadata(md))
+}
+
+func TestFromMetadataBadType(t *testing.T) {
+	md := metadata.MD{
+		"retries":          {"three"},
+		"retries-tri-type": {"int32"},
+	}
+	assert.Equal(t, map[string]interface{}{"retries": "three"}, FromMetadata(md))
+}
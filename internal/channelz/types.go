@@ -570,6 +570,10 @@ type ServerInternalMetric struct {
 	CallsFailed int64
 	// The last time a call was started on the server.
 	LastCallStartedTimestamp time.Time
+	// ServiceKeys are the sorted dubbo service keys "group/service:version"
+	// of the services registered with a group or a version. The channelz
+	// service proto has no field for them, they are only available in process.
+	ServiceKeys []string
 }
 
 // Server is the interface to be satisfied in order to be tracked by channelz as
@@ -0,0 +1,293 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package status
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+)
+
+// JavaExceptionDomain is the errdetails.ErrorInfo domain of java exceptions.
+// Each exception of a cause chain is an ErrorInfo, whose reason is the class
+// name, followed by an errdetails.DebugInfo holding its message and stack
+// trace elements in the format of Throwable.printStackTrace.
+const JavaExceptionDomain = "org.apache.dubbo"
+
+const javaMessageKey = "message"
+
+// StackTraceElement is a frame of a java stack trace.
+type StackTraceElement struct {
+	DeclaringClass string
+	MethodName     string
+	// FileName is empty if unknown.
+	FileName string
+	// LineNumber is negative if unknown, -2 for native methods.
+	LineNumber int
+}
+
+// String formats @e like java.lang.StackTraceElement.toString.
+func (e StackTraceElement) String() string {
+	var source string
+	switch {
+	case e.LineNumber == -2:
+		source = "Native Method"
+	case e.FileName == "":
+		source = "Unknown Source"
+	case e.LineNumber >= 0:
+		source = e.FileName + ":" + strconv.Itoa(e.LineNumber)
+	default:
+		source = e.FileName
+	}
+	return e.DeclaringClass + "." + e.MethodName + "(" + source + ")"
+}
+
+var javaFrameRegexp = regexp.MustCompile(`^\s*at\s+(\S+)\.([^.(\s]+)\(([^)]*)\)\s*$`)
+
+// parseStackTraceElement parses a line "at pkg.Class.method(File.java:12)".
+func parseStackTraceElement(line string) (StackTraceElement, bool) {
+	m := javaFrameRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return StackTraceElement{}, false
+	}
+	e := StackTraceElement{DeclaringClass: m[1], MethodName: m[2], LineNumber: -1}
+	switch source := m[3]; source {
+	case "Native Method":
+		e.LineNumber = -2
+	case "Unknown Source":
+	default:
+		e.FileName = source
+		if pos := strings.LastIndexByte(source, ':'); pos >= 0 {
+			if n, err := strconv.Atoi(source[pos+1:]); err == nil {
+				e.FileName, e.LineNumber = source[:pos], n
+			}
+		}
+	}
+	return e, true
+}
+
+// JavaException is an exception thrown by a java provider, or thrown by a go
+// provider to java consumers, carried in the status details.
+type JavaException struct {
+	ClassName  string
+	Message    string
+	StackTrace []StackTraceElement
+	Cause      *JavaException
+}
+
+// Error formats @e like java.lang.Throwable.toString.
+func (e *JavaException) Error() string {
+	if e.Message == "" {
+		return e.ClassName
+	}
+	return e.ClassName + ": " + e.Message
+}
+
+// Unwrap returns the cause of @e, converted by the factory registered for
+// its class name if any.
+func (e *JavaException) Unwrap() error {
+	if e.Cause == nil {
+		return nil
+	}
+	return e.Cause.typed()
+}
+
+// typed returns @e converted by the factory registered for its class name,
+// or @e itself.
+func (e *JavaException) typed() error {
+	javaExceptionFactoriesLock.RLock()
+	factory, ok := javaExceptionFactories[e.ClassName]
+	javaExceptionFactoriesLock.RUnlock()
+	if !ok {
+		return e
+	}
+	if err := factory(e); err != nil {
+		return err
+	}
+	return e
+}
+
+var (
+	javaExceptionFactoriesLock sync.RWMutex
+	javaExceptionFactories     = make(map[string]func(*JavaException) error)
+)
+
+// RegisterJavaException makes remote exceptions of @className, and their
+// causes, unwrap to the error returned by @factory, so that callers can use
+// errors.As against their own error types.
+func RegisterJavaException(className string, factory func(*JavaException) error) {
+	javaExceptionFactoriesLock.Lock()
+	defer javaExceptionFactoriesLock.Unlock()
+	javaExceptionFactories[className] = factory
+}
+
+// FromJavaException returns a Status representing code @c and exception @e,
+// which java consumers see as an RpcException caused by @e.
+func FromJavaException(c codes.Code, e *JavaException) *Status {
+	s := &spb.Status{Code: int32(c), Message: e.Error()}
+	for ex := e; ex != nil; ex = ex.Cause {
+		info := &errdetails.ErrorInfo{
+			Reason:   ex.ClassName,
+			Domain:   JavaExceptionDomain,
+			Metadata: map[string]string{javaMessageKey: ex.Message},
+		}
+		debug := &errdetails.DebugInfo{Detail: ex.Message}
+		for _, frame := range ex.StackTrace {
+			debug.StackEntries = append(debug.StackEntries, "at "+frame.String())
+		}
+		for _, detail := range []proto.Message{info, debug} {
+			any, err := ptypes.MarshalAny(detail)
+			if err != nil {
+				continue
+			}
+			s.Details = append(s.Details, any)
+		}
+	}
+	return &Status{s: s}
+}
+
+// JavaException returns the java exception carried by s. A status without
+// java errdetails.ErrorInfo, but with java stack trace elements, as sent by
+// older java providers, gets the class name from its message.
+func (s *Status) JavaException() (*JavaException, bool) {
+	if s == nil || s.s == nil {
+		return nil, false
+	}
+
+	var top, last *JavaException
+	var fallback *errdetails.DebugInfo
+	for _, any := range s.s.Details {
+		switch {
+		case ptypes.Is(any, (*errdetails.ErrorInfo)(nil)):
+			info := &errdetails.ErrorInfo{}
+			if err := ptypes.UnmarshalAny(any, info); err != nil || info.Domain != JavaExceptionDomain {
+				continue
+			}
+			ex := &JavaException{ClassName: info.Reason, Message: info.Metadata[javaMessageKey]}
+			if top == nil {
+				top = ex
+			} else {
+				last.Cause = ex
+			}
+			last = ex
+		case ptypes.Is(any, (*errdetails.DebugInfo)(nil)):
+			debug := &errdetails.DebugInfo{}
+			if err := ptypes.UnmarshalAny(any, debug); err != nil {
+				continue
+			}
+			frames := parseStackTrace(debug.StackEntries)
+			if len(frames) == 0 {
+				continue
+			}
+			if last != nil && last.StackTrace == nil {
+				last.StackTrace = frames
+			} else if fallback == nil {
+				fallback = debug
+			}
+		}
+	}
+	if top != nil {
+		return top, true
+	}
+	if fallback == nil {
+		return nil, false
+	}
+
+	ex := &JavaException{Message: fallback.Detail, StackTrace: parseStackTrace(fallback.StackEntries)}
+	if className, msg, ok := splitJavaMessage(s.s.Message); ok {
+		ex.ClassName = className
+		if ex.Message == "" {
+			ex.Message = msg
+		}
+	}
+	return ex, true
+}
+
+// isJavaExceptionDebugInfo reports whether @details[i] is the
+// errdetails.DebugInfo of a java exception, which follows its ErrorInfo.
+func isJavaExceptionDebugInfo(details []*anypb.Any, i int) bool {
+	if i == 0 || !ptypes.Is(details[i-1], (*errdetails.ErrorInfo)(nil)) {
+		return false
+	}
+	info := &errdetails.ErrorInfo{}
+	return ptypes.UnmarshalAny(details[i-1], info) == nil && info.Domain == JavaExceptionDomain
+}
+
+// parseStackTrace returns the java frames of @entries, which may be lines or
+// whole printed stack traces.
+func parseStackTrace(entries []string) []StackTraceElement {
+	var frames []StackTraceElement
+	for _, entry := range entries {
+		for _, line := range strings.Split(entry, "\n") {
+			if frame, ok := parseStackTraceElement(line); ok {
+				frames = append(frames, frame)
+			}
+		}
+	}
+	return frames
+}
+
+var javaClassRegexp = regexp.MustCompile(`^(?:[A-Za-z_$][\w$]*\.)+[A-Z][\w$]*$`)
+
+// splitJavaMessage splits "pkg.SomeException: message" as formatted by
+// java.lang.Throwable.toString.
+func splitJavaMessage(msg string) (className, message string, ok bool) {
+	className, message = msg, ""
+	if pos := strings.Index(msg, ": "); pos >= 0 {
+		className, message = msg[:pos], msg[pos+2:]
+	}
+	if !javaClassRegexp.MatchString(className) {
+		return "", "", false
+	}
+	return className, message, true
+}
+
+// PrintStackTrace formats @e with its cause chain and stack traces like
+// java.lang.Throwable.printStackTrace.
+func (e *JavaException) PrintStackTrace() string {
+	var sb strings.Builder
+	for ex := e; ex != nil; ex = ex.Cause {
+		if ex != e {
+			sb.WriteString("Caused by: ")
+		}
+		sb.WriteString(ex.Error())
+		for _, frame := range ex.StackTrace {
+			fmt.Fprintf(&sb, "\n\tat %s", frame)
+		}
+		if ex.Cause != nil {
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
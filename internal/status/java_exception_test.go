@@ -0,0 +1,121 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package status
+
+import (
+	"errors"
+	"testing"
+)
+
+import (
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/stretchr/testify/assert"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+)
+
+var bizException = &JavaException{
+	ClassName: "com.foo.BizException",
+	Message:   "order not found",
+	StackTrace: []StackTraceElement{
+		{DeclaringClass: "com.foo.OrderService", MethodName: "get", FileName: "OrderService.java", LineNumber: 42},
+		{DeclaringClass: "sun.reflect.NativeMethodAccessorImpl", MethodName: "invoke0", LineNumber: -2},
+	},
+	Cause: &JavaException{
+		ClassName:  "java.sql.SQLException",
+		Message:    "timeout",
+		StackTrace: []StackTraceElement{{DeclaringClass: "com.foo.Dao", MethodName: "query", LineNumber: -1}},
+	},
+}
+
+func TestJavaExceptionRoundTrip(t *testing.T) {
+	st := FromProto(FromJavaException(codes.Unknown, bizException).Proto())
+	assert.Equal(t, "com.foo.BizException: order not found", st.Message())
+
+	ex, ok := st.JavaException()
+	assert.True(t, ok)
+	assert.Equal(t, bizException, ex)
+	assert.Equal(t, "com.foo.BizException: order not found\n"+
+		"\tat com.foo.OrderService.get(OrderService.java:42)\n"+
+		"\tat sun.reflect.NativeMethodAccessorImpl.invoke0(Native Method)\n"+
+		"Caused by: java.sql.SQLException: timeout\n"+
+		"\tat com.foo.Dao.query(Unknown Source)", ex.PrintStackTrace())
+}
+
+func TestJavaExceptionExemptFromStackPolicy(t *testing.T) {
+	st := FromJavaException(codes.Unknown, bizException)
+	assert.Empty(t, st.StackEntries())
+	for _, p := range []StackPolicy{{Mode: StackSkip}, {Mode: StackLogOnly}, {Mode: StackTruncate, MaxFrames: 0}} {
+		filtered, _ := p.Filter(st, nil)
+		ex, ok := filtered.JavaException()
+		assert.True(t, ok)
+		assert.Equal(t, bizException, ex)
+	}
+}
+
+func TestJavaExceptionFallback(t *testing.T) {
+	detail, err := ptypes.MarshalAny(&errdetails.DebugInfo{
+		Detail:       "order not found",
+		StackEntries: []string{"at com.foo.OrderService.get(OrderService.java:42)"},
+	})
+	assert.Nil(t, err)
+	st := FromProto(&spb.Status{
+		Code:    int32(codes.Unknown),
+		Message: "com.foo.BizException: order not found",
+		Details: []*anypb.Any{detail},
+	})
+	ex, ok := st.JavaException()
+	assert.True(t, ok)
+	assert.Equal(t, "com.foo.BizException", ex.ClassName)
+	assert.Equal(t, "order not found", ex.Message)
+	assert.Len(t, ex.StackTrace, 1)
+
+	_, ok = New(codes.Internal, "go error").JavaException()
+	assert.False(t, ok)
+}
+
+type bizError struct {
+	ex *JavaException
+}
+
+func (e *bizError) Error() string {
+	return e.ex.Error()
+}
+
+func TestJavaExceptionErrorsAs(t *testing.T) {
+	err := FromJavaException(codes.Unknown, bizException).Err()
+	var ex *JavaException
+	assert.True(t, errors.As(err, &ex))
+	assert.Equal(t, "com.foo.BizException", ex.ClassName)
+
+	RegisterJavaException("java.sql.SQLException", func(ex *JavaException) error { return &bizError{ex: ex} })
+	defer delete(javaExceptionFactories, "java.sql.SQLException")
+	var be *bizError
+	assert.True(t, errors.As(err, &be))
+	assert.Equal(t, "timeout", be.ex.Message)
+
+	assert.Nil(t, errors.Unwrap(New(codes.Internal, "go error").Err()))
+}
@@ -0,0 +1,184 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package status
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+)
+
+import (
+	"github.com/golang/protobuf/ptypes"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// StackMode selects how the stack traces of statuses are sent.
+type StackMode int
+
+const (
+	// StackSend sends stack traces to every peer, it is the default.
+	StackSend StackMode = iota
+	// StackSkip never sends stack traces. Use SetStackCapture to not
+	// capture them at all.
+	StackSkip
+	// StackLogOnly logs stack traces on the server, but never sends them.
+	StackLogOnly
+	// StackAllowlist sends stack traces only to the peers accepted by
+	// StackPolicy.AllowPeer.
+	StackAllowlist
+	// StackTruncate sends at most StackPolicy.MaxFrames frames.
+	StackTruncate
+)
+
+// StackPolicy controls the errdetails.DebugInfo stack traces of the statuses
+// a server serializes into grpc-status-details-bin.
+type StackPolicy struct {
+	Mode StackMode
+	// MaxFrames is the number of frames kept in StackTruncate mode.
+	MaxFrames int
+	// AllowPeer reports whether stack traces may be sent to the peer at addr
+	// in StackAllowlist mode. Stacks are never sent if it is nil.
+	AllowPeer func(addr net.Addr) bool
+}
+
+// captureFrames is the number of frames New captures, 0 for none.
+var captureFrames int32 = maxCaptureDepth
+
+// SetStackCapture sets the max number of frames New captures in the process,
+// 0 disables stack capture. At most 32 frames are captured.
+func SetStackCapture(maxFrames int) {
+	if maxFrames < 0 {
+		maxFrames = 0
+	}
+	if maxFrames > maxCaptureDepth {
+		maxFrames = maxCaptureDepth
+	}
+	atomic.StoreInt32(&captureFrames, int32(maxFrames))
+}
+
+// captureDepth returns the number of frames New captures, 0 for none.
+func captureDepth() int {
+	return int(atomic.LoadInt32(&captureFrames))
+}
+
+// Filter returns @st as it should be sent to @peer under policy @p. @stripped
+// reports whether stack traces were removed, which should then be logged in
+// StackLogOnly mode.
+func (p StackPolicy) Filter(st *Status, peer net.Addr) (filtered *Status, stripped bool) {
+	if st == nil || len(st.StackEntries()) == 0 {
+		return st, false
+	}
+	switch p.Mode {
+	case StackSkip, StackLogOnly:
+		return st.WithoutStacks(), true
+	case StackAllowlist:
+		if p.AllowPeer != nil && peer != nil && p.AllowPeer(peer) {
+			return st, false
+		}
+		return st.WithoutStacks(), true
+	case StackTruncate:
+		return st.WithTruncatedStacks(p.MaxFrames), false
+	default:
+		return st, false
+	}
+}
+
+// StackEntries returns the stack entries of the errdetails.DebugInfo details of s,
+// except the stack traces of java exceptions.
+func (s *Status) StackEntries() []string {
+	if s == nil || s.s == nil {
+		return nil
+	}
+	var entries []string
+	for i, any := range s.s.Details {
+		if !isStackDetail(s.s.Details, i) {
+			continue
+		}
+		info := &errdetails.DebugInfo{}
+		if err := ptypes.UnmarshalAny(any, info); err != nil {
+			continue
+		}
+		entries = append(entries, info.StackEntries...)
+	}
+	return entries
+}
+
+// WithoutStacks returns a copy of s without errdetails.DebugInfo details, except
+// the ones of java exceptions.
+func (s *Status) WithoutStacks() *Status {
+	p := s.Proto()
+	if p == nil {
+		return s
+	}
+	details := p.Details[:0]
+	for i, any := range p.Details {
+		if !isStackDetail(p.Details, i) {
+			details = append(details, any)
+		}
+	}
+	p.Details = details
+	return &Status{s: p}
+}
+
+// WithTruncatedStacks returns a copy of s whose stack entries keep at most
+// @maxFrames frames. A frame is the two lines "function\n\tfile:line" that
+// follow the first line of an entry.
+func (s *Status) WithTruncatedStacks(maxFrames int) *Status {
+	p := s.Proto()
+	if p == nil {
+		return s
+	}
+	for i, any := range p.Details {
+		if !isStackDetail(p.Details, i) {
+			continue
+		}
+		info := &errdetails.DebugInfo{}
+		if err := ptypes.UnmarshalAny(any, info); err != nil {
+			continue
+		}
+		for j, entry := range info.StackEntries {
+			info.StackEntries[j] = truncateStack(entry, maxFrames)
+		}
+		if truncated, err := ptypes.MarshalAny(info); err == nil {
+			p.Details[i] = truncated
+		}
+	}
+	return &Status{s: p}
+}
+
+// isStackDetail reports whether @details[i] is a errdetails.DebugInfo holding
+// go stack traces. The java exceptions of FromJavaException are exempt from
+// the stack policy, since they are part of the error returned to callers.
+func isStackDetail(details []*anypb.Any, i int) bool {
+	return ptypes.Is(details[i], (*errdetails.DebugInfo)(nil)) && !isJavaExceptionDebugInfo(details, i)
+}
+
+func truncateStack(entry string, maxFrames int) string {
+	if maxFrames < 0 {
+		maxFrames = 0
+	}
+	lines := strings.SplitN(entry, "\n", 2*maxFrames+2)
+	if len(lines) <= 2*maxFrames+1 {
+		return entry
+	}
+	return strings.Join(lines[:2*maxFrames+1], "\n")
+}
@@ -0,0 +1,102 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package status
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+)
+
+func withStackCapture(t *testing.T, maxFrames int) {
+	old := captureDepth()
+	SetStackCapture(maxFrames)
+	t.Cleanup(func() { SetStackCapture(old) })
+}
+
+func TestNewCapture(t *testing.T) {
+	assert.Len(t, New(codes.Internal, "boom").StackEntries(), 1)
+	assert.Empty(t, New(codes.OK, "").StackEntries())
+
+	withStackCapture(t, 0)
+	st := New(codes.Internal, "boom")
+	assert.Empty(t, st.StackEntries())
+	assert.Equal(t, "", st.Err().(*Error).Stacks())
+}
+
+// nestedNew calls New @depth frames deeper, as New skips the frames of the
+// public status package.
+func nestedNew(depth int) *Status {
+	if depth == 0 {
+		return New(codes.Internal, "boom")
+	}
+	return nestedNew(depth - 1)
+}
+
+func TestNewTruncatedCapture(t *testing.T) {
+	withStackCapture(t, 2)
+	entries := nestedNew(8).StackEntries()
+	assert.Len(t, entries, 1)
+	// "\nfunc\n\tfile:line" per frame
+	assert.Equal(t, 2, strings.Count(entries[0], "\n\t"))
+}
+
+func TestFilter(t *testing.T) {
+	st := nestedNew(8)
+	local := &net.TCPAddr{IP: net.ParseIP("127.0.0.1")}
+
+	filtered, stripped := StackPolicy{Mode: StackSend}.Filter(st, local)
+	assert.False(t, stripped)
+	assert.Equal(t, st, filtered)
+
+	filtered, stripped = StackPolicy{Mode: StackLogOnly}.Filter(st, local)
+	assert.True(t, stripped)
+	assert.Empty(t, filtered.StackEntries())
+	assert.Equal(t, "boom", filtered.Message())
+	assert.NotEmpty(t, st.StackEntries())
+
+	allow := StackPolicy{Mode: StackAllowlist, AllowPeer: func(addr net.Addr) bool {
+		return addr.(*net.TCPAddr).IP.IsLoopback()
+	}}
+	filtered, _ = allow.Filter(st, local)
+	assert.NotEmpty(t, filtered.StackEntries())
+	filtered, _ = allow.Filter(st, &net.TCPAddr{IP: net.ParseIP("10.0.0.1")})
+	assert.Empty(t, filtered.StackEntries())
+	filtered, _ = allow.Filter(st, nil)
+	assert.Empty(t, filtered.StackEntries())
+
+	filtered, stripped = StackPolicy{Mode: StackTruncate, MaxFrames: 1}.Filter(st, local)
+	assert.False(t, stripped)
+	assert.Equal(t, 1, strings.Count(filtered.StackEntries()[0], "\n\t"))
+}
+
+func TestTruncateStack(t *testing.T) {
+	entry := "msg\nf1\n\ta.go:1\nf2\n\tb.go:2"
+	assert.Equal(t, "msg\nf1\n\ta.go:1", truncateStack(entry, 1))
+	assert.Equal(t, entry, truncateStack(entry, 2))
+	assert.Equal(t, entry, truncateStack(entry, 5))
+	assert.Equal(t, "msg", truncateStack(entry, 0))
+}
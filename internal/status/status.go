@@ -55,11 +55,16 @@ type Status struct {
 }
 
 // New returns a Status representing c and msg, with user-made error as stack
+// unless stack capture is disabled by SetStackCapture.
 func New(c codes.Code, msg string) *Status {
 	newStatus := &Status{s: &spb.Status{Code: int32(c), Message: msg}}
+	depth := captureDepth()
+	if depth == 0 {
+		return newStatus
+	}
 	newStatusWithDetail, _ := newStatus.WithDetails(&errdetails.DebugInfo{
 		StackEntries: []string{
-			fmt.Sprintf("%+v", callers().StackTrace()), // use e.String() as triple stack
+			fmt.Sprintf("%+v", callers(depth).StackTrace()), // use e.String() as triple stack
 		},
 	})
 	return newStatusWithDetail
@@ -68,6 +73,9 @@ func New(c codes.Code, msg string) *Status {
 // New returns a Status representing c and msg. with e.String() as triple stack field
 func NewWithoutStacks(c codes.Code, e error) *Status {
 	newStatus := &Status{s: &spb.Status{Code: int32(c), Message: e.Error()}}
+	if captureDepth() == 0 {
+		return newStatus
+	}
 	newStatusWithDetail, _ := newStatus.WithDetails(&errdetails.DebugInfo{
 		StackEntries: []string{
 			fmt.Sprintf("%+v", e), // use e.String() as triple stack
@@ -205,6 +213,16 @@ func (e *Error) Stacks() string {
 	return stackTracesStr
 }
 
+// Unwrap returns the java exception carried by se, converted by the factory
+// registered for its class name if any, so that errors.As finds it.
+func (e *Error) Unwrap() error {
+	ex, ok := e.s.JavaException()
+	if !ok {
+		return nil
+	}
+	return ex.typed()
+}
+
 // Is implements future error.Is functionality.
 // A Error is equivalent if the code and message are identical.
 func (e *Error) Is(target error) bool {
@@ -239,10 +257,12 @@ func (s *stack) StackTrace() perrors.StackTrace {
 	return f
 }
 
-func callers() *stack {
-	const depth = 32
-	var pcs [depth]uintptr
-	n := runtime.Callers(5, pcs[:])
+// maxCaptureDepth is the max number of frames captured by New.
+const maxCaptureDepth = 32
+
+func callers(depth int) *stack {
+	var pcs [maxCaptureDepth]uintptr
+	n := runtime.Callers(5, pcs[:depth])
 	var st stack = pcs[0:n]
 	return &st
 }
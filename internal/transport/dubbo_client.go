@@ -0,0 +1,881 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// This file is the implementation of a client transport speaking the dubbo2
+// protocol, used for the addresses of the dubbo protocol, see the
+// resolver/protocol package.
+
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+import (
+	hessian "github.com/apache/dubbo-go-hessian2"
+	"github.com/apache/dubbo-go-hessian2/java_exception"
+
+	"github.com/golang/protobuf/proto"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/credentials"
+	"github.com/dubbogo/grpc-go/encoding"
+	ghessian "github.com/dubbogo/grpc-go/encoding/hessian"
+	"github.com/dubbogo/grpc-go/encoding/proto_wrapper_api"
+	icredentials "github.com/dubbogo/grpc-go/internal/credentials"
+	"github.com/dubbogo/grpc-go/metadata"
+	"github.com/dubbogo/grpc-go/peer"
+	"github.com/dubbogo/grpc-go/resolver"
+	"github.com/dubbogo/grpc-go/stats"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+const (
+	// defaultDubboHeartbeat is the interval of the heartbeats of dubbo2
+	// connections, unless keepalive parameters are set.
+	defaultDubboHeartbeat = 60 * time.Second
+	// dubboHeartbeatTimeouts is the number of heartbeat intervals without
+	// receiving anything after which a dubbo2 connection is closed.
+	dubboHeartbeatTimeouts = 3
+)
+
+// dubboClient is a ClientTransport making dubbo2 requests on a connection.
+// Every stream is a unary call, whose only message is a TripleRequestWrapper
+// of the arguments, multiplexed by request ID. The attachments of responses
+// are received as trailers.
+type dubboClient struct {
+	conn         net.Conn
+	remoteAddr   net.Addr
+	authInfo     credentials.AuthInfo
+	perRPCCreds  []credentials.PerRPCCredentials
+	statsHandler stats.Handler
+	onGoAway     func(GoAwayReason)
+	onClose      func()
+
+	heartbeat time.Duration
+	// lastRead is the time of the last package received, in unix nanoseconds.
+	// Accessed atomically.
+	lastRead int64
+	// nextID is the last request ID. Accessed atomically.
+	nextID uint32
+
+	// writeMu serializes the writes of packages.
+	writeMu sync.Mutex
+
+	// errorChan is closed to notify the I/O error to the caller.
+	errorChan chan struct{}
+	// goAway is closed when the provider sends the readonly event.
+	goAway chan struct{}
+
+	mu      sync.Mutex
+	state   transportState
+	streams map[uint32]*dubboStream
+}
+
+// dubboStream is the state of the dubbo2 request of a stream.
+type dubboStream struct {
+	s           *Stream
+	attachments map[string]string
+	sent        bool
+}
+
+func newDubboClient(connectCtx context.Context, addr resolver.Address, opts ConnectOptions, onPrefaceReceipt func(), onGoAway func(GoAwayReason), onClose func()) (_ *dubboClient, err error) {
+	conn, err := dial(connectCtx, opts.Dialer, addr, opts.UseProxy, opts.UserAgent)
+	if err != nil {
+		if opts.FailOnNonTempDialError {
+			return nil, connectionErrorf(isTemporary(err), err, "transport: error while dialing: %v", err)
+		}
+		return nil, connectionErrorf(true, err, "transport: Error while dialing %v", err)
+	}
+	// Any further errors will close the underlying connection
+	defer func(conn net.Conn) {
+		if err != nil {
+			conn.Close()
+		}
+	}(conn)
+
+	transportCreds := opts.TransportCredentials
+	perRPCCreds := opts.PerRPCCredentials
+	if b := opts.CredsBundle; b != nil {
+		if t := b.TransportCredentials(); t != nil {
+			transportCreds = t
+		}
+		if t := b.PerRPCCredentials(); t != nil {
+			perRPCCreds = append(perRPCCreds, t)
+		}
+	}
+	var authInfo credentials.AuthInfo
+	if transportCreds != nil {
+		connectCtx = icredentials.NewClientHandshakeInfoContext(connectCtx, credentials.ClientHandshakeInfo{Attributes: addr.Attributes})
+		rawConn := conn
+		deadline, _ := connectCtx.Deadline()
+		rawConn.SetDeadline(deadline)
+		conn, authInfo, err = transportCreds.ClientHandshake(connectCtx, addr.ServerName, rawConn)
+		rawConn.SetDeadline(time.Time{})
+		if err != nil {
+			return nil, connectionErrorf(isTemporary(err), err, "transport: authentication handshake failed: %v", err)
+		}
+	}
+	heartbeat := opts.KeepaliveParams.Time
+	if heartbeat <= 0 || heartbeat == infinity {
+		heartbeat = defaultDubboHeartbeat
+	}
+
+	t := &dubboClient{
+		conn:         conn,
+		remoteAddr:   conn.RemoteAddr(),
+		authInfo:     authInfo,
+		perRPCCreds:  perRPCCreds,
+		statsHandler: opts.StatsHandler,
+		onGoAway:     onGoAway,
+		onClose:      onClose,
+		heartbeat:    heartbeat,
+		lastRead:     time.Now().UnixNano(),
+		errorChan:    make(chan struct{}),
+		goAway:       make(chan struct{}),
+		state:        reachable,
+		streams:      make(map[uint32]*dubboStream),
+	}
+	if t.statsHandler != nil {
+		connBegin := &stats.ConnBegin{
+			Client: true,
+		}
+		t.statsHandler.HandleConn(connectCtx, connBegin)
+	}
+	// dubbo2 has no connection preface, the connection is usable as soon as
+	// it is established.
+	onPrefaceReceipt()
+	go t.reader()
+	go t.keepalive()
+	return t, nil
+}
+
+func (t *dubboClient) getPeer() *peer.Peer {
+	return &peer.Peer{
+		Addr:     t.remoteAddr,
+		AuthInfo: t.authInfo,
+	}
+}
+
+// NewStream creates a stream whose request is sent by the last Write. All
+// non-nil errors returned will be *NewStreamError.
+func (t *dubboClient) NewStream(ctx context.Context, callHdr *CallHdr) (*Stream, error) {
+	if callHdr.ContentSubtype != DubboContentSubtype {
+		return nil, &NewStreamError{
+			Err:        status.Errorf(codes.Internal, "transport: dubbo2 requests must have the content-subtype %s, not %q", DubboContentSubtype, callHdr.ContentSubtype),
+			DoNotRetry: true,
+		}
+	}
+	ctx = peer.NewContext(ctx, t.getPeer())
+	attachments, err := t.createAttachments(ctx, callHdr)
+	if err != nil {
+		return nil, &NewStreamError{Err: err, DoNotTransparentRetry: true}
+	}
+	s := &Stream{
+		id:             atomic.AddUint32(&t.nextID, 1),
+		ct:             t,
+		ctx:            ctx,
+		done:           make(chan struct{}),
+		method:         callHdr.Method,
+		sendCompress:   callHdr.SendCompress,
+		buf:            newRecvBuffer(),
+		headerChan:     make(chan struct{}),
+		contentSubtype: callHdr.ContentSubtype,
+		doneFunc:       callHdr.DoneFunc,
+		requestRead:    func(int) {},
+	}
+	s.trReader = &transportReader{
+		reader: &recvBufferReader{
+			ctx:     s.ctx,
+			ctxDone: s.ctx.Done(),
+			recv:    s.buf,
+			closeStream: func(err error) {
+				t.CloseStream(s, err)
+			},
+			freeBuffer: func(*bytes.Buffer) {},
+		},
+		windowHandler: func(int) {},
+	}
+
+	t.mu.Lock()
+	if state := t.state; state != reachable {
+		t.mu.Unlock()
+		err := error(errStreamDrain)
+		if state == closing {
+			err = ErrConnClosing
+		}
+		return nil, &NewStreamError{Err: err}
+	}
+	t.streams[s.id] = &dubboStream{s: s, attachments: attachments}
+	t.mu.Unlock()
+
+	if t.statsHandler != nil {
+		outHeader := &stats.OutHeader{
+			Client:      true,
+			FullMethod:  callHdr.Method,
+			RemoteAddr:  t.remoteAddr,
+			Compression: callHdr.SendCompress,
+			Header:      metadata.New(attachments),
+		}
+		t.statsHandler.HandleRPC(s.ctx, outHeader)
+	}
+	return s, nil
+}
+
+// createAttachments returns the attachments of the request of a stream, from
+// the per-RPC credentials and the outgoing metadata of @ctx.
+func (t *dubboClient) createAttachments(ctx context.Context, callHdr *CallHdr) (map[string]string, error) {
+	attachments := make(map[string]string)
+	if md, added, ok := metadata.FromOutgoingContextRaw(ctx); ok {
+		for k, vv := range md {
+			if isReservedHeader(k) || len(vv) == 0 {
+				continue
+			}
+			attachments[k] = vv[0]
+		}
+		for _, vv := range added {
+			for i := 0; i+1 < len(vv); i += 2 {
+				k := strings.ToLower(vv[i])
+				if _, ok := attachments[k]; ok || isReservedHeader(k) {
+					continue
+				}
+				attachments[k] = vv[i+1]
+			}
+		}
+	}
+
+	pos := strings.LastIndex(callHdr.Method, "/")
+	if pos == -1 {
+		pos = len(callHdr.Method)
+	}
+	audience := "dubbo://" + callHdr.Host + callHdr.Method[:pos]
+	data, err := perRPCMetadata(ctx, t.perRPCCreds, callHdr, t.authInfo, audience)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range data {
+		attachments[k] = v
+	}
+	return attachments, nil
+}
+
+// perRPCMetadata returns the metadata of the per-RPC credentials @creds and
+// @callHdr.Creds for a request to @audience on a connection with @authInfo.
+// The keys are lower-cased.
+func perRPCMetadata(ctx context.Context, creds []credentials.PerRPCCredentials, callHdr *CallHdr, authInfo credentials.AuthInfo, audience string) (map[string]string, error) {
+	if callHdr.Creds != nil {
+		creds = append(creds[:len(creds):len(creds)], callHdr.Creds)
+	}
+	if len(creds) == 0 {
+		return nil, nil
+	}
+	ri := credentials.RequestInfo{
+		Method:   callHdr.Method,
+		AuthInfo: authInfo,
+	}
+	ctx = icredentials.NewRequestInfoContext(ctx, ri)
+	md := make(map[string]string)
+	for _, c := range creds {
+		if c.RequireTransportSecurity() && credentials.CheckSecurityLevel(authInfo, credentials.PrivacyAndIntegrity) != nil {
+			return nil, status.Error(codes.Unauthenticated, "transport: cannot send secure credentials on an insecure connection")
+		}
+		data, err := c.GetRequestMetadata(ctx, audience)
+		if err != nil {
+			if _, ok := status.FromError(err); ok {
+				return nil, err
+			}
+			return nil, status.Errorf(codes.Unauthenticated, "transport: %v", err)
+		}
+		for k, v := range data {
+			md[strings.ToLower(k)] = v
+		}
+	}
+	return md, nil
+}
+
+// Write sends the request of @s, whose arguments are in the
+// TripleRequestWrapper @data. dubbo2 requests carry exactly one message, so
+// @opts.Last must be set.
+func (t *dubboClient) Write(s *Stream, hdr []byte, data []byte, opts *Options) error {
+	t.mu.Lock()
+	ds, ok := t.streams[s.id]
+	if !ok || ds.s != s {
+		t.mu.Unlock()
+		return errStreamDone
+	}
+	sent := ds.sent
+	ds.sent = true
+	t.mu.Unlock()
+	if sent {
+		if len(data) == 0 && opts.Last {
+			// CloseSend after the request.
+			return nil
+		}
+		err := status.Error(codes.Unimplemented, "transport: dubbo2 requests can not carry more than one message")
+		t.CloseStream(s, err)
+		return err
+	}
+	if !opts.Last {
+		err := status.Error(codes.Unimplemented, "transport: dubbo2 does not support client streaming")
+		t.CloseStream(s, err)
+		return err
+	}
+
+	pkg, err := t.createRequest(s, ds.attachments, hdr, data)
+	if err != nil {
+		t.CloseStream(s, err)
+		return err
+	}
+	if err := t.write(pkg); err != nil {
+		t.Close(connectionErrorf(true, err, "transport: failed to write dubbo2 request: %v", err))
+		return ErrConnClosing
+	}
+	return nil
+}
+
+// createRequest returns the dubbo2 request package of @s.
+func (t *dubboClient) createRequest(s *Stream, attachments map[string]string, hdr []byte, data []byte) ([]byte, error) {
+	if len(hdr) > 0 && hdr[0] == 1 {
+		var err error
+		if data, err = t.decompress(s, data); err != nil {
+			return nil, err
+		}
+	}
+	wrapper := &proto_wrapper_api.TripleRequestWrapper{}
+	if err := proto.Unmarshal(data, wrapper); err != nil {
+		return nil, status.Errorf(codes.Internal, "transport: dubbo2 request is not a TripleRequestWrapper: %v", err)
+	}
+	if wrapper.SerializeType != "" && wrapper.SerializeType != DubboContentSubtype {
+		return nil, status.Errorf(codes.Internal, "transport: dubbo2 request is serialized by %s instead of hessian2", wrapper.SerializeType)
+	}
+	args := make([]interface{}, 0, len(wrapper.Args))
+	for _, arg := range wrapper.Args {
+		v, err := hessian.NewDecoder(arg).Decode()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "transport: failed to decode dubbo2 argument: %v", err)
+		}
+		args = append(args, v)
+	}
+
+	if len(wrapper.ArgTypes) != len(args) {
+		return nil, status.Errorf(codes.Internal, "transport: %d argument types for %d dubbo2 arguments", len(wrapper.ArgTypes), len(args))
+	}
+	var argTypes strings.Builder
+	for _, javaType := range wrapper.ArgTypes {
+		argTypes.WriteString(descOfJavaType(javaType))
+	}
+
+	path := strings.TrimPrefix(s.method, "/")
+	pos := strings.LastIndex(path, "/")
+	if pos == -1 {
+		return nil, status.Errorf(codes.Internal, "transport: malformed method name %q", s.method)
+	}
+	service, method := path[:pos], path[pos+1:]
+	version := attachments[DubboServiceVersionHeader]
+	reqAttachments := make(map[string]string, len(attachments)+4)
+	for k, v := range attachments {
+		if k != DubboServiceGroupHeader && k != DubboServiceVersionHeader {
+			reqAttachments[k] = v
+		}
+	}
+	reqAttachments[hessian.PATH_KEY] = service
+	reqAttachments[hessian.INTERFACE_KEY] = service
+	reqAttachments[hessian.VERSION_KEY] = version
+	if group := attachments[DubboServiceGroupHeader]; group != "" {
+		reqAttachments[hessian.GROUP_KEY] = group
+	}
+	if dl, ok := s.ctx.Deadline(); ok {
+		// dubbo2 timeouts are in milliseconds, round up the ones below.
+		timeout := time.Until(dl) / time.Millisecond
+		if timeout < 1 {
+			timeout = 1
+		}
+		reqAttachments[hessian.TIMEOUT_KEY] = strconv.FormatInt(int64(timeout), 10)
+	}
+
+	header := [hessian.HEADER_LENGTH]byte{hessian.MAGIC_HIGH, hessian.MAGIC_LOW, hessian.FLAG_REQUEST | hessian.FLAG_TWOWAY | dubboHessian2SerialID}
+	binary.BigEndian.PutUint64(header[4:], uint64(s.id))
+	encoder := hessian.NewEncoder()
+	encoder.Append(header[:])
+	for _, v := range []interface{}{hessian.DEFAULT_DUBBO_PROTOCOL_VERSION, service, version, method, argTypes.String()} {
+		encoder.Encode(v)
+	}
+	for _, arg := range args {
+		if err := encoder.Encode(arg); err != nil {
+			return nil, status.Errorf(codes.Internal, "transport: failed to encode dubbo2 argument: %v", err)
+		}
+	}
+	if err := encoder.Encode(reqAttachments); err != nil {
+		return nil, status.Errorf(codes.Internal, "transport: failed to encode dubbo2 attachments: %v", err)
+	}
+	pkg := encoder.Buffer()
+	if len(pkg) > hessian.DEFAULT_LEN {
+		return nil, status.Errorf(codes.ResourceExhausted, "transport: dubbo2 request of %d bytes exceeds %d", len(pkg), hessian.DEFAULT_LEN)
+	}
+	binary.BigEndian.PutUint32(pkg[12:], uint32(len(pkg)-hessian.HEADER_LENGTH))
+	return pkg, nil
+}
+
+func (t *dubboClient) decompress(s *Stream, data []byte) ([]byte, error) {
+	compressor := encoding.GetCompressor(s.sendCompress)
+	if compressor == nil {
+		return nil, status.Errorf(codes.Internal, "transport: no compressor registered for %q", s.sendCompress)
+	}
+	r, err := compressor.Decompress(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+var primitiveJavaTypes = map[string]string{
+	"void":    "V",
+	"boolean": "Z",
+	"byte":    "B",
+	"char":    "C",
+	"short":   "S",
+	"int":     "I",
+	"long":    "J",
+	"float":   "F",
+	"double":  "D",
+}
+
+// descOfJavaType returns the JVM descriptor of java class name @javaType,
+// e.g. Ljava/lang/String; for java.lang.String, it is the reverse of
+// javaTypeOfDesc.
+func descOfJavaType(javaType string) string {
+	if desc, ok := primitiveJavaTypes[javaType]; ok {
+		return desc
+	}
+	if _, ok := primitiveDescs[javaType]; ok || strings.HasPrefix(javaType, "[") {
+		return strings.Replace(javaType, ".", "/", -1)
+	}
+	return "L" + strings.Replace(javaType, ".", "/", -1) + ";"
+}
+
+func (t *dubboClient) write(pkg []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err := t.conn.Write(pkg)
+	return err
+}
+
+// reader reads the packages of the connection until it fails.
+func (t *dubboClient) reader() {
+	r := bufio.NewReader(t.conn)
+	for {
+		pkg, err := readDubboPackage(r, hessian.DEFAULT_LEN)
+		if err != nil {
+			t.Close(connectionErrorf(true, err, "transport: failed to read dubbo2 package: %v", err))
+			return
+		}
+		atomic.StoreInt64(&t.lastRead, time.Now().UnixNano())
+		if err := t.operatePackage(pkg); err != nil {
+			t.Close(connectionErrorf(true, err, "transport: failed to handle dubbo2 package: %v", err))
+			return
+		}
+	}
+}
+
+// operatePackage handles the dubbo2 package @pkg, it returns an error only
+// if the connection must be closed.
+func (t *dubboClient) operatePackage(pkg []byte) error {
+	codec := hessian.NewHessianCodec(bufio.NewReaderSize(bytes.NewReader(pkg), len(pkg)))
+	var header hessian.DubboHeader
+	if err := codec.ReadHeader(&header); err != nil {
+		return err
+	}
+	body := pkg[hessian.HEADER_LENGTH:]
+	if header.Type&hessian.PackageRequest != 0 {
+		if header.Type&hessian.PackageHeartbeat == 0 {
+			return fmt.Errorf("unexpected request %d from the provider", header.ID)
+		}
+		if event, _ := ghessian.DecodeGeneric(body); event == dubboReadonlyEvent {
+			t.handleReadonly()
+		}
+		if header.Type&hessian.PackageRequest_TwoWay == 0 {
+			return nil
+		}
+		return t.writeHeartbeat(header.ID, hessian.Response_OK)
+	}
+	if header.Type&hessian.PackageHeartbeat != 0 {
+		// the response of a heartbeat, which only updated lastRead
+		return nil
+	}
+
+	t.mu.Lock()
+	ds, ok := t.streams[uint32(header.ID)]
+	t.mu.Unlock()
+	if !ok {
+		// the response of a canceled or timed out stream
+		return nil
+	}
+	t.operateResponse(ds.s, header, body)
+	return nil
+}
+
+// operateResponse delivers the response to the request of @s with @header and
+// @body, and closes @s.
+func (t *dubboClient) operateResponse(s *Stream, header hessian.DubboHeader, body []byte) {
+	if header.ResponseStatus != hessian.Response_OK {
+		msg, err := ghessian.DecodeGeneric(body)
+		if err != nil {
+			msg = err.Error()
+		}
+		st := status.New(dubboStatusCode(header.ResponseStatus), fmt.Sprint(msg))
+		t.closeStream(s, io.EOF, st, nil)
+		return
+	}
+
+	decoder := hessian.NewDecoder(body)
+	flag, err := decoder.Decode()
+	respType, ok := flag.(int32)
+	if err == nil && !ok {
+		err = fmt.Errorf("response type %v is not an int", flag)
+	}
+	if err != nil {
+		st := status.Newf(codes.Internal, "transport: failed to decode dubbo2 response: %v", err)
+		t.closeStream(s, st.Err(), st, nil)
+		return
+	}
+	var (
+		result     []byte
+		exception  interface{}
+		attachment interface{}
+	)
+	switch respType {
+	case hessian.RESPONSE_VALUE, hessian.RESPONSE_VALUE_WITH_ATTACHMENTS:
+		// the result is re-encoded on its own for the codec, its objects
+		// must be of registered classes
+		var value interface{}
+		if value, err = decoder.Decode(); err == nil {
+			encoder := hessian.NewEncoder()
+			if err = encoder.Encode(value); err == nil {
+				result = encoder.Buffer()
+			}
+		}
+	case hessian.RESPONSE_NULL_VALUE, hessian.RESPONSE_NULL_VALUE_WITH_ATTACHMENTS:
+		result = []byte{'N'}
+	case hessian.RESPONSE_WITH_EXCEPTION, hessian.RESPONSE_WITH_EXCEPTION_WITH_ATTACHMENTS:
+		exception, err = decoder.Decode()
+	default:
+		st := status.Newf(codes.Internal, "transport: unknown dubbo2 response type %d", respType)
+		t.closeStream(s, st.Err(), st, nil)
+		return
+	}
+	if err == nil && isDubboResponseWithAttachments(respType) {
+		attachment, err = decoder.Decode()
+	}
+	if err != nil {
+		st := status.Newf(codes.Internal, "transport: failed to decode dubbo2 response: %v", err)
+		t.closeStream(s, st.Err(), st, nil)
+		return
+	}
+	trailer := dubboTrailer(attachment)
+
+	if result == nil {
+		st := dubboExceptionStatus(exception)
+		t.closeStream(s, io.EOF, st, trailer)
+		return
+	}
+	data, err := proto.Marshal(&proto_wrapper_api.TripleResponseWrapper{
+		SerializeType: DubboContentSubtype,
+		Data:          result,
+	})
+	if err != nil {
+		st := status.Newf(codes.Internal, "transport: failed to encode dubbo2 response: %v", err)
+		t.closeStream(s, st.Err(), st, trailer)
+		return
+	}
+	msg := make([]byte, 5+len(data))
+	binary.BigEndian.PutUint32(msg[1:], uint32(len(data)))
+	copy(msg[5:], data)
+
+	// the response has no headers, its attachments are the trailer
+	s.header = metadata.MD{}
+	s.headerValid = true
+	s.recvContentSubtype = DubboContentSubtype
+	if atomic.CompareAndSwapUint32(&s.headerChanClosed, 0, 1) {
+		close(s.headerChan)
+	}
+	if t.statsHandler != nil {
+		t.statsHandler.HandleRPC(s.ctx, &stats.InHeader{
+			Client:     true,
+			WireLength: hessian.HEADER_LENGTH,
+			Header:     metadata.MD{},
+		})
+	}
+	atomic.StoreUint32(&s.bytesReceived, 1)
+	s.write(recvMsg{buffer: bytes.NewBuffer(msg)})
+	t.closeStream(s, io.EOF, status.New(codes.OK, ""), trailer)
+}
+
+// isDubboResponseWithAttachments reports whether dubbo2 responses of
+// @respType end with attachments.
+func isDubboResponseWithAttachments(respType int32) bool {
+	switch respType {
+	case hessian.RESPONSE_VALUE_WITH_ATTACHMENTS, hessian.RESPONSE_NULL_VALUE_WITH_ATTACHMENTS, hessian.RESPONSE_WITH_EXCEPTION_WITH_ATTACHMENTS:
+		return true
+	}
+	return false
+}
+
+// dubboTrailer returns the trailer of the response with attachments
+// @attachment.
+func dubboTrailer(attachment interface{}) metadata.MD {
+	attachments, _ := attachment.(map[interface{}]interface{})
+	trailer := make(metadata.MD, len(attachments))
+	for k, v := range attachments {
+		key, ok := k.(string)
+		if !ok || v == nil {
+			continue
+		}
+		key = strings.ToLower(key)
+		if isReservedHeader(key) {
+			continue
+		}
+		trailer[key] = append(trailer[key], fmt.Sprint(v))
+	}
+	return trailer
+}
+
+// dubboExceptionStatus returns the Unknown status of java exception
+// @exception, which the hessian2 decoder decodes to a Throwabler, registering
+// the classes it does not know on the fly.
+func dubboExceptionStatus(exception interface{}) *status.Status {
+	throwable, ok := exception.(java_exception.Throwabler)
+	if !ok {
+		return status.New(codes.Unknown, fmt.Sprint(exception))
+	}
+	ex := &status.JavaException{ClassName: throwable.JavaClassName(), Message: throwable.Error()}
+	switch e := throwable.(type) {
+	case *java_exception.DubboGenericException:
+		// thrown by GenericService implementations, holding the class and
+		// message of the original exception
+		if e.ExceptionClass != "" {
+			ex.ClassName, ex.Message = e.ExceptionClass, e.ExceptionMessage
+		}
+	case *hessian.UnknownException:
+		ex.Message = e.DetailMessage
+	}
+	return status.FromJavaException(codes.Unknown, ex)
+}
+
+// dubboStatusCode returns the code of dubbo2 failures with @responseStatus.
+func dubboStatusCode(responseStatus byte) codes.Code {
+	switch responseStatus {
+	case hessian.Response_SERVICE_NOT_FOUND:
+		return codes.Unimplemented
+	case hessian.Response_CLIENT_TIMEOUT, hessian.Response_SERVER_TIMEOUT:
+		return codes.DeadlineExceeded
+	case hessian.Response_BAD_REQUEST:
+		return codes.InvalidArgument
+	case hessian.Response_SERVER_ERROR, hessian.Response_BAD_RESPONSE, hessian.Response_CLIENT_ERROR:
+		return codes.Internal
+	default:
+		return codes.Unknown
+	}
+}
+
+// handleReadonly handles the readonly event of a provider going away: new
+// streams are refused and the connection is closed once the active ones are
+// done.
+func (t *dubboClient) handleReadonly() {
+	t.mu.Lock()
+	if t.state != reachable {
+		t.mu.Unlock()
+		return
+	}
+	t.state = draining
+	close(t.goAway)
+	idle := len(t.streams) == 0
+	t.mu.Unlock()
+
+	t.onGoAway(GoAwayNoReason)
+	if idle {
+		t.Close(connectionErrorf(true, nil, "transport: the provider is readonly"))
+	}
+}
+
+func (t *dubboClient) writeHeartbeat(id int64, responseStatus byte) error {
+	pkg, err := hessian.NewHessianCodec(nil).Write(hessian.Service{}, hessian.DubboHeader{
+		SerialID:       dubboHessian2SerialID,
+		Type:           hessian.PackageHeartbeat,
+		ID:             id,
+		ResponseStatus: responseStatus,
+	}, []interface{}{})
+	if err != nil {
+		return err
+	}
+	return t.write(pkg)
+}
+
+// keepalive sends heartbeats and closes the connection when nothing has been
+// received for dubboHeartbeatTimeouts heartbeats.
+func (t *dubboClient) keepalive() {
+	ticker := time.NewTicker(t.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.errorChan:
+			return
+		case <-ticker.C:
+		}
+		if time.Since(time.Unix(0, atomic.LoadInt64(&t.lastRead))) > dubboHeartbeatTimeouts*t.heartbeat {
+			t.Close(connectionErrorf(true, nil, "transport: no heartbeat received for %v", dubboHeartbeatTimeouts*t.heartbeat))
+			return
+		}
+		// heartbeat requests have no response status
+		if err := t.writeHeartbeat(int64(atomic.AddUint32(&t.nextID, 1)), hessian.Zero); err != nil {
+			t.Close(connectionErrorf(true, err, "transport: failed to write dubbo2 heartbeat: %v", err))
+			return
+		}
+	}
+}
+
+// CloseStream clears the footprint of a stream when the stream is not needed
+// any more. The request of a sent stream can not be canceled, its response is
+// ignored.
+func (t *dubboClient) CloseStream(s *Stream, err error) {
+	t.closeStream(s, err, status.Convert(err), nil)
+}
+
+func (t *dubboClient) closeStream(s *Stream, err error, st *status.Status, trailer metadata.MD) {
+	if s.swapState(streamDone) == streamDone {
+		// If it was already done, return.  If multiple closeStream calls
+		// happen simultaneously, wait for the first to finish.
+		<-s.done
+		return
+	}
+	s.status = st
+	if len(trailer) > 0 {
+		s.trailer = trailer
+	}
+	if err != nil {
+		// This will unblock reads eventually.
+		s.write(recvMsg{err: err})
+	}
+	// If headerChan isn't closed, then close it.
+	if atomic.CompareAndSwapUint32(&s.headerChanClosed, 0, 1) {
+		s.noHeaders = true
+		close(s.headerChan)
+	}
+	if t.statsHandler != nil && err == io.EOF {
+		t.statsHandler.HandleRPC(s.ctx, &stats.InTrailer{
+			Client:  true,
+			Trailer: s.trailer.Copy(),
+		})
+	}
+
+	t.mu.Lock()
+	if t.streams != nil {
+		delete(t.streams, s.id)
+	}
+	drained := t.state == draining && len(t.streams) == 0
+	t.mu.Unlock()
+	close(s.done)
+	if s.doneFunc != nil {
+		s.doneFunc()
+	}
+	if drained {
+		t.Close(connectionErrorf(true, nil, "transport: the connection is drained"))
+	}
+}
+
+// Close kicks off the shutdown process of the transport, the pending streams
+// fail with @err.
+func (t *dubboClient) Close(err error) {
+	t.mu.Lock()
+	if t.state == closing {
+		t.mu.Unlock()
+		return
+	}
+	// Call t.onClose before setting the state to closing to prevent the client
+	// from attempting to create new streams ASAP.
+	t.onClose()
+	t.state = closing
+	streams := t.streams
+	t.streams = nil
+	t.mu.Unlock()
+
+	t.conn.Close()
+	close(t.errorChan)
+	st := status.New(codes.Unavailable, err.Error())
+	for _, ds := range streams {
+		t.closeStream(ds.s, err, st, nil)
+	}
+	if t.statsHandler != nil {
+		connEnd := &stats.ConnEnd{
+			Client: true,
+		}
+		t.statsHandler.HandleConn(context.Background(), connEnd)
+	}
+}
+
+// GracefulClose closes the transport once the active streams are done.
+func (t *dubboClient) GracefulClose() {
+	t.mu.Lock()
+	if t.state == closing {
+		t.mu.Unlock()
+		return
+	}
+	t.state = draining
+	idle := len(t.streams) == 0
+	t.mu.Unlock()
+	if idle {
+		t.Close(ErrConnClosing)
+	}
+}
+
+func (t *dubboClient) Error() <-chan struct{} {
+	return t.errorChan
+}
+
+func (t *dubboClient) GoAway() <-chan struct{} {
+	return t.goAway
+}
+
+func (t *dubboClient) GetGoAwayReason() (GoAwayReason, string) {
+	select {
+	case <-t.goAway:
+		return GoAwayNoReason, "the provider is readonly"
+	default:
+		return GoAwayInvalid, ""
+	}
+}
+
+func (t *dubboClient) RemoteAddr() net.Addr {
+	return t.remoteAddr
+}
+
+func (t *dubboClient) IncrMsgSent() {}
+
+func (t *dubboClient) IncrMsgRecv() {}
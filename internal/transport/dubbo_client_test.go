@@ -0,0 +1,234 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+import (
+	hessian "github.com/apache/dubbo-go-hessian2"
+	"github.com/apache/dubbo-go-hessian2/java_exception"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/encoding/proto_wrapper_api"
+	"github.com/dubbogo/grpc-go/metadata"
+	"github.com/dubbogo/grpc-go/resolver"
+	"github.com/dubbogo/grpc-go/resolver/protocol"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+func (s) TestDubboClientTransport(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	assert.Nil(t, err)
+	defer lis.Close()
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		st, _ := NewDubboServerTransport(conn, &ServerConfig{})
+		st.HandleStreams(func(s *Stream) {
+			md, _ := metadata.FromIncomingContext(s.Context())
+			if s.Method() != "/org.example.Greeter/SayHello" {
+				st.WriteStatus(s, status.Newf(codes.NotFound, "unknown method %s", s.Method()))
+				return
+			}
+			s.SetTrailer(md)
+			st.WriteStatus(s, status.FromJavaException(codes.Unknown, &status.JavaException{
+				ClassName: "java.lang.IllegalStateException",
+				Message:   strings.Join(md.Get(DubboServiceVersionHeader), ","),
+			}))
+		}, func(ctx context.Context, _ string) context.Context { return ctx })
+	}()
+
+	addr := protocol.Set(resolver.Address{Addr: lis.Addr().String()}, protocol.Dubbo)
+	ct, err := NewClientTransport(context.Background(), context.Background(), addr, ConnectOptions{}, func() {}, func(GoAwayReason) {}, func() {})
+	assert.Nil(t, err)
+	defer ct.Close(ErrConnClosing)
+
+	_, err = ct.NewStream(context.Background(), &CallHdr{Method: "/org.example.Greeter/SayHello"})
+	assert.Equal(t, codes.Internal, status.Code(err.(*NewStreamError).Err))
+
+	call := func(method string) *Stream {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-trace", "abc", DubboServiceVersionHeader, "1.0.0")
+		s, err := ct.NewStream(ctx, &CallHdr{Method: method, ContentSubtype: DubboContentSubtype})
+		assert.Nil(t, err)
+		encoder := hessian.NewEncoder()
+		assert.Nil(t, encoder.Encode("world"))
+		data, err := proto.Marshal(&proto_wrapper_api.TripleRequestWrapper{
+			SerializeType: DubboContentSubtype,
+			Args:          [][]byte{encoder.Buffer()},
+			ArgTypes:      []string{"java.lang.String"},
+		})
+		assert.Nil(t, err)
+		assert.Nil(t, ct.Write(s, []byte{0, 0, 0, 0, 0}, data, &Options{Last: true}))
+		_, err = s.Read(make([]byte, 5))
+		assert.Equal(t, io.EOF, err)
+		return s
+	}
+
+	s := call("/org.example.Greeter/SayHello")
+	assert.Equal(t, codes.Unknown, s.Status().Code())
+	ex, ok := s.Status().JavaException()
+	assert.True(t, ok)
+	assert.Equal(t, "java.lang.IllegalStateException", ex.ClassName)
+	assert.Equal(t, "1.0.0", ex.Message)
+	assert.Equal(t, []string{"abc"}, s.Trailer().Get("x-trace"))
+
+	s = call("/org.example.Greeter/SayGoodbye")
+	assert.Equal(t, codes.Unimplemented, s.Status().Code())
+}
+
+// dubboResponseBodies are the bodies of the responses to the methods of
+// TestDubboClientResponses, with the response type first.
+var dubboResponseBodies = map[string][]interface{}{
+	"Value":                    {[]byte{'I', 0, 0, 0, 1}, "hello"},
+	"ValueWithAttachments":     {hessian.RESPONSE_VALUE_WITH_ATTACHMENTS, []interface{}{"a", int32(1)}, map[string]string{"x-trace": "abc"}},
+	"NullValueWithAttachments": {hessian.RESPONSE_NULL_VALUE_WITH_ATTACHMENTS, map[string]string{"x-trace": "abc"}},
+	"Exception":                {hessian.RESPONSE_WITH_EXCEPTION, java_exception.NewIllegalStateException("boom")},
+	"ExceptionWithAttachments": {hessian.RESPONSE_WITH_EXCEPTION_WITH_ATTACHMENTS, java_exception.NewIllegalStateException("boom"), map[string]string{"x-trace": "abc"}},
+	"Truncated":                {hessian.RESPONSE_VALUE_WITH_ATTACHMENTS},
+}
+
+// serveDubboResponses answers the requests of @conn with dubboResponseBodies.
+func serveDubboResponses(t *testing.T, conn net.Conn) {
+	r := bufio.NewReader(conn)
+	for {
+		pkg, err := readDubboPackage(r, hessian.DEFAULT_LEN)
+		if err != nil {
+			return
+		}
+		if pkg[2]&hessian.FLAG_EVENT != 0 {
+			continue
+		}
+		// the dubbo version, the path and the version precede the method
+		decoder := hessian.NewDecoder(pkg[hessian.HEADER_LENGTH:])
+		var method interface{}
+		for i := 0; i < 4; i++ {
+			method, err = decoder.Decode()
+			assert.Nil(t, err)
+		}
+		encoder := hessian.NewEncoder()
+		var body []byte
+		for _, v := range dubboResponseBodies[method.(string)] {
+			if raw, ok := v.([]byte); ok {
+				body = append(body, raw...)
+				continue
+			}
+			assert.Nil(t, encoder.Encode(v))
+			body = append(body, encoder.Buffer()...)
+			encoder.Clean()
+		}
+		header := []byte{hessian.MAGIC_HIGH, hessian.MAGIC_LOW, dubboHessian2SerialID, hessian.Response_OK}
+		header = append(header, pkg[4:12]...)
+		header = append(header, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(header[12:], uint32(len(body)))
+		if _, err := conn.Write(append(header, body...)); err != nil {
+			return
+		}
+	}
+}
+
+func (s) TestDubboClientResponses(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	assert.Nil(t, err)
+	defer lis.Close()
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serveDubboResponses(t, conn)
+	}()
+
+	addr := protocol.Set(resolver.Address{Addr: lis.Addr().String()}, protocol.Dubbo)
+	ct, err := NewClientTransport(context.Background(), context.Background(), addr, ConnectOptions{}, func() {}, func(GoAwayReason) {}, func() {})
+	assert.Nil(t, err)
+	defer ct.Close(ErrConnClosing)
+
+	// call returns the stream of @method and its result, if any
+	call := func(method string) (*Stream, interface{}) {
+		s, err := ct.NewStream(context.Background(), &CallHdr{Method: "/org.example.Greeter/" + method, ContentSubtype: DubboContentSubtype})
+		assert.Nil(t, err)
+		data, err := proto.Marshal(&proto_wrapper_api.TripleRequestWrapper{SerializeType: DubboContentSubtype})
+		assert.Nil(t, err)
+		assert.Nil(t, ct.Write(s, []byte{0, 0, 0, 0, 0}, data, &Options{Last: true}))
+
+		hdr := make([]byte, 5)
+		if _, err := io.ReadFull(s, hdr); err != nil {
+			return s, nil
+		}
+		msg := make([]byte, binary.BigEndian.Uint32(hdr[1:]))
+		_, err = io.ReadFull(s, msg)
+		assert.Nil(t, err)
+		rsp := &proto_wrapper_api.TripleResponseWrapper{}
+		assert.Nil(t, proto.Unmarshal(msg, rsp))
+		result, err := hessian.NewDecoder(rsp.Data).Decode()
+		assert.Nil(t, err)
+		_, err = s.Read(hdr)
+		assert.Equal(t, io.EOF, err)
+		return s, result
+	}
+
+	s, result := call("Value")
+	assert.Equal(t, codes.OK, s.Status().Code())
+	assert.Equal(t, "hello", result)
+	assert.Empty(t, s.Trailer())
+
+	s, result = call("ValueWithAttachments")
+	assert.Equal(t, codes.OK, s.Status().Code())
+	assert.Equal(t, []interface{}{"a", int32(1)}, result)
+	assert.Equal(t, []string{"abc"}, s.Trailer().Get("x-trace"))
+
+	s, result = call("NullValueWithAttachments")
+	assert.Equal(t, codes.OK, s.Status().Code())
+	assert.Nil(t, result)
+	assert.Equal(t, []string{"abc"}, s.Trailer().Get("x-trace"))
+
+	for _, method := range []string{"Exception", "ExceptionWithAttachments"} {
+		s, _ = call(method)
+		assert.Equal(t, codes.Unknown, s.Status().Code())
+		ex, ok := s.Status().JavaException()
+		assert.True(t, ok)
+		assert.Equal(t, "java.lang.IllegalStateException", ex.ClassName)
+		assert.Equal(t, "boom", ex.Message)
+		if method == "ExceptionWithAttachments" {
+			assert.Equal(t, []string{"abc"}, s.Trailer().Get("x-trace"))
+		} else {
+			assert.Empty(t, s.Trailer())
+		}
+	}
+
+	s, _ = call("Truncated")
+	assert.Equal(t, codes.Internal, s.Status().Code())
+	assert.Contains(t, s.Status().Message(), "failed to decode dubbo2 response")
+}
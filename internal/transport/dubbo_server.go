@@ -0,0 +1,629 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// This file is the implementation of a server transport speaking the dubbo2
+// protocol, i.e. the dubbo:// binary protocol with hessian2 bodies. It is the
+// implementation of *grpc.Server.ServeDubbo.
+
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+import (
+	hessian "github.com/apache/dubbo-go-hessian2"
+	"github.com/apache/dubbo-go-hessian2/java_exception"
+
+	"github.com/golang/protobuf/proto"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/credentials"
+	"github.com/dubbogo/grpc-go/encoding"
+	"github.com/dubbogo/grpc-go/encoding/proto_wrapper_api"
+	"github.com/dubbogo/grpc-go/metadata"
+	"github.com/dubbogo/grpc-go/peer"
+	"github.com/dubbogo/grpc-go/stats"
+	"github.com/dubbogo/grpc-go/status"
+	"github.com/dubbogo/grpc-go/tap"
+)
+
+const (
+	// DubboContentSubtype is the content-subtype of the streams of dubbo2
+	// requests, whose arguments are re-encoded in a TripleRequestWrapper.
+	DubboContentSubtype = "hessian2"
+	// DubboServiceGroupHeader and DubboServiceVersionHeader are the metadata
+	// keys the group and version of dubbo2 requests are copied to, they must
+	// match the triple service group and version headers.
+	DubboServiceGroupHeader   = "tri-service-group"
+	DubboServiceVersionHeader = "tri-service-version"
+
+	// dubboHessian2SerialID is the serialization id of hessian2.
+	dubboHessian2SerialID = 2
+	// dubboReadonlyEvent is the body of the event telling clients that the
+	// server does not accept new requests.
+	dubboReadonlyEvent = "R"
+)
+
+// dubboRequest is the state of the stream of a dubbo2 request.
+type dubboRequest struct {
+	header       hessian.DubboHeader
+	dubboVersion string
+	twoWay       bool
+	// rsp is the hessian2 encoded result written by Write.
+	rsp     []byte
+	written bool
+}
+
+// dubboServer is a ServerTransport serving dubbo2 requests of a connection.
+// Every request is a unary stream whose only message is a
+// TripleRequestWrapper holding the arguments, responses are decoded from
+// TripleResponseWrapper messages.
+type dubboServer struct {
+	conn        net.Conn
+	authInfo    credentials.AuthInfo
+	stats       stats.Handler
+	inTapHandle tap.ServerInHandle
+	// maxBodyLen is the max body length of the packages read.
+	maxBodyLen uint32
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// writeMu serializes the writes of packages.
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	requests map[*Stream]*dubboRequest
+	draining bool
+	closed   bool
+}
+
+// NewDubboServerTransport creates a ServerTransport serving the dubbo2
+// protocol on conn with configuration options from config. Flow control,
+// keepalive and header list options do not apply to dubbo2. It fails if no
+// codec is registered for DubboContentSubtype, which importing
+// github.com/dubbogo/grpc-go/encoding/hessian does.
+func NewDubboServerTransport(conn net.Conn, config *ServerConfig) (ServerTransport, error) {
+	if encoding.GetCodec(DubboContentSubtype) == nil {
+		return nil, connectionErrorf(false, nil, "transport: no codec registered for content-subtype %q, import github.com/dubbogo/grpc-go/encoding/hessian", DubboContentSubtype)
+	}
+	var authInfo credentials.AuthInfo
+	if config.Credentials != nil {
+		rawConn := conn
+		var err error
+		conn, authInfo, err = config.Credentials.ServerHandshake(rawConn)
+		if err != nil {
+			if err == credentials.ErrConnDispatched || err == io.EOF {
+				return nil, err
+			}
+			return nil, connectionErrorf(false, err, "ServerHandshake(%q) failed: %v", rawConn.RemoteAddr(), err)
+		}
+	}
+	maxBodyLen := uint32(hessian.DEFAULT_LEN)
+	if config.MaxReceiveMessageSize > 0 && config.MaxReceiveMessageSize < hessian.DEFAULT_LEN {
+		maxBodyLen = uint32(config.MaxReceiveMessageSize)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &dubboServer{
+		conn:        conn,
+		authInfo:    authInfo,
+		stats:       config.StatsHandler,
+		inTapHandle: config.InTapHandle,
+		maxBodyLen:  maxBodyLen,
+		ctx:         ctx,
+		cancel:      cancel,
+		requests:    make(map[*Stream]*dubboRequest),
+	}, nil
+}
+
+func (t *dubboServer) HandleStreams(handle func(*Stream), traceCtx func(context.Context, string) context.Context) {
+	defer t.Close()
+	reader := bufio.NewReader(t.conn)
+	for {
+		pkg, err := readDubboPackage(reader, t.maxBodyLen)
+		if st, ok := status.FromError(err); ok && st.Code() == codes.ResourceExhausted {
+			if err = t.rejectPackage(reader, pkg, st); err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				logger.Warningf("transport: dubboServer failed to read package from %v: %v", t.conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if err := t.operatePackage(pkg, handle, traceCtx); err != nil {
+			logger.Warningf("transport: dubboServer failed to serve package from %v: %v", t.conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// readDubboPackage reads the header and the body of a dubbo2 package. If the
+// body is longer than @maxBodyLen, it returns the header only and a
+// ResourceExhausted status error, without reading the body.
+func readDubboPackage(r io.Reader, maxBodyLen uint32) ([]byte, error) {
+	header := make([]byte, hessian.HEADER_LENGTH)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != hessian.MAGIC_HIGH || header[1] != hessian.MAGIC_LOW {
+		return nil, fmt.Errorf("illegal magic 0x%x%x", header[0], header[1])
+	}
+	bodyLen := binary.BigEndian.Uint32(header[12:])
+	if bodyLen > maxBodyLen {
+		return header, status.Errorf(codes.ResourceExhausted, "grpc: received message larger than max (%d vs. %d)", bodyLen, maxBodyLen)
+	}
+	pkg := make([]byte, hessian.HEADER_LENGTH+int(bodyLen))
+	copy(pkg, header)
+	if _, err := io.ReadFull(r, pkg[hessian.HEADER_LENGTH:]); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// rejectPackage skips the body of the package with @header read from @r,
+// which is too long to be served, and answers it with @st if it is a two-way
+// request. It returns an error only if the connection must be closed.
+func (t *dubboServer) rejectPackage(r io.Reader, header []byte, st *status.Status) error {
+	bodyLen := binary.BigEndian.Uint32(header[12:])
+	if _, err := io.CopyN(ioutil.Discard, r, int64(bodyLen)); err != nil {
+		return err
+	}
+	flag := header[2]
+	if flag&hessian.FLAG_REQUEST == 0 || flag&hessian.FLAG_EVENT != 0 {
+		return nil
+	}
+	reqHeader := hessian.DubboHeader{ID: int64(binary.BigEndian.Uint64(header[4:12]))}
+	return t.writeError(reqHeader, flag&hessian.FLAG_TWOWAY != 0, hessian.Response_BAD_REQUEST, st.Message())
+}
+
+// operatePackage serves dubbo2 package @pkg, it returns an error only if the
+// connection must be closed.
+func (t *dubboServer) operatePackage(pkg []byte, handle func(*Stream), traceCtx func(context.Context, string) context.Context) error {
+	codec := hessian.NewHessianCodec(bufio.NewReaderSize(bytes.NewReader(pkg), len(pkg)))
+	var header hessian.DubboHeader
+	if err := codec.ReadHeader(&header); err != nil {
+		return err
+	}
+	if header.Type&hessian.PackageRequest == 0 {
+		// responses to the readonly event, if any
+		return nil
+	}
+	if header.Type&hessian.PackageHeartbeat != 0 {
+		if header.Type&hessian.PackageRequest_TwoWay == 0 {
+			return nil
+		}
+		return t.writePackage(hessian.DubboHeader{
+			SerialID:       header.SerialID,
+			Type:           hessian.PackageHeartbeat,
+			ID:             header.ID,
+			ResponseStatus: hessian.Response_OK,
+		}, nil)
+	}
+
+	twoWay := header.Type&hessian.PackageRequest_TwoWay != 0
+	if header.SerialID != dubboHessian2SerialID {
+		return t.writeError(header, twoWay, hessian.Response_BAD_REQUEST,
+			fmt.Sprintf("unsupported serialization id %d, only hessian2 is supported", header.SerialID))
+	}
+	req := make([]interface{}, 7)
+	if err := codec.ReadBody(req); err != nil {
+		return t.writeError(header, twoWay, hessian.Response_BAD_REQUEST, fmt.Sprintf("failed to decode request: %v", err))
+	}
+	dubboVersion, _ := req[0].(string)
+	path, _ := req[1].(string)
+	serviceVersion, _ := req[2].(string)
+	method, _ := req[3].(string)
+	argTypes, _ := req[4].(string)
+	args, _ := req[5].([]interface{})
+	attachments, _ := req[6].(map[string]string)
+
+	msg, err := dubboRequestMessage(argTypes, args)
+	if err != nil {
+		return t.writeError(header, twoWay, hessian.Response_BAD_REQUEST, fmt.Sprintf("failed to encode arguments: %v", err))
+	}
+
+	ctx := t.ctx
+	var cancel context.CancelFunc
+	if timeout, err := strconv.ParseInt(attachments[hessian.TIMEOUT_KEY], 10, 64); err == nil && timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Millisecond)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	s := &Stream{
+		id:             uint32(header.ID),
+		st:             t,
+		method:         "/" + path + "/" + method,
+		buf:            newRecvBuffer(),
+		requestRead:    func(int) {},
+		cancel:         cancel,
+		contentSubtype: DubboContentSubtype,
+	}
+	pr := &peer.Peer{
+		Addr:     t.conn.RemoteAddr(),
+		AuthInfo: t.authInfo,
+	}
+	ctx = peer.NewContext(ctx, pr)
+	ctx = metadata.NewIncomingContext(ctx, dubboMetadata(attachments, serviceVersion))
+	s.ctx = traceCtx(ctx, s.method)
+	if t.inTapHandle != nil {
+		if s.ctx, err = t.inTapHandle(s.ctx, &tap.Info{FullMethodName: s.method}); err != nil {
+			cancel()
+			st, ok := status.FromError(err)
+			if !ok {
+				st = status.New(codes.PermissionDenied, err.Error())
+			}
+			return t.writeError(header, twoWay, hessian.Response_SERVICE_ERROR, st.Message())
+		}
+	}
+	if t.stats != nil {
+		s.ctx = t.stats.TagRPC(s.ctx, &stats.RPCTagInfo{FullMethodName: s.method})
+		t.stats.HandleRPC(s.ctx, &stats.InHeader{
+			FullMethod: s.method,
+			RemoteAddr: t.conn.RemoteAddr(),
+			Header:     dubboMetadata(attachments, serviceVersion),
+		})
+	}
+	s.trReader = &transportReader{
+		reader:        &recvBufferReader{ctx: s.ctx, ctxDone: s.ctx.Done(), recv: s.buf, freeBuffer: func(*bytes.Buffer) {}},
+		windowHandler: func(int) {},
+	}
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		cancel()
+		return ErrConnClosing
+	}
+	t.requests[s] = &dubboRequest{
+		header:       header,
+		dubboVersion: dubboVersion,
+		twoWay:       twoWay,
+	}
+	t.mu.Unlock()
+
+	s.buf.put(recvMsg{buffer: bytes.NewBuffer(msg)})
+	s.buf.put(recvMsg{err: io.EOF})
+	handle(s)
+	return nil
+}
+
+// dubboRequestMessage returns the length-prefixed TripleRequestWrapper of
+// @args, which are re-encoded one by one. @argTypes are the JVM descriptors of
+// the argument types, e.g. "Ljava/lang/String;I".
+func dubboRequestMessage(argTypes string, args []interface{}) ([]byte, error) {
+	descs := hessian.DescRegex.FindAllString(argTypes, -1)
+	if len(descs) != len(args) {
+		return nil, fmt.Errorf("%d argument types for %d arguments", len(descs), len(args))
+	}
+	wrapper := &proto_wrapper_api.TripleRequestWrapper{
+		SerializeType: DubboContentSubtype,
+		Args:          make([][]byte, 0, len(args)),
+		ArgTypes:      make([]string, 0, len(args)),
+	}
+	for i, arg := range args {
+		encoder := hessian.NewEncoder()
+		if err := encoder.Encode(arg); err != nil {
+			return nil, err
+		}
+		wrapper.Args = append(wrapper.Args, encoder.Buffer())
+		wrapper.ArgTypes = append(wrapper.ArgTypes, javaTypeOfDesc(descs[i]))
+	}
+	data, err := proto.Marshal(wrapper)
+	if err != nil {
+		return nil, err
+	}
+	msg := make([]byte, 5+len(data))
+	binary.BigEndian.PutUint32(msg[1:], uint32(len(data)))
+	copy(msg[5:], data)
+	return msg, nil
+}
+
+var primitiveDescs = map[string]string{
+	"V": "void",
+	"Z": "boolean",
+	"B": "byte",
+	"C": "char",
+	"S": "short",
+	"I": "int",
+	"J": "long",
+	"F": "float",
+	"D": "double",
+}
+
+// javaTypeOfDesc returns the java class name of JVM descriptor @desc, e.g.
+// java.lang.String for Ljava/lang/String; or [I for [I.
+func javaTypeOfDesc(desc string) string {
+	if name, ok := primitiveDescs[desc]; ok {
+		return name
+	}
+	if strings.HasPrefix(desc, "L") && strings.HasSuffix(desc, ";") {
+		desc = desc[1 : len(desc)-1]
+	}
+	return strings.Replace(desc, "/", ".", -1)
+}
+
+// dubboMetadata returns the incoming metadata of a request with
+// @attachments, including its group and @serviceVersion for service lookup.
+func dubboMetadata(attachments map[string]string, serviceVersion string) metadata.MD {
+	md := metadata.MD{"content-type": []string{"application/grpc+" + DubboContentSubtype}}
+	for k, v := range attachments {
+		k = strings.ToLower(k)
+		if isReservedHeader(k) {
+			continue
+		}
+		md[k] = append(md[k], v)
+	}
+	if group := attachments[hessian.GROUP_KEY]; group != "" {
+		md[DubboServiceGroupHeader] = []string{group}
+	}
+	if serviceVersion == "" {
+		serviceVersion = attachments[hessian.VERSION_KEY]
+	}
+	if serviceVersion != "" {
+		md[DubboServiceVersionHeader] = []string{serviceVersion}
+	}
+	return md
+}
+
+func (t *dubboServer) request(s *Stream) (*dubboRequest, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, ErrConnClosing
+	}
+	req, ok := t.requests[s]
+	if !ok {
+		return nil, ErrIllegalHeaderWrite
+	}
+	return req, nil
+}
+
+// WriteHeader only records @md, which is sent as the attachments of the
+// response.
+func (t *dubboServer) WriteHeader(s *Stream, md metadata.MD) error {
+	if _, err := t.request(s); err != nil {
+		return err
+	}
+	if err := s.SetHeader(md); err != nil {
+		return err
+	}
+	s.updateHeaderSent()
+	return nil
+}
+
+// Write records the result of the request of @s, dubbo2 responses carry
+// exactly one result.
+func (t *dubboServer) Write(s *Stream, hdr []byte, data []byte, opts *Options) error {
+	req, err := t.request(s)
+	if err != nil {
+		return err
+	}
+	s.updateHeaderSent()
+	if req.written {
+		return status.Error(codes.Unimplemented, "transport: dubbo2 responses can not carry more than one message")
+	}
+	if len(hdr) > 0 && hdr[0] == 1 {
+		if data, err = t.decompress(s, data); err != nil {
+			return err
+		}
+	}
+	wrapper := &proto_wrapper_api.TripleResponseWrapper{}
+	if err := proto.Unmarshal(data, wrapper); err != nil {
+		return status.Errorf(codes.Internal, "transport: dubbo2 response is not a TripleResponseWrapper: %v", err)
+	}
+	if wrapper.SerializeType != "" && wrapper.SerializeType != DubboContentSubtype {
+		return status.Errorf(codes.Internal, "transport: dubbo2 response is serialized by %s instead of hessian2", wrapper.SerializeType)
+	}
+	req.rsp = wrapper.Data
+	req.written = true
+	return nil
+}
+
+func (t *dubboServer) decompress(s *Stream, data []byte) ([]byte, error) {
+	compressor := encoding.GetCompressor(s.sendCompress)
+	if compressor == nil {
+		return nil, status.Errorf(codes.Internal, "transport: no compressor registered for %q", s.sendCompress)
+	}
+	r, err := compressor.Decompress(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+// WriteStatus sends the response of the request of @s, OK statuses carry the
+// result written by Write, statuses of java exceptions and Unknown ones carry
+// an exception and other codes are mapped to dubbo2 response statuses.
+func (t *dubboServer) WriteStatus(s *Stream, st *status.Status) error {
+	t.mu.Lock()
+	req, ok := t.requests[s]
+	delete(t.requests, s)
+	closeConn := t.draining && len(t.requests) == 0
+	t.mu.Unlock()
+	s.cancel()
+	if closeConn {
+		defer t.Close()
+	}
+	if !ok {
+		return nil
+	}
+	s.updateHeaderSent()
+	if t.stats != nil {
+		t.stats.HandleRPC(s.Context(), &stats.OutTrailer{
+			Trailer: s.trailer.Copy(),
+		})
+	}
+	if !req.twoWay {
+		return nil
+	}
+
+	attachments := dubboAttachments(s)
+	// the response carries attachments only if the consumer supports them
+	attachments[hessian.DUBBO_VERSION_KEY] = req.dubboVersion
+	header := hessian.DubboHeader{
+		SerialID:       req.header.SerialID,
+		Type:           hessian.PackageResponse,
+		ID:             req.header.ID,
+		ResponseStatus: hessian.Response_OK,
+	}
+
+	if ex, ok := st.JavaException(); ok {
+		return t.writePackage(header, hessian.NewResponse(nil, java_exception.NewDubboGenericException(ex.ClassName, ex.Message), attachments))
+	}
+	switch st.Code() {
+	case codes.OK:
+		var result interface{}
+		if len(req.rsp) > 0 {
+			var err error
+			if result, err = hessian.NewDecoder(req.rsp).Decode(); err != nil {
+				return t.writeError(req.header, true, hessian.Response_SERVER_ERROR, fmt.Sprintf("failed to decode result: %v", err))
+			}
+		}
+		return t.writePackage(header, hessian.NewResponse(result, nil, attachments))
+	case codes.Unknown:
+		return t.writePackage(header, hessian.NewResponse(nil, java_exception.NewThrowable(st.Message()), attachments))
+	default:
+		return t.writeError(req.header, true, dubboResponseStatus(st.Code()), st.Message())
+	}
+}
+
+// dubboAttachments returns the header and trailer metadata of @s as response
+// attachments.
+func dubboAttachments(s *Stream) map[string]string {
+	attachments := make(map[string]string)
+	s.hdrMu.Lock()
+	defer s.hdrMu.Unlock()
+	for _, md := range []metadata.MD{s.header, s.trailer} {
+		for k, vv := range md {
+			if isReservedHeader(k) || len(vv) == 0 {
+				continue
+			}
+			attachments[k] = vv[0]
+		}
+	}
+	return attachments
+}
+
+// dubboResponseStatus returns the dubbo2 response status of failures with @code.
+func dubboResponseStatus(code codes.Code) byte {
+	switch code {
+	case codes.Unimplemented, codes.NotFound:
+		return hessian.Response_SERVICE_NOT_FOUND
+	case codes.DeadlineExceeded:
+		return hessian.Response_SERVER_TIMEOUT
+	case codes.InvalidArgument:
+		return hessian.Response_BAD_REQUEST
+	case codes.Internal:
+		return hessian.Response_SERVER_ERROR
+	default:
+		return hessian.Response_SERVICE_ERROR
+	}
+}
+
+// writeError sends a response with failure @responseStatus and error message
+// @msg to request @reqHeader, unless it is one-way.
+func (t *dubboServer) writeError(reqHeader hessian.DubboHeader, twoWay bool, responseStatus byte, msg string) error {
+	if !twoWay {
+		return nil
+	}
+	return t.writePackage(hessian.DubboHeader{
+		SerialID:       dubboHessian2SerialID,
+		Type:           hessian.PackageResponse,
+		ID:             reqHeader.ID,
+		ResponseStatus: responseStatus,
+	}, msg)
+}
+
+func (t *dubboServer) writePackage(header hessian.DubboHeader, body interface{}) error {
+	pkg, err := hessian.NewHessianCodec(nil).Write(hessian.Service{}, header, body)
+	if err != nil {
+		return err
+	}
+	return t.write(pkg)
+}
+
+func (t *dubboServer) write(pkg []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err := t.conn.Write(pkg)
+	return err
+}
+
+func (t *dubboServer) Close() {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.closed = true
+	requests := t.requests
+	t.requests = nil
+	t.mu.Unlock()
+
+	t.cancel()
+	t.conn.Close()
+	for s := range requests {
+		s.cancel()
+	}
+}
+
+func (t *dubboServer) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
+
+// Drain sends the readonly event, so that consumers stop sending requests,
+// and closes the connection once the pending requests are done.
+func (t *dubboServer) Drain() {
+	t.mu.Lock()
+	if t.draining || t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.draining = true
+	idle := len(t.requests) == 0
+	t.mu.Unlock()
+
+	header := [hessian.HEADER_LENGTH]byte{hessian.MAGIC_HIGH, hessian.MAGIC_LOW, hessian.FLAG_REQUEST | hessian.FLAG_EVENT | dubboHessian2SerialID}
+	encoder := hessian.NewEncoder()
+	encoder.Append(header[:])
+	encoder.Encode(dubboReadonlyEvent)
+	pkg := encoder.Buffer()
+	binary.BigEndian.PutUint32(pkg[12:], uint32(len(pkg)-hessian.HEADER_LENGTH))
+	if err := t.write(pkg); err != nil || idle {
+		t.Close()
+	}
+}
+
+func (t *dubboServer) IncrMsgSent() {}
+
+func (t *dubboServer) IncrMsgRecv() {}
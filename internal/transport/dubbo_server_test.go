@@ -0,0 +1,166 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	hessian "github.com/apache/dubbo-go-hessian2"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/encoding/proto_wrapper_api"
+	"github.com/dubbogo/grpc-go/metadata"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+// dubboCall sends a two-way dubbo2 request to @conn and returns the response.
+func dubboCall(t *testing.T, conn net.Conn, r *bufio.Reader, header hessian.DubboHeader, method string, args []interface{}) (hessian.DubboHeader, *hessian.Response) {
+	pkg, err := hessian.NewHessianCodec(nil).Write(
+		hessian.Service{Path: "org.example.Greeter", Version: "1.0.0", Method: method, Timeout: time.Second},
+		header, hessian.NewRequest(args, map[string]string{"group": "g1", "x-trace": "abc"}))
+	assert.Nil(t, err)
+	_, err = conn.Write(pkg)
+	assert.Nil(t, err)
+
+	codec := hessian.NewHessianCodec(r)
+	var rspHeader hessian.DubboHeader
+	assert.Nil(t, codec.ReadHeader(&rspHeader))
+	var reply interface{}
+	rsp := &hessian.Response{RspObj: &reply}
+	assert.Nil(t, codec.ReadBody(rsp))
+	rsp.RspObj = reply
+	return rspHeader, rsp
+}
+
+func (s) TestDubboServerTransport(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	st, err := NewDubboServerTransport(server, &ServerConfig{})
+	assert.Nil(t, err)
+
+	go st.HandleStreams(func(s *Stream) {
+		md, _ := metadata.FromIncomingContext(s.Context())
+		_, hasDeadline := s.Context().Deadline()
+		if s.Method() != "/org.example.Greeter/SayHello" || !hasDeadline {
+			st.WriteStatus(s, status.Newf(codes.NotFound, "unknown method %s", s.Method()))
+			return
+		}
+		msg := make([]byte, 5)
+		_, err := io.ReadFull(s, msg)
+		assert.Nil(t, err)
+		msg = make([]byte, int(msg[1])<<24|int(msg[2])<<16|int(msg[3])<<8|int(msg[4]))
+		_, err = io.ReadFull(s, msg)
+		assert.Nil(t, err)
+		req := &proto_wrapper_api.TripleRequestWrapper{}
+		assert.Nil(t, proto.Unmarshal(msg, req))
+		assert.Equal(t, []string{"java.lang.String", "int"}, req.ArgTypes)
+		name, err := hessian.NewDecoder(req.Args[0]).Decode()
+		assert.Nil(t, err)
+
+		encoder := hessian.NewEncoder()
+		assert.Nil(t, encoder.Encode("hello "+name.(string)))
+		data, err := proto.Marshal(&proto_wrapper_api.TripleResponseWrapper{SerializeType: "hessian2", Data: encoder.Buffer()})
+		assert.Nil(t, err)
+		st.WriteHeader(s, metadata.Pairs("x-trace", md.Get("x-trace")[0]))
+		assert.Nil(t, st.Write(s, []byte{0, 0, 0, 0, 0}, data, &Options{}))
+		st.WriteStatus(s, status.New(codes.OK, ""))
+		assert.Equal(t, []string{"g1"}, md.Get(DubboServiceGroupHeader))
+		assert.Equal(t, []string{"1.0.0"}, md.Get(DubboServiceVersionHeader))
+	}, func(ctx context.Context, _ string) context.Context { return ctx })
+
+	r := bufio.NewReader(client)
+	header := hessian.DubboHeader{SerialID: 2, Type: hessian.PackageRequest_TwoWay, ID: 1}
+	rspHeader, rsp := dubboCall(t, client, r, header, "SayHello", []interface{}{"world", int32(1)})
+	assert.Equal(t, int64(1), rspHeader.ID)
+	assert.Equal(t, hessian.Response_OK, rspHeader.ResponseStatus)
+	assert.Nil(t, rsp.Exception)
+	assert.Equal(t, "hello world", rsp.RspObj)
+	assert.Equal(t, "abc", rsp.Attachments["x-trace"])
+
+	header.ID = 2
+	rspHeader, rsp = dubboCall(t, client, r, header, "SayGoodbye", []interface{}{"world"})
+	assert.Equal(t, hessian.Response_SERVICE_NOT_FOUND, rspHeader.ResponseStatus)
+	assert.Contains(t, rsp.Exception.Error(), "unknown method /org.example.Greeter/SayGoodbye")
+
+	// heartbeats are answered by the transport
+	header = hessian.DubboHeader{SerialID: 2, Type: hessian.PackageHeartbeat, ID: 3}
+	pkg, err := hessian.NewHessianCodec(nil).Write(hessian.Service{}, header, []interface{}{})
+	assert.Nil(t, err)
+	_, err = client.Write(pkg)
+	assert.Nil(t, err)
+	codec := hessian.NewHessianCodec(r)
+	assert.Nil(t, codec.ReadHeader(&header))
+	assert.Equal(t, int64(3), header.ID)
+	assert.NotZero(t, header.Type&hessian.PackageHeartbeat)
+}
+
+func (s) TestDubboServerTransportMaxReceiveMessageSize(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	st, err := NewDubboServerTransport(server, &ServerConfig{MaxReceiveMessageSize: 256})
+	assert.Nil(t, err)
+
+	var handled []string
+	go st.HandleStreams(func(s *Stream) {
+		handled = append(handled, s.Method())
+		st.WriteStatus(s, status.Newf(codes.NotFound, "unknown method %s", s.Method()))
+	}, func(ctx context.Context, _ string) context.Context { return ctx })
+
+	// the oversized request is rejected without reaching the handler
+	r := bufio.NewReader(client)
+	header := hessian.DubboHeader{SerialID: 2, Type: hessian.PackageRequest_TwoWay, ID: 1}
+	rspHeader, rsp := dubboCall(t, client, r, header, "SayHello", []interface{}{strings.Repeat("x", 1024)})
+	assert.Equal(t, int64(1), rspHeader.ID)
+	assert.Equal(t, hessian.Response_BAD_REQUEST, rspHeader.ResponseStatus)
+	assert.Contains(t, rsp.Exception.Error(), "larger than max")
+
+	// and the connection keeps serving
+	header.ID = 2
+	rspHeader, rsp = dubboCall(t, client, r, header, "SayHello", []interface{}{"world"})
+	assert.Equal(t, int64(2), rspHeader.ID)
+	assert.Equal(t, hessian.Response_SERVICE_NOT_FOUND, rspHeader.ResponseStatus)
+	assert.Equal(t, []string{"/org.example.Greeter/SayHello"}, handled)
+}
+
+func (s) TestJavaTypeOfDesc(t *testing.T) {
+	for desc, javaType := range map[string]string{
+		"Ljava/lang/String;":  "java.lang.String",
+		"I":                   "int",
+		"J":                   "long",
+		"[I":                  "[I",
+		"[Ljava/lang/String;": "[Ljava.lang.String;",
+	} {
+		assert.Equal(t, javaType, javaTypeOfDesc(desc))
+	}
+}
@@ -20,12 +20,15 @@
 // uses the standard Go http2 Server implementation (via the
 // http.Handler interface), rather than speaking low-level HTTP/2
 // frames itself. It is the implementation of *grpc.Server.ServeHTTP.
+// It also speaks gRPC-Web and triple's HTTP/1.1 JSON gateway, see
+// handler_web.go.
 
 package transport
 
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -45,6 +48,7 @@ import (
 import (
 	"github.com/dubbogo/grpc-go/codes"
 	"github.com/dubbogo/grpc-go/credentials"
+	"github.com/dubbogo/grpc-go/encoding"
 	"github.com/dubbogo/grpc-go/internal/grpcutil"
 	"github.com/dubbogo/grpc-go/metadata"
 	"github.com/dubbogo/grpc-go/peer"
@@ -54,31 +58,38 @@ import (
 
 // NewServerHandlerTransport returns a ServerTransport handling gRPC
 // from inside an http.Handler. It requires that the http Server
-// supports HTTP/2.
-func NewServerHandlerTransport(w http.ResponseWriter, r *http.Request, stats stats.Handler) (ServerTransport, error) {
-	if r.ProtoMajor != 2 {
+// supports HTTP/2, unless the request is a gRPC-Web request or a unary
+// request of the JSON gateway, which may use HTTP/1.1. The json body of the
+// latter is read up to maxRecvMsgSize bytes, if positive.
+func NewServerHandlerTransport(w http.ResponseWriter, r *http.Request, stats stats.Handler, maxRecvMsgSize int) (ServerTransport, error) {
+	contentType := r.Header.Get("Content-Type")
+	// TODO: do we assume contentType is lowercase? we did before
+	protocol, contentSubtype, validContentType := handlerContentType(contentType)
+	if r.ProtoMajor != 2 && (!validContentType || protocol == handlerGRPC) {
 		return nil, errors.New("gRPC requires HTTP/2")
 	}
 	if r.Method != "POST" {
 		return nil, errors.New("invalid gRPC request method")
 	}
-	contentType := r.Header.Get("Content-Type")
-	// TODO: do we assume contentType is lowercase? we did before
-	contentSubtype, validContentType := grpcutil.ContentSubtype(contentType)
 	if !validContentType {
 		return nil, errors.New("invalid gRPC request content-type")
 	}
-	if _, ok := w.(http.Flusher); !ok {
+	if protocol == handlerJSON && encoding.GetCodec(contentSubtype) == nil {
+		return nil, errors.New("json requests require the codec of the encoding/json package")
+	}
+	if _, ok := w.(http.Flusher); !ok && protocol != handlerJSON {
 		return nil, errors.New("gRPC requires a ResponseWriter supporting http.Flusher")
 	}
 
 	st := &serverHandlerTransport{
 		rw:             w,
 		req:            r,
+		protocol:       protocol,
 		closedCh:       make(chan struct{}),
 		writes:         make(chan func()),
 		contentType:    contentType,
 		contentSubtype: contentSubtype,
+		maxRecvMsgSize: maxRecvMsgSize,
 		stats:          stats,
 	}
 
@@ -117,10 +128,11 @@ func NewServerHandlerTransport(w http.ResponseWriter, r *http.Request, stats sta
 // which replies to exactly one gRPC request (exactly one HTTP request),
 // using the net/http.Handler interface. This http.Handler is guaranteed
 // at this point to be speaking over HTTP/2, so it's able to speak valid
-// gRPC.
+// gRPC, or to be speaking gRPC-Web or JSON, which need no HTTP/2.
 type serverHandlerTransport struct {
 	rw         http.ResponseWriter
 	req        *http.Request
+	protocol   handlerProtocol
 	timeoutSet bool
 	timeout    time.Duration
 
@@ -144,6 +156,19 @@ type serverHandlerTransport struct {
 	// TODO make sure this is consistent across handler_server and http2_server
 	contentSubtype string
 
+	// maxRecvMsgSize limits the body of json requests, if positive.
+	maxRecvMsgSize int
+
+	// jsonMsg is the response of a json request, it is sent with the
+	// status, which decides the HTTP status code. It is only accessed by
+	// the ServeHTTP goroutine, in the functions run by do.
+	jsonMsg []byte
+	// jsonEncoding is the compression of jsonMsg, if compressed.
+	jsonEncoding string
+	// jsonMsgSent is set by the first Write of a json request. It is only
+	// accessed by the goroutine writing the messages of the stream.
+	jsonMsgSent bool
+
 	stats stats.Handler
 }
 
@@ -197,27 +222,8 @@ func (ht *serverHandlerTransport) WriteStatus(s *Stream, st *status.Status) erro
 			ht.writePendingHeaders(s)
 		}
 
-		// And flush, in case no header or body has been sent yet.
-		// This forces a separation of headers and trailers if this is the
-		// first call (for example, in end2end tests's TestNoService).
-		ht.rw.(http.Flusher).Flush()
-
-		h := ht.rw.Header()
-		h.Set("Grpc-Status", fmt.Sprintf("%d", st.Code()))
-		if m := st.Message(); m != "" {
-			h.Set("Grpc-Message", encodeGrpcMessage(m))
-		}
-
-		if p := st.Proto(); p != nil && len(p.Details) > 0 {
-			stBytes, err := proto.Marshal(p)
-			if err != nil {
-				// TODO: return error instead, when callers are able to handle it.
-				panic(err)
-			}
-
-			h.Set("Grpc-Status-Details-Bin", encodeBinHeader(stBytes))
-		}
-
+		statusFields := statusFields(st)
+		var mdFields []string
 		if md := s.Trailer(); len(md) > 0 {
 			for k, vv := range md {
 				// Clients don't tolerate reading restricted headers after some non restricted ones were sent.
@@ -225,12 +231,35 @@ func (ht *serverHandlerTransport) WriteStatus(s *Stream, st *status.Status) erro
 					continue
 				}
 				for _, v := range vv {
-					// http2 ResponseWriter mechanism to send undeclared Trailers after
-					// the headers have possibly been written.
-					h.Add(http2.TrailerPrefix+k, encodeMetadataHeader(k, v))
+					mdFields = append(mdFields, k, encodeMetadataHeader(k, v))
 				}
 			}
 		}
+
+		switch ht.protocol {
+		case handlerGRPCWeb, handlerGRPCWebText:
+			ht.writeWeb(webTrailerFrame(append(statusFields, mdFields...)))
+			ht.rw.(http.Flusher).Flush()
+			return
+		case handlerJSON:
+			ht.writeJSON(st, append(statusFields, mdFields...))
+			return
+		}
+
+		// And flush, in case no header or body has been sent yet.
+		// This forces a separation of headers and trailers if this is the
+		// first call (for example, in end2end tests's TestNoService).
+		ht.rw.(http.Flusher).Flush()
+
+		h := ht.rw.Header()
+		for i := 0; i < len(statusFields); i += 2 {
+			h.Set(statusFields[i], statusFields[i+1])
+		}
+		for i := 0; i < len(mdFields); i += 2 {
+			// http2 ResponseWriter mechanism to send undeclared Trailers after
+			// the headers have possibly been written.
+			h.Add(http2.TrailerPrefix+mdFields[i], mdFields[i+1])
+		}
 	})
 
 	if err == nil { // transport has not been closed
@@ -246,6 +275,26 @@ func (ht *serverHandlerTransport) WriteStatus(s *Stream, st *status.Status) erro
 	return err
 }
 
+// statusFields returns the pairs of lower case keys and values of the
+// status fields of the trailer.
+func statusFields(st *status.Status) []string {
+	fields := []string{"grpc-status", fmt.Sprintf("%d", st.Code())}
+	if m := st.Message(); m != "" {
+		fields = append(fields, "grpc-message", encodeGrpcMessage(m))
+	}
+
+	if p := st.Proto(); p != nil && len(p.Details) > 0 {
+		stBytes, err := proto.Marshal(p)
+		if err != nil {
+			// TODO: return error instead, when callers are able to handle it.
+			panic(err)
+		}
+
+		fields = append(fields, "grpc-status-details-bin", encodeBinHeader(stBytes))
+	}
+	return fields
+}
+
 // writePendingHeaders sets common and custom headers on the first
 // write call (Write, WriteHeader, or WriteStatus)
 func (ht *serverHandlerTransport) writePendingHeaders(s *Stream) {
@@ -258,7 +307,26 @@ func (ht *serverHandlerTransport) writePendingHeaders(s *Stream) {
 func (ht *serverHandlerTransport) writeCommonHeaders(s *Stream) {
 	h := ht.rw.Header()
 	h["Date"] = nil // suppress Date to make tests happy; TODO: restore
-	h.Set("Content-Type", ht.contentType)
+	switch {
+	case ht.protocol == handlerJSON:
+		// the json response is sent by WriteStatus, without trailers
+		h.Set("Content-Type", jsonContentType)
+		return
+	case s.sendContentSubtype == "" || s.sendContentSubtype == ht.contentSubtype:
+		h.Set("Content-Type", ht.contentType)
+	case ht.protocol == handlerGRPC:
+		h.Set("Content-Type", grpcutil.ContentType(s.sendContentSubtype))
+	default:
+		h.Set("Content-Type", webContentType(ht.protocol, s.sendContentSubtype))
+	}
+
+	if s.sendCompress != "" {
+		h.Set("Grpc-Encoding", s.sendCompress)
+	}
+	if ht.protocol != handlerGRPC {
+		// gRPC-Web sends the trailers in the body
+		return
+	}
 
 	// Predeclare trailers we'll set later in WriteStatus (after the body).
 	// This is a SHOULD in the HTTP RFC, and the way you add (known)
@@ -268,10 +336,6 @@ func (ht *serverHandlerTransport) writeCommonHeaders(s *Stream) {
 	h.Add("Trailer", "Grpc-Status")
 	h.Add("Trailer", "Grpc-Message")
 	h.Add("Trailer", "Grpc-Status-Details-Bin")
-
-	if s.sendCompress != "" {
-		h.Set("Grpc-Encoding", s.sendCompress)
-	}
 }
 
 // writeCustomHeaders sets custom headers set on the stream via SetHeader
@@ -293,17 +357,78 @@ func (ht *serverHandlerTransport) writeCustomHeaders(s *Stream) {
 }
 
 func (ht *serverHandlerTransport) Write(s *Stream, hdr []byte, data []byte, opts *Options) error {
+	if ht.protocol == handlerJSON {
+		return ht.writeJSONMsg(s, hdr, data)
+	}
 	headersWritten := s.updateHeaderSent()
 	return ht.do(func() {
 		if !headersWritten {
 			ht.writePendingHeaders(s)
 		}
-		ht.rw.Write(hdr)
-		ht.rw.Write(data)
+		if ht.protocol == handlerGRPCWebText {
+			// the message is a single base64 chunk
+			ht.writeWeb(append(append(make([]byte, 0, len(hdr)+len(data)), hdr...), data...))
+		} else {
+			ht.rw.Write(hdr)
+			ht.rw.Write(data)
+		}
 		ht.rw.(http.Flusher).Flush()
 	})
 }
 
+// writeWeb writes @b to the body of a gRPC-Web response, encoding it as a
+// base64 chunk for gRPC-Web text.
+func (ht *serverHandlerTransport) writeWeb(b []byte) {
+	if ht.protocol == handlerGRPCWebText {
+		enc := make([]byte, base64.StdEncoding.EncodedLen(len(b)))
+		base64.StdEncoding.Encode(enc, b)
+		b = enc
+	}
+	ht.rw.Write(b)
+}
+
+// writeJSONMsg keeps the response message of a json request, to be sent by
+// WriteStatus. JSON requests are unary, a second message is an error.
+func (ht *serverHandlerTransport) writeJSONMsg(s *Stream, hdr []byte, data []byte) error {
+	if ht.jsonMsgSent {
+		return status.Error(codes.Internal, "json requests can not be replied by more than one message")
+	}
+	ht.jsonMsgSent = true
+	headersWritten := s.updateHeaderSent()
+	var compress string
+	if len(hdr) > 0 && hdr[0] == 1 {
+		// compressed by the server compressor
+		compress = s.sendCompress
+	}
+	return ht.do(func() {
+		if !headersWritten {
+			ht.writePendingHeaders(s)
+		}
+		ht.jsonMsg = data
+		ht.jsonEncoding = compress
+	})
+}
+
+// writeJSON writes the response of a json request, with the status and
+// trailer @fields as headers. Errors are replied by their HTTP status
+// code, with the json form of the status as the body.
+func (ht *serverHandlerTransport) writeJSON(st *status.Status, fields []string) {
+	h := ht.rw.Header()
+	for i := 0; i < len(fields); i += 2 {
+		h.Add(fields[i], fields[i+1])
+	}
+	if st.Code() != codes.OK {
+		ht.rw.WriteHeader(jsonStatusCode(st))
+		ht.rw.Write(jsonStatusBody(st))
+		return
+	}
+	if ht.jsonEncoding != "" {
+		h.Set("Content-Encoding", ht.jsonEncoding)
+	}
+	ht.rw.WriteHeader(http.StatusOK)
+	ht.rw.Write(ht.jsonMsg)
+}
+
 func (ht *serverHandlerTransport) WriteHeader(s *Stream, md metadata.MD) error {
 	if err := s.SetHeader(md); err != nil {
 		return err
@@ -315,6 +440,10 @@ func (ht *serverHandlerTransport) WriteHeader(s *Stream, md metadata.MD) error {
 			ht.writePendingHeaders(s)
 		}
 
+		if ht.protocol == handlerJSON {
+			// the status code is decided by WriteStatus
+			return
+		}
 		ht.rw.WriteHeader(200)
 		ht.rw.(http.Flusher).Flush()
 	})
@@ -367,6 +496,12 @@ func (ht *serverHandlerTransport) HandleStreams(startStream func(*Stream), trace
 		recvCompress:   req.Header.Get("grpc-encoding"),
 		contentSubtype: ht.contentSubtype,
 	}
+	if ht.protocol == handlerJSON {
+		// json bodies are not framed, hence never compressed
+		s.recvCompress = ""
+	} else if v := req.Header.Get("grpc-accept-content-subtype"); v != "" {
+		s.acceptContentSubtypes = parseContentSubtypes(v)
+	}
 	pr := &peer.Peer{
 		Addr: ht.RemoteAddr(),
 	}
@@ -389,6 +524,14 @@ func (ht *serverHandlerTransport) HandleStreams(startStream func(*Stream), trace
 		windowHandler: func(int) {},
 	}
 
+	var body io.Reader = req.Body
+	switch ht.protocol {
+	case handlerGRPCWebText:
+		body = newWebTextReader(req.Body)
+	case handlerJSON:
+		body = newJSONRequestReader(req.Body, ht.maxRecvMsgSize)
+	}
+
 	// readerDone is closed when the Body.Read-ing goroutine exits.
 	readerDone := make(chan struct{})
 	go func() {
@@ -397,7 +540,7 @@ func (ht *serverHandlerTransport) HandleStreams(startStream func(*Stream), trace
 		// TODO: minimize garbage, optimize recvBuffer code/ownership
 		const readSize = 8196
 		for buf := make([]byte, readSize); ; {
-			n, err := req.Body.Read(buf)
+			n, err := body.Read(buf)
 			if n > 0 {
 				s.buf.put(recvMsg{buffer: bytes.NewBuffer(buf[:n:n])})
 				buf = buf[n:]
@@ -441,9 +584,9 @@ func (ht *serverHandlerTransport) IncrMsgSent() {}
 
 func (ht *serverHandlerTransport) IncrMsgRecv() {}
 
-func (ht *serverHandlerTransport) Drain() {
-	panic("Drain() is not implemented")
-}
+// Drain does nothing: the transport serves a single request, which is
+// left to finish, and the connection belongs to the http.Server.
+func (ht *serverHandlerTransport) Drain() {}
 
 // mapRecvMsgError returns the non-nil err into the appropriate
 // error value as expected by callers of *grpc.parser.recvMsg.
@@ -456,6 +599,10 @@ func mapRecvMsgError(err error) error {
 	if err == io.EOF || err == io.ErrUnexpectedEOF {
 		return err
 	}
+	if _, ok := status.FromError(err); ok {
+		// e.g. a malformed gRPC-Web text body
+		return err
+	}
 	if se, ok := err.(http2.StreamError); ok {
 		if code, ok := http2ErrConvTab[se.Code]; ok {
 			return status.Error(code, se.Error())
@@ -0,0 +1,272 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// This file holds the gRPC-Web and HTTP/1.1 JSON gateway parts of the
+// serverHandlerTransport in handler_server.go.
+
+package transport
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+)
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/internal/grpcutil"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+// handlerProtocol is the protocol spoken by a serverHandlerTransport.
+type handlerProtocol int
+
+const (
+	// handlerGRPC is gRPC over HTTP/2.
+	handlerGRPC handlerProtocol = iota
+	// handlerGRPCWeb is gRPC-Web, whose trailers are sent in the body.
+	handlerGRPCWeb
+	// handlerGRPCWebText is gRPC-Web with base64 encoded bodies.
+	handlerGRPCWebText
+	// handlerJSON is triple's unary HTTP/1.1 POST with json bodies.
+	handlerJSON
+)
+
+const (
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+	jsonContentType        = "application/json"
+	// jsonContentSubtype is the codec of json requests, registered by the
+	// encoding/json package.
+	jsonContentSubtype = "raw_json"
+	// webTrailerFlag is the flag of the gRPC-Web frame holding the trailers.
+	webTrailerFlag = 0x80
+)
+
+// handlerContentType returns the protocol and the content-subtype of a
+// request of @contentType, or false if it is neither a gRPC, a gRPC-Web nor
+// a json content-type.
+func handlerContentType(contentType string) (handlerProtocol, string, bool) {
+	if subtype, ok := grpcutil.ContentSubtype(contentType); ok {
+		return handlerGRPC, subtype, true
+	}
+	// grpc-web is a prefix of grpc-web-text
+	if subtype, ok := webContentSubtype(contentType, grpcWebTextContentType); ok {
+		return handlerGRPCWebText, subtype, true
+	}
+	if subtype, ok := webContentSubtype(contentType, grpcWebContentType); ok {
+		return handlerGRPCWeb, subtype, true
+	}
+	if contentType == jsonContentType || strings.HasPrefix(contentType, jsonContentType+";") {
+		return handlerJSON, jsonContentSubtype, true
+	}
+	return handlerGRPC, "", false
+}
+
+// webContentSubtype returns the content-subtype following @base in
+// @contentType, like grpcutil.ContentSubtype does for "application/grpc".
+func webContentSubtype(contentType, base string) (string, bool) {
+	if contentType == base {
+		return "", true
+	}
+	if !strings.HasPrefix(contentType, base) {
+		return "", false
+	}
+	switch contentType[len(base)] {
+	case '+', ';':
+		return contentType[len(base)+1:], true
+	default:
+		return "", false
+	}
+}
+
+// webContentType returns the gRPC-Web content-type of @contentSubtype.
+func webContentType(protocol handlerProtocol, contentSubtype string) string {
+	base := grpcWebContentType
+	if protocol == handlerGRPCWebText {
+		base = grpcWebTextContentType
+	}
+	if contentSubtype == "" {
+		return base
+	}
+	return base + "+" + contentSubtype
+}
+
+// webTrailerFrame returns the gRPC-Web frame of the trailer @fields, which
+// are pairs of lower case keys and values.
+func webTrailerFrame(fields []string) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{webTrailerFlag, 0, 0, 0, 0})
+	for i := 0; i+1 < len(fields); i += 2 {
+		buf.WriteString(fields[i])
+		buf.WriteString(": ")
+		buf.WriteString(fields[i+1])
+		buf.WriteString("\r\n")
+	}
+	frame := buf.Bytes()
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(frame)-5))
+	return frame
+}
+
+// webTextReader decodes the base64 body of gRPC-Web text requests, which
+// may be the concatenation of separately padded chunks.
+type webTextReader struct {
+	r   io.Reader
+	err error
+	// enc is the undecoded input, shorter than a base64 quantum.
+	enc []byte
+	// dec is the decoded output, not read yet.
+	dec []byte
+}
+
+func newWebTextReader(r io.Reader) *webTextReader {
+	return &webTextReader{r: r}
+}
+
+func (w *webTextReader) Read(p []byte) (int, error) {
+	for len(w.dec) == 0 {
+		if w.err != nil {
+			return 0, w.err
+		}
+		buf := make([]byte, 4096)
+		n, err := w.r.Read(buf)
+		w.enc = append(w.enc, buf[:n]...)
+		if q := len(w.enc) / 4 * 4; q > 0 {
+			dec, derr := decodeWebText(w.enc[:q])
+			if derr != nil {
+				w.err = status.Errorf(codes.Internal, "malformed grpc-web-text body: %v", derr)
+				return 0, w.err
+			}
+			w.dec = dec
+			w.enc = append(w.enc[:0], w.enc[q:]...)
+		}
+		if err == io.EOF && len(w.enc) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		w.err = err
+	}
+	n := copy(p, w.dec)
+	w.dec = w.dec[n:]
+	return n, nil
+}
+
+// decodeWebText decodes the base64 quanta @enc, a padded quantum may be
+// followed by the next chunk.
+func decodeWebText(enc []byte) ([]byte, error) {
+	dec := make([]byte, 0, base64.StdEncoding.DecodedLen(len(enc)))
+	for len(enc) > 0 {
+		end := len(enc)
+		if i := bytes.IndexByte(enc, '='); i >= 0 {
+			// the chunk ends with this quantum
+			end = (i/4 + 1) * 4
+		}
+		n, err := base64.StdEncoding.Decode(dec[len(dec):cap(dec)], enc[:end])
+		if err != nil {
+			return nil, err
+		}
+		dec = dec[:len(dec)+n]
+		enc = enc[end:]
+	}
+	return dec, nil
+}
+
+// jsonRequestReader frames the json body of a request as a single gRPC
+// message. The body is read entirely by the first Read, up to limit bytes.
+type jsonRequestReader struct {
+	r     io.Reader
+	limit int64
+	msg   *bytes.Reader
+}
+
+// newJSONRequestReader returns a jsonRequestReader of @r, whose body must not
+// be longer than @limit bytes if it is positive, nor than a message length.
+func newJSONRequestReader(r io.Reader, limit int) *jsonRequestReader {
+	max := int64(math.MaxUint32)
+	if limit > 0 && int64(limit) < max {
+		max = int64(limit)
+	}
+	return &jsonRequestReader{r: r, limit: max}
+}
+
+func (j *jsonRequestReader) Read(p []byte) (int, error) {
+	if j.msg == nil {
+		body, err := ioutil.ReadAll(io.LimitReader(j.r, j.limit+1))
+		if err != nil {
+			return 0, err
+		}
+		if int64(len(body)) > j.limit {
+			return 0, status.Errorf(codes.ResourceExhausted, "grpc: received json message larger than max (%d bytes)", j.limit)
+		}
+		msg := make([]byte, 5+len(body))
+		binary.BigEndian.PutUint32(msg[1:], uint32(len(body)))
+		copy(msg[5:], body)
+		j.msg = bytes.NewReader(msg)
+	}
+	return j.msg.Read(p)
+}
+
+// httpStatusFromCode maps the status codes to the HTTP status codes of json
+// responses, as the grpc-gateway does.
+var httpStatusFromCode = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.DataLoss:           http.StatusInternalServerError,
+}
+
+// jsonStatusCode returns the HTTP status code of a json response of @st.
+func jsonStatusCode(st *status.Status) int {
+	if code, ok := httpStatusFromCode[st.Code()]; ok {
+		return code
+	}
+	return http.StatusInternalServerError
+}
+
+// jsonStatusBody returns the json body of a json response of the error @st,
+// in the form of google.rpc.Status. The details are dropped if their types
+// are unknown.
+func jsonStatusBody(st *status.Status) []byte {
+	p := st.Proto()
+	body, err := protojson.Marshal(p)
+	if err != nil {
+		p.Details = nil
+		body, _ = protojson.Marshal(p)
+	}
+	return body
+}
@@ -0,0 +1,194 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	_ "github.com/dubbogo/grpc-go/encoding/json"
+	"github.com/dubbogo/grpc-go/metadata"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+func (s) TestHandlerContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		protocol    handlerProtocol
+		subtype     string
+		valid       bool
+	}{
+		{"application/grpc", handlerGRPC, "", true},
+		{"application/grpc+json", handlerGRPC, "json", true},
+		{"application/grpc-web", handlerGRPCWeb, "", true},
+		{"application/grpc-web+proto", handlerGRPCWeb, "proto", true},
+		{"application/grpc-web-text", handlerGRPCWebText, "", true},
+		{"application/grpc-web-text+proto", handlerGRPCWebText, "proto", true},
+		{"application/json", handlerJSON, jsonContentSubtype, true},
+		{"application/json; charset=utf-8", handlerJSON, jsonContentSubtype, true},
+		{"application/grpc-webby", handlerGRPC, "", false},
+		{"text/plain", handlerGRPC, "", false},
+	}
+	for _, test := range tests {
+		protocol, subtype, valid := handlerContentType(test.contentType)
+		assert.Equal(t, test.protocol, protocol, test.contentType)
+		assert.Equal(t, test.subtype, subtype, test.contentType)
+		assert.Equal(t, test.valid, valid, test.contentType)
+	}
+}
+
+func (s) TestWebTextReader(t *testing.T) {
+	msg := []byte("a gRPC-Web text message")
+	// separately padded chunks
+	body := base64.StdEncoding.EncodeToString(msg[:4]) + base64.StdEncoding.EncodeToString(msg[4:])
+	got, err := ioutil.ReadAll(newWebTextReader(strings.NewReader(body)))
+	assert.Nil(t, err)
+	assert.Equal(t, msg, got)
+
+	_, err = ioutil.ReadAll(newWebTextReader(strings.NewReader(body[:len(body)-1])))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	_, err = ioutil.ReadAll(newWebTextReader(strings.NewReader("!!!!")))
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+// webFrame returns the gRPC frame of @msg.
+func webFrame(flag byte, msg []byte) []byte {
+	frame := make([]byte, 5+len(msg))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	copy(frame[5:], msg)
+	return frame
+}
+
+// serveHandlerTest serves the HTTP/1.1 request of @contentType and @body by
+// a serverHandlerTransport, replying the request message by @handle.
+func serveHandlerTest(t *testing.T, contentType string, body []byte, handle func(ht ServerTransport, s *Stream, msg []byte)) testHandlerResponseWriter {
+	req := &http.Request{
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Method:     "POST",
+		Header: http.Header{
+			"Content-Type": {contentType},
+		},
+		URL: &url.URL{
+			Path: "/service/foo.bar",
+		},
+		Body: ioutil.NopCloser(bytes.NewReader(body)),
+	}
+	rw := newTestHandlerResponseWriter().(testHandlerResponseWriter)
+	ht, err := NewServerHandlerTransport(rw, req, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht.HandleStreams(
+		func(s *Stream) {
+			go func() {
+				hdr := make([]byte, 5)
+				if _, err := s.Read(hdr); err != nil {
+					t.Errorf("reading the message header: %v", err)
+				}
+				msg := make([]byte, binary.BigEndian.Uint32(hdr[1:]))
+				if _, err := s.Read(msg); err != nil {
+					t.Errorf("reading the message: %v", err)
+				}
+				handle(ht, s, msg)
+			}()
+		},
+		func(ctx context.Context, method string) context.Context { return ctx },
+	)
+	return rw
+}
+
+func (s) TestHandlerTransport_GRPCWeb(t *testing.T) {
+	for _, text := range []bool{false, true} {
+		contentType, body := "application/grpc-web+proto", webFrame(0, []byte("ping"))
+		if text {
+			contentType = "application/grpc-web-text+proto"
+			body = []byte(base64.StdEncoding.EncodeToString(body))
+		}
+		rw := serveHandlerTest(t, contentType, body, func(ht ServerTransport, s *Stream, msg []byte) {
+			assert.Equal(t, "ping", string(msg))
+			s.SetTrailer(metadata.Pairs("custom-trailer", "value"))
+			ht.Write(s, []byte{0, 0, 0, 0, 4}, []byte("pong"), &Options{})
+			ht.WriteStatus(s, status.New(codes.OK, ""))
+		})
+
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.Equal(t, contentType, rw.Header().Get("Content-Type"))
+		assert.Empty(t, rw.Header().Get("Trailer"))
+		want := append(webFrame(0, []byte("pong")), webFrame(webTrailerFlag, []byte("grpc-status: 0\r\ncustom-trailer: value\r\n"))...)
+		got := rw.Body.Bytes()
+		if text {
+			// the message and the trailers are separate chunks
+			want = []byte(base64.StdEncoding.EncodeToString(want[:9]) + base64.StdEncoding.EncodeToString(want[9:]))
+		}
+		assert.Equal(t, want, got)
+	}
+}
+
+func (s) TestHandlerTransport_JSON(t *testing.T) {
+	rw := serveHandlerTest(t, "application/json", []byte(`{"name":"ping"}`), func(ht ServerTransport, s *Stream, msg []byte) {
+		assert.Equal(t, jsonContentSubtype, s.ContentSubtype())
+		assert.Equal(t, `{"name":"ping"}`, string(msg))
+		s.SetHeader(metadata.Pairs("custom-header", "value"))
+		ht.Write(s, []byte{0, 0, 0, 0, 15}, []byte(`{"name":"pong"}`), &Options{})
+		assert.NotNil(t, ht.Write(s, []byte{0, 0, 0, 0, 15}, []byte(`{"name":"pong"}`), &Options{}))
+		ht.WriteStatus(s, status.New(codes.OK, ""))
+	})
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+	assert.Equal(t, "value", rw.Header().Get("Custom-Header"))
+	assert.Equal(t, "0", rw.Header().Get("Grpc-Status"))
+	assert.Equal(t, `{"name":"pong"}`, rw.Body.String())
+
+	rw = serveHandlerTest(t, "application/json", []byte(`{}`), func(ht ServerTransport, s *Stream, msg []byte) {
+		ht.WriteStatus(s, status.New(codes.NotFound, "no such name"))
+	})
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+	assert.Equal(t, "5", rw.Header().Get("Grpc-Status"))
+	var body map[string]interface{}
+	assert.Nil(t, json.Unmarshal(rw.Body.Bytes(), &body))
+	assert.Equal(t, float64(codes.NotFound), body["code"])
+	assert.Equal(t, "no such name", body["message"])
+}
+
+func (s) TestJSONRequestReader(t *testing.T) {
+	msg, err := ioutil.ReadAll(newJSONRequestReader(strings.NewReader(`{"a":1}`), 7))
+	assert.Nil(t, err)
+	assert.Equal(t, append([]byte{0, 0, 0, 0, 7}, `{"a":1}`...), msg)
+
+	_, err = ioutil.ReadAll(newJSONRequestReader(strings.NewReader(`{"a":10}`), 7))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
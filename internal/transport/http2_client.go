@@ -510,6 +510,9 @@ func (t *http2Client) createHeaderFields(ctx context.Context, callHdr *CallHdr)
 		headerFields = append(headerFields, hpack.HeaderField{Name: "grpc-encoding", Value: callHdr.SendCompress})
 		headerFields = append(headerFields, hpack.HeaderField{Name: "grpc-accept-encoding", Value: callHdr.SendCompress})
 	}
+	if len(callHdr.AcceptContentSubtypes) > 0 {
+		headerFields = append(headerFields, hpack.HeaderField{Name: "grpc-accept-content-subtype", Value: strings.Join(callHdr.AcceptContentSubtypes, ",")})
+	}
 	if dl, ok := ctx.Deadline(); ok {
 		// Send out timeout regardless its value. The server can detect timeout context by itself.
 		// TODO(mmukhi): Perhaps this field should be updated when actually writing out to the wire.
@@ -1311,6 +1314,7 @@ func (t *http2Client) operateHeaders(frame *http2.MetaHeadersFrame) {
 		grpcMessage    string
 		statusGen      *status.Status
 		recvCompress   string
+		contentSubtype string
 		httpStatusCode *int
 		httpStatusErr  string
 		rawStatusCode  = codes.Unknown
@@ -1325,11 +1329,13 @@ func (t *http2Client) operateHeaders(frame *http2.MetaHeadersFrame) {
 	for _, hf := range frame.Fields {
 		switch hf.Name {
 		case "content-type":
-			if _, validContentType := grpcutil.ContentSubtype(hf.Value); !validContentType {
+			subtype, validContentType := grpcutil.ContentSubtype(hf.Value)
+			if !validContentType {
 				contentTypeErr = fmt.Sprintf("transport: received unexpected content-type %q", hf.Value)
 				break
 			}
 			contentTypeErr = ""
+			contentSubtype = subtype
 			mdata[hf.Name] = append(mdata[hf.Name], hf.Value)
 			isGRPC = true
 		case "grpc-encoding":
@@ -1427,6 +1433,7 @@ func (t *http2Client) operateHeaders(frame *http2.MetaHeadersFrame) {
 			// stream goroutine will read it only after seeing a closed
 			// headerChan which we'll close after setting this.
 			s.recvCompress = recvCompress
+			s.recvContentSubtype = contentSubtype
 			if len(mdata) > 0 {
 				s.header = mdata
 			}
@@ -114,6 +114,7 @@ func isReservedHeader(hdr string) bool {
 		"user-agent",
 		"grpc-message-type",
 		"grpc-encoding",
+		"grpc-accept-content-subtype",
 		"grpc-message",
 		"grpc-status",
 		"grpc-timeout",
@@ -128,6 +129,18 @@ func isReservedHeader(hdr string) bool {
 	}
 }
 
+// parseContentSubtypes parses the comma separated content-subtypes of the
+// grpc-accept-content-subtype header.
+func parseContentSubtypes(v string) []string {
+	var subtypes []string
+	for _, st := range strings.Split(v, ",") {
+		if st = strings.ToLower(strings.TrimSpace(st)); st != "" {
+			subtypes = append(subtypes, st)
+		}
+	}
+	return subtypes
+}
+
 // isWhitelistedHeader checks whether hdr should be propagated into metadata
 // visible to users, even though it is classified as "reserved", above.
 func isWhitelistedHeader(hdr string) bool {
@@ -186,6 +186,21 @@ func (s) TestDecodeMetadataHeader(t *testing.T) {
 	}
 }
 
+func (s) TestParseContentSubtypes(t *testing.T) {
+	for _, test := range []struct {
+		in  string
+		out []string
+	}{
+		{"json", []string{"json"}},
+		{"Hessian2, json,,msgpack ", []string{"hessian2", "json", "msgpack"}},
+		{" , ", nil},
+	} {
+		if out := parseContentSubtypes(test.in); !reflect.DeepEqual(out, test.out) {
+			t.Fatalf("parseContentSubtypes(%q) = %q, want %q", test.in, out, test.out)
+		}
+	}
+}
+
 func (s) TestParseDialTarget(t *testing.T) {
 	for _, test := range []struct {
 		target, wantNet, wantAddr string
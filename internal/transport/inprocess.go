@@ -0,0 +1,975 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// This file is the implementation of the in-process transports, whose client
+// and server streams are in the same process and pass messages as objects,
+// without codec nor framing.
+
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/credentials"
+	"github.com/dubbogo/grpc-go/internal/grpcutil"
+	"github.com/dubbogo/grpc-go/metadata"
+	"github.com/dubbogo/grpc-go/peer"
+	"github.com/dubbogo/grpc-go/stats"
+	"github.com/dubbogo/grpc-go/status"
+	"github.com/dubbogo/grpc-go/tap"
+)
+
+var (
+	// InProcessAddr is the address of both ends of in-process connections.
+	InProcessAddr net.Addr = inProcessAddr{}
+
+	// errObjectMessage is returned when reading the bytes of a message
+	// passed as an object.
+	errObjectMessage = status.Error(codes.Internal, "transport: the message of an in-process stream is an object, not bytes")
+	// errInProcessBytes is returned when writing bytes on in-process streams.
+	errInProcessBytes     = status.Error(codes.Internal, "transport: in-process streams carry message objects, not bytes")
+	errInProcessConnWrite = errors.New("transport: in-process connections can not be written")
+)
+
+type inProcessAddr struct{}
+
+func (inProcessAddr) Network() string { return "inprocess" }
+
+func (inProcessAddr) String() string { return "inprocess" }
+
+// inProcessAuthInfo is the AuthInfo of in-process connections, which never
+// leave the process and are as secure as it.
+type inProcessAuthInfo struct {
+	credentials.CommonAuthInfo
+}
+
+func (inProcessAuthInfo) AuthType() string {
+	return "inprocess"
+}
+
+// InProcessConn is a connection between an in-process client transport and
+// an in-process server transport. It carries no bytes: it only hands the
+// server transport to the client and tells each end when the other one goes
+// away. It implements net.Conn so that it can be accepted by listeners.
+type InProcessConn struct {
+	deepCopy bool
+
+	// server is set before ready is closed, when the server transport
+	// handles streams.
+	server *inProcessServer
+	ready  chan struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	goAwayOnce sync.Once
+	goAway     chan struct{}
+}
+
+// NewInProcessConn creates an in-process connection. If @deepCopy is set,
+// the streams of the connection deep copy their messages.
+func NewInProcessConn(deepCopy bool) *InProcessConn {
+	return &InProcessConn{
+		deepCopy: deepCopy,
+		ready:    make(chan struct{}),
+		closed:   make(chan struct{}),
+		goAway:   make(chan struct{}),
+	}
+}
+
+// Read blocks until the connection is closed.
+func (c *InProcessConn) Read(b []byte) (int, error) {
+	<-c.closed
+	return 0, io.EOF
+}
+
+// Write always fails, in-process connections do not carry bytes.
+func (c *InProcessConn) Write(b []byte) (int, error) {
+	return 0, errInProcessConnWrite
+}
+
+func (c *InProcessConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
+
+func (c *InProcessConn) LocalAddr() net.Addr {
+	return InProcessAddr
+}
+
+func (c *InProcessConn) RemoteAddr() net.Addr {
+	return InProcessAddr
+}
+
+func (c *InProcessConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *InProcessConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *InProcessConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *InProcessConn) drain() {
+	c.goAwayOnce.Do(func() {
+		close(c.goAway)
+	})
+}
+
+// InProcess reports whether @s is a stream of an in-process transport, whose
+// messages are sent by WriteObject and received by RecvObject.
+func (s *Stream) InProcess() bool {
+	return s.inProcess
+}
+
+// DeepCopy reports whether the messages of the in-process stream @s must be
+// deep copied, so that the sender and the receiver do not share them.
+func (s *Stream) DeepCopy() bool {
+	return s.deepCopy
+}
+
+// WriteObject sends the message object @m on the in-process stream @s.
+func (s *Stream) WriteObject(m interface{}, opts *Options) error {
+	switch t := s.st.(type) {
+	case *inProcessServer:
+		return t.writeObject(s, m, opts)
+	case nil:
+		if t, ok := s.ct.(*inProcessClient); ok {
+			return t.writeObject(s, m, opts)
+		}
+	}
+	return status.Error(codes.Internal, "transport: WriteObject called on a stream which is not in-process")
+}
+
+// RecvObject receives the next message object of the in-process stream @s.
+// The error is io.EOF when the stream is done or another non-nil error if the
+// stream broke.
+func (s *Stream) RecvObject() (interface{}, error) {
+	return s.trReader.(*transportReader).reader.(*recvBufferReader).readObject()
+}
+
+// readObject reads the next message object from recv, it is Read for
+// in-process streams.
+func (r *recvBufferReader) readObject() (interface{}, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	var m recvMsg
+	select {
+	case <-r.ctxDone:
+		if r.closeStream == nil {
+			r.err = ContextErr(r.ctx.Err())
+			return nil, r.err
+		}
+		// see readClient
+		r.closeStream(ContextErr(r.ctx.Err()))
+		m = <-r.recv.get()
+	case m = <-r.recv.get():
+	}
+	r.recv.load()
+	if m.err != nil {
+		r.err = m.err
+		return nil, r.err
+	}
+	return m.msg, nil
+}
+
+// inProcessServer is the ServerTransport of an in-process connection. Its
+// streams are created by the client transport, each one paired with a client
+// stream.
+type inProcessServer struct {
+	conn        *InProcessConn
+	stats       stats.Handler
+	inTapHandle tap.ServerInHandle
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// handle and traceCtx are set before conn.ready is closed.
+	handle   func(*Stream)
+	traceCtx func(context.Context, string) context.Context
+	nextID   uint32
+	// handling counts the calls of handle made by client goroutines, which
+	// HandleStreams waits for before returning.
+	handling sync.WaitGroup
+
+	mu sync.Mutex
+	// streams maps the active server streams to their client streams.
+	streams  map[*Stream]*Stream
+	draining bool
+	closed   bool
+}
+
+// NewInProcessServerTransport creates a ServerTransport serving the streams
+// of the in-process connection @conn, with configuration options from
+// @config. Credentials, flow control, keepalive and message size options do
+// not apply to in-process connections.
+func NewInProcessServerTransport(conn *InProcessConn, config *ServerConfig) (ServerTransport, error) {
+	t := &inProcessServer{
+		conn:        conn,
+		stats:       config.StatsHandler,
+		inTapHandle: config.InTapHandle,
+		streams:     make(map[*Stream]*Stream),
+	}
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	if t.stats != nil {
+		t.ctx = t.stats.TagConn(t.ctx, &stats.ConnTagInfo{
+			RemoteAddr: InProcessAddr,
+			LocalAddr:  InProcessAddr,
+		})
+		t.stats.HandleConn(t.ctx, &stats.ConnBegin{})
+	}
+	conn.server = t
+	return t, nil
+}
+
+// HandleStreams makes the connection ready for the client transport, whose
+// streams are handled by @handle, and blocks until the connection is closed.
+func (t *inProcessServer) HandleStreams(handle func(*Stream), traceCtx func(context.Context, string) context.Context) {
+	t.handle = handle
+	t.traceCtx = traceCtx
+	close(t.conn.ready)
+	<-t.conn.closed
+	t.Close()
+	t.handling.Wait()
+}
+
+// newStream creates the server stream of the client stream @cs, whose
+// request header is @md. The stream must be handled by handleStream.
+func (t *inProcessServer) newStream(cs *Stream, callHdr *CallHdr, md metadata.MD) (*Stream, error) {
+	s := &Stream{
+		id:                    atomic.AddUint32(&t.nextID, 1),
+		st:                    t,
+		method:                callHdr.Method,
+		buf:                   newRecvBuffer(),
+		requestRead:           func(int) {},
+		contentSubtype:        callHdr.ContentSubtype,
+		acceptContentSubtypes: callHdr.AcceptContentSubtypes,
+		inProcess:             true,
+		deepCopy:              t.conn.deepCopy,
+	}
+	// the deadline of the client is the one of the server, as a grpc-timeout
+	// header would be.
+	if dl, ok := cs.ctx.Deadline(); ok {
+		s.ctx, s.cancel = context.WithDeadline(t.ctx, dl)
+	} else {
+		s.ctx, s.cancel = context.WithCancel(t.ctx)
+	}
+	s.ctx = peer.NewContext(s.ctx, &peer.Peer{
+		Addr:     InProcessAddr,
+		AuthInfo: inProcessAuthInfo{credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity}},
+	})
+	s.ctx = metadata.NewIncomingContext(s.ctx, md)
+	if statsTags := md["grpc-tags-bin"]; len(statsTags) > 0 {
+		s.ctx = stats.SetIncomingTags(s.ctx, []byte(statsTags[len(statsTags)-1]))
+	}
+	if statsTrace := md["grpc-trace-bin"]; len(statsTrace) > 0 {
+		s.ctx = stats.SetIncomingTrace(s.ctx, []byte(statsTrace[len(statsTrace)-1]))
+	}
+	if t.inTapHandle != nil {
+		var err error
+		if s.ctx, err = t.inTapHandle(s.ctx, &tap.Info{FullMethodName: s.method}); err != nil {
+			s.cancel()
+			st, ok := status.FromError(err)
+			if !ok {
+				st = status.New(codes.PermissionDenied, err.Error())
+			}
+			return nil, st.Err()
+		}
+	}
+
+	t.mu.Lock()
+	if t.draining || t.closed {
+		t.mu.Unlock()
+		s.cancel()
+		return nil, errStreamDrain
+	}
+	t.streams[s] = cs
+	t.mu.Unlock()
+
+	s.ctx = t.traceCtx(s.ctx, s.method)
+	if t.stats != nil {
+		s.ctx = t.stats.TagRPC(s.ctx, &stats.RPCTagInfo{FullMethodName: s.method})
+		t.stats.HandleRPC(s.ctx, &stats.InHeader{
+			FullMethod: s.method,
+			RemoteAddr: InProcessAddr,
+			LocalAddr:  InProcessAddr,
+			Header:     md.Copy(),
+		})
+	}
+	s.ctxDone = s.ctx.Done()
+	s.trReader = &transportReader{
+		reader: &recvBufferReader{
+			ctx:        s.ctx,
+			ctxDone:    s.ctxDone,
+			recv:       s.buf,
+			freeBuffer: func(*bytes.Buffer) {},
+		},
+		windowHandler: func(int) {},
+	}
+	return s, nil
+}
+
+// handleStream passes @s to the handle of HandleStreams on the goroutine of
+// the client stream, unless the transport is closed.
+func (t *inProcessServer) handleStream(s *Stream) {
+	t.mu.Lock()
+	if t.closed {
+		// HandleStreams is returning, @s was canceled by Close.
+		t.mu.Unlock()
+		return
+	}
+	t.handling.Add(1)
+	t.mu.Unlock()
+	defer t.handling.Done()
+	t.handle(s)
+}
+
+// clientStream returns the client stream of the active server stream @s.
+func (t *inProcessServer) clientStream(s *Stream) (*Stream, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, ErrConnClosing
+	}
+	cs, ok := t.streams[s]
+	if !ok {
+		return nil, errStreamDone
+	}
+	return cs, nil
+}
+
+// WriteHeader delivers the header of @s, joined with @md, to the client
+// stream.
+func (t *inProcessServer) WriteHeader(s *Stream, md metadata.MD) error {
+	if s.updateHeaderSent() || s.getState() == streamDone {
+		return ErrIllegalHeaderWrite
+	}
+	s.hdrMu.Lock()
+	defer s.hdrMu.Unlock()
+	if md.Len() > 0 {
+		s.header = metadata.Join(s.header, md)
+	}
+	return t.writeHeaderLocked(s)
+}
+
+func (t *inProcessServer) writeHeaderLocked(s *Stream) error {
+	cs, err := t.clientStream(s)
+	if err != nil {
+		return err
+	}
+	cs.ct.(*inProcessClient).operateHeader(cs, s.SendContentSubtype(), s.sendCompress, s.header.Copy())
+	if t.stats != nil {
+		t.stats.HandleRPC(s.Context(), &stats.OutHeader{
+			Header:      s.header.Copy(),
+			Compression: s.sendCompress,
+		})
+	}
+	return nil
+}
+
+// Write always fails, the messages of in-process streams are written by
+// WriteObject.
+func (t *inProcessServer) Write(s *Stream, hdr []byte, data []byte, opts *Options) error {
+	return errInProcessBytes
+}
+
+func (t *inProcessServer) writeObject(s *Stream, m interface{}, opts *Options) error {
+	if !s.isHeaderSent() {
+		if err := t.WriteHeader(s, nil); err != nil {
+			if _, ok := err.(ConnectionError); ok {
+				return err
+			}
+			return status.Errorf(codes.Internal, "transport: %v", err)
+		}
+	}
+	if s.getState() == streamDone {
+		return ContextErr(s.ctx.Err())
+	}
+	cs, err := t.clientStream(s)
+	if err != nil {
+		if err == errStreamDone {
+			// the client canceled the stream
+			s.cancel()
+			return ContextErr(s.ctx.Err())
+		}
+		return err
+	}
+	atomic.StoreUint32(&cs.bytesReceived, 1)
+	cs.write(recvMsg{msg: m})
+	return nil
+}
+
+// WriteStatus delivers @st and the trailer of @s to the client stream, which
+// is done.
+func (t *inProcessServer) WriteStatus(s *Stream, st *status.Status) error {
+	if s.swapState(streamDone) == streamDone {
+		return nil
+	}
+	s.hdrMu.Lock()
+	if !s.updateHeaderSent() && len(s.header) > 0 {
+		// not a trailers-only response
+		if err := t.writeHeaderLocked(s); err != nil {
+			s.hdrMu.Unlock()
+			s.cancel()
+			return err
+		}
+	}
+	trailer := s.trailer.Copy()
+	s.hdrMu.Unlock()
+
+	t.mu.Lock()
+	cs, ok := t.streams[s]
+	delete(t.streams, s)
+	closeConn := t.draining && len(t.streams) == 0
+	t.mu.Unlock()
+	if ok {
+		ct := cs.ct.(*inProcessClient)
+		if err := clientContextErr(cs.ctx); err != nil {
+			// Over a network, the client would see its deadline or its
+			// cancellation before the status.
+			ct.closeStream(cs, err, status.Convert(err), nil)
+		} else {
+			if atomic.CompareAndSwapUint32(&cs.headerChanClosed, 0, 1) {
+				// a trailers-only response
+				cs.recvContentSubtype = s.SendContentSubtype()
+				cs.noHeaders = true
+				close(cs.headerChan)
+			}
+			ct.closeStream(cs, io.EOF, st, trailer)
+		}
+	}
+	if t.stats != nil {
+		t.stats.HandleRPC(s.Context(), &stats.OutTrailer{
+			Trailer: trailer,
+		})
+	}
+	s.cancel()
+	if closeConn {
+		t.Close()
+	}
+	return nil
+}
+
+// clientContextErr returns the error of the context @ctx of a client stream,
+// which is DeadlineExceeded as soon as its deadline is reached, even if the
+// timer of @ctx has not fired yet.
+func clientContextErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return ContextErr(err)
+	}
+	if dl, ok := ctx.Deadline(); ok && !time.Now().Before(dl) {
+		return ContextErr(context.DeadlineExceeded)
+	}
+	return nil
+}
+
+// Close closes the connection, the client transport fails its pending
+// streams.
+func (t *inProcessServer) Close() {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.closed = true
+	streams := t.streams
+	t.streams = nil
+	t.mu.Unlock()
+
+	t.conn.Close()
+	t.cancel()
+	for s := range streams {
+		s.cancel()
+	}
+	if t.stats != nil {
+		t.stats.HandleConn(t.ctx, &stats.ConnEnd{})
+	}
+}
+
+func (t *inProcessServer) RemoteAddr() net.Addr {
+	return InProcessAddr
+}
+
+// Drain tells the client transport to stop creating streams, the connection
+// is closed once the active ones are done.
+func (t *inProcessServer) Drain() {
+	t.mu.Lock()
+	if t.draining || t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.draining = true
+	idle := len(t.streams) == 0
+	t.mu.Unlock()
+
+	t.conn.drain()
+	if idle {
+		t.Close()
+	}
+}
+
+func (t *inProcessServer) IncrMsgSent() {}
+
+func (t *inProcessServer) IncrMsgRecv() {}
+
+// inProcessClient is the ClientTransport of an in-process connection, its
+// streams are paired with server streams created by the server transport.
+type inProcessClient struct {
+	conn         *InProcessConn
+	server       *inProcessServer
+	userAgent    string
+	perRPCCreds  []credentials.PerRPCCredentials
+	statsHandler stats.Handler
+	onGoAway     func(GoAwayReason)
+	onClose      func()
+	nextID       uint32
+
+	// errorChan is closed when the connection is closed.
+	errorChan chan struct{}
+	// goAway is closed when the server drains the connection.
+	goAway chan struct{}
+
+	mu    sync.Mutex
+	state transportState
+	// streams maps the active client streams to their server streams.
+	streams map[*Stream]*Stream
+}
+
+func newInProcessClient(connectCtx context.Context, opts ConnectOptions, onPrefaceReceipt func(), onGoAway func(GoAwayReason), onClose func()) (*inProcessClient, error) {
+	conn, err := opts.InProcessDial(connectCtx)
+	if err != nil {
+		return nil, connectionErrorf(true, err, "transport: error while dialing in-process: %v", err)
+	}
+	select {
+	case <-conn.ready:
+	case <-conn.closed:
+		return nil, connectionErrorf(true, nil, "transport: in-process connection closed by the server")
+	case <-connectCtx.Done():
+		conn.Close()
+		return nil, connectionErrorf(true, connectCtx.Err(), "transport: error while dialing in-process: %v", connectCtx.Err())
+	}
+
+	perRPCCreds := opts.PerRPCCredentials
+	if b := opts.CredsBundle; b != nil {
+		if t := b.PerRPCCredentials(); t != nil {
+			perRPCCreds = append(perRPCCreds, t)
+		}
+	}
+	t := &inProcessClient{
+		conn:         conn,
+		server:       conn.server,
+		userAgent:    opts.UserAgent,
+		perRPCCreds:  perRPCCreds,
+		statsHandler: opts.StatsHandler,
+		onGoAway:     onGoAway,
+		onClose:      onClose,
+		errorChan:    make(chan struct{}),
+		goAway:       make(chan struct{}),
+		state:        reachable,
+		streams:      make(map[*Stream]*Stream),
+	}
+	if t.statsHandler != nil {
+		connBegin := &stats.ConnBegin{
+			Client: true,
+		}
+		t.statsHandler.HandleConn(connectCtx, connBegin)
+	}
+	onPrefaceReceipt()
+	go t.monitor()
+	return t, nil
+}
+
+// monitor handles the server draining and closing the connection.
+func (t *inProcessClient) monitor() {
+	select {
+	case <-t.conn.goAway:
+		t.handleGoAway()
+	case <-t.conn.closed:
+		t.Close(connectionErrorf(true, nil, "transport: in-process connection closed by the server"))
+		return
+	case <-t.errorChan:
+		return
+	}
+	select {
+	case <-t.conn.closed:
+		t.Close(connectionErrorf(true, nil, "transport: in-process connection closed by the server"))
+	case <-t.errorChan:
+	}
+}
+
+func (t *inProcessClient) handleGoAway() {
+	t.mu.Lock()
+	if t.state != reachable {
+		t.mu.Unlock()
+		return
+	}
+	t.state = draining
+	close(t.goAway)
+	idle := len(t.streams) == 0
+	t.mu.Unlock()
+
+	t.onGoAway(GoAwayNoReason)
+	if idle {
+		t.Close(connectionErrorf(true, nil, "transport: the in-process server is draining"))
+	}
+}
+
+func (t *inProcessClient) authInfo() credentials.AuthInfo {
+	return inProcessAuthInfo{credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity}}
+}
+
+// NewStream creates a stream and its server stream, which is handled right
+// away. All non-nil errors returned will be *NewStreamError.
+func (t *inProcessClient) NewStream(ctx context.Context, callHdr *CallHdr) (*Stream, error) {
+	ctx = peer.NewContext(ctx, &peer.Peer{
+		Addr:     InProcessAddr,
+		AuthInfo: t.authInfo(),
+	})
+	md, err := t.createHeader(ctx, callHdr)
+	if err != nil {
+		return nil, &NewStreamError{Err: err, DoNotTransparentRetry: true}
+	}
+	s := &Stream{
+		id:             atomic.AddUint32(&t.nextID, 1),
+		ct:             t,
+		ctx:            ctx,
+		done:           make(chan struct{}),
+		method:         callHdr.Method,
+		sendCompress:   callHdr.SendCompress,
+		buf:            newRecvBuffer(),
+		headerChan:     make(chan struct{}),
+		contentSubtype: callHdr.ContentSubtype,
+		doneFunc:       callHdr.DoneFunc,
+		requestRead:    func(int) {},
+		inProcess:      true,
+		deepCopy:       t.conn.deepCopy,
+	}
+	s.trReader = &transportReader{
+		reader: &recvBufferReader{
+			ctx:     s.ctx,
+			ctxDone: s.ctx.Done(),
+			recv:    s.buf,
+			closeStream: func(err error) {
+				t.CloseStream(s, err)
+			},
+			freeBuffer: func(*bytes.Buffer) {},
+		},
+		windowHandler: func(int) {},
+	}
+
+	t.mu.Lock()
+	if state := t.state; state != reachable {
+		t.mu.Unlock()
+		err := error(errStreamDrain)
+		if state == closing {
+			err = ErrConnClosing
+		}
+		return nil, &NewStreamError{Err: err}
+	}
+	t.streams[s] = nil
+	t.mu.Unlock()
+
+	if t.statsHandler != nil {
+		t.statsHandler.HandleRPC(s.ctx, &stats.OutHeader{
+			Client:      true,
+			FullMethod:  callHdr.Method,
+			RemoteAddr:  InProcessAddr,
+			LocalAddr:   InProcessAddr,
+			Compression: callHdr.SendCompress,
+			Header:      md.Copy(),
+		})
+	}
+	ss, err := t.server.newStream(s, callHdr, md)
+	if err != nil {
+		if err == errStreamDrain {
+			// the server did not see the stream, which can be retried.
+			atomic.StoreUint32(&s.unprocessed, 1)
+		}
+		t.closeStream(s, err, status.Convert(err), nil)
+		return nil, &NewStreamError{Err: err, DoNotTransparentRetry: err != errStreamDrain}
+	}
+	t.mu.Lock()
+	_, active := t.streams[s]
+	if active {
+		t.streams[s] = ss
+	}
+	t.mu.Unlock()
+	if !active {
+		// closed by Close meanwhile
+		ss.cancel()
+	}
+	t.server.handleStream(ss)
+	return s, nil
+}
+
+// createHeader returns the metadata the server stream of a stream receives,
+// which holds the request headers a http2 server would see.
+func (t *inProcessClient) createHeader(ctx context.Context, callHdr *CallHdr) (metadata.MD, error) {
+	md := metadata.MD{
+		":authority":   []string{callHdr.Host},
+		"content-type": []string{grpcutil.ContentType(callHdr.ContentSubtype)},
+	}
+	if t.userAgent != "" {
+		md["user-agent"] = []string{t.userAgent}
+	}
+	if callHdr.PreviousAttempts > 0 {
+		md["grpc-previous-rpc-attempts"] = []string{strconv.Itoa(callHdr.PreviousAttempts)}
+	}
+	if b := stats.OutgoingTags(ctx); b != nil {
+		md["grpc-tags-bin"] = []string{string(b)}
+	}
+	if b := stats.OutgoingTrace(ctx); b != nil {
+		md["grpc-trace-bin"] = []string{string(b)}
+	}
+	if out, added, ok := metadata.FromOutgoingContextRaw(ctx); ok {
+		for k, vv := range out {
+			if isReservedHeader(k) {
+				continue
+			}
+			md[k] = append(md[k], vv...)
+		}
+		for _, vv := range added {
+			for i := 0; i+1 < len(vv); i += 2 {
+				k := strings.ToLower(vv[i])
+				if isReservedHeader(k) {
+					continue
+				}
+				md[k] = append(md[k], vv[i+1])
+			}
+		}
+	}
+
+	pos := strings.LastIndex(callHdr.Method, "/")
+	if pos == -1 {
+		pos = len(callHdr.Method)
+	}
+	audience := "inprocess://" + callHdr.Host + callHdr.Method[:pos]
+	data, err := perRPCMetadata(ctx, t.perRPCCreds, callHdr, t.authInfo(), audience)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range data {
+		md[k] = append(md[k], v)
+	}
+	return md, nil
+}
+
+// serverStream returns the server stream of the active stream @s.
+func (t *inProcessClient) serverStream(s *Stream) *Stream {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.streams[s]
+}
+
+// Write only half-closes @s, its messages are written by WriteObject.
+func (t *inProcessClient) Write(s *Stream, hdr []byte, data []byte, opts *Options) error {
+	if len(hdr) > 0 || len(data) > 0 || !opts.Last {
+		return errInProcessBytes
+	}
+	return t.writeObjects(s, nil, opts)
+}
+
+func (t *inProcessClient) writeObject(s *Stream, m interface{}, opts *Options) error {
+	return t.writeObjects(s, []recvMsg{{msg: m}}, opts)
+}
+
+// writeObjects delivers @msgs to the server stream of @s, followed by the end
+// of the stream if @opts.Last is set.
+func (t *inProcessClient) writeObjects(s *Stream, msgs []recvMsg, opts *Options) error {
+	if opts.Last {
+		// If it's the last message, update stream state.
+		if !s.compareAndSwapState(streamActive, streamWriteDone) {
+			return errStreamDone
+		}
+	} else if s.getState() != streamActive {
+		return errStreamDone
+	}
+	ss := t.serverStream(s)
+	if ss == nil {
+		return errStreamDone
+	}
+	for _, m := range msgs {
+		ss.write(m)
+	}
+	if opts.Last {
+		ss.write(recvMsg{err: io.EOF})
+	}
+	return nil
+}
+
+// operateHeader delivers the response header @md of the server stream of
+// @s.
+func (t *inProcessClient) operateHeader(s *Stream, contentSubtype, recvCompress string, md metadata.MD) {
+	if !atomic.CompareAndSwapUint32(&s.headerChanClosed, 0, 1) {
+		return
+	}
+	s.header = md
+	s.recvContentSubtype = contentSubtype
+	s.recvCompress = recvCompress
+	s.headerValid = true
+	close(s.headerChan)
+	if t.statsHandler != nil {
+		t.statsHandler.HandleRPC(s.ctx, &stats.InHeader{
+			Client:      true,
+			Compression: recvCompress,
+			Header:      md.Copy(),
+		})
+	}
+}
+
+// CloseStream clears the footprint of a stream when the stream is not needed
+// any more. A non-nil @err cancels the server stream.
+func (t *inProcessClient) CloseStream(s *Stream, err error) {
+	t.closeStream(s, err, status.Convert(err), nil)
+}
+
+func (t *inProcessClient) closeStream(s *Stream, err error, st *status.Status, trailer metadata.MD) {
+	if s.swapState(streamDone) == streamDone {
+		// If it was already done, return.  If multiple closeStream calls
+		// happen simultaneously, wait for the first to finish.
+		<-s.done
+		return
+	}
+	s.status = st
+	if len(trailer) > 0 {
+		s.trailer = trailer
+	}
+	if err != nil {
+		// This will unblock reads eventually.
+		s.write(recvMsg{err: err})
+	}
+	// If headerChan isn't closed, then close it.
+	if atomic.CompareAndSwapUint32(&s.headerChanClosed, 0, 1) {
+		s.noHeaders = true
+		close(s.headerChan)
+	}
+	if t.statsHandler != nil && err == io.EOF {
+		t.statsHandler.HandleRPC(s.ctx, &stats.InTrailer{
+			Client:  true,
+			Trailer: s.trailer.Copy(),
+		})
+	}
+
+	t.mu.Lock()
+	ss := t.streams[s]
+	if t.streams != nil {
+		delete(t.streams, s)
+	}
+	drained := t.state == draining && len(t.streams) == 0
+	t.mu.Unlock()
+	if ss != nil && err != io.EOF {
+		// the RPC is canceled, as a RST_STREAM would cancel it.
+		ss.cancel()
+	}
+	close(s.done)
+	if s.doneFunc != nil {
+		s.doneFunc()
+	}
+	if drained {
+		t.Close(connectionErrorf(true, nil, "transport: the connection is drained"))
+	}
+}
+
+// Close closes the connection, the pending streams fail with @err.
+func (t *inProcessClient) Close(err error) {
+	t.mu.Lock()
+	if t.state == closing {
+		t.mu.Unlock()
+		return
+	}
+	// Call t.onClose before setting the state to closing to prevent the client
+	// from attempting to create new streams ASAP.
+	t.onClose()
+	t.state = closing
+	streams := t.streams
+	t.streams = nil
+	t.mu.Unlock()
+
+	t.conn.Close()
+	close(t.errorChan)
+	st := status.New(codes.Unavailable, err.Error())
+	for s, ss := range streams {
+		if ss != nil {
+			ss.cancel()
+		}
+		t.closeStream(s, err, st, nil)
+	}
+	if t.statsHandler != nil {
+		connEnd := &stats.ConnEnd{
+			Client: true,
+		}
+		t.statsHandler.HandleConn(context.Background(), connEnd)
+	}
+}
+
+// GracefulClose closes the transport once the active streams are done.
+func (t *inProcessClient) GracefulClose() {
+	t.mu.Lock()
+	if t.state == closing {
+		t.mu.Unlock()
+		return
+	}
+	t.state = draining
+	idle := len(t.streams) == 0
+	t.mu.Unlock()
+	if idle {
+		t.Close(ErrConnClosing)
+	}
+}
+
+func (t *inProcessClient) Error() <-chan struct{} {
+	return t.errorChan
+}
+
+func (t *inProcessClient) GoAway() <-chan struct{} {
+	return t.goAway
+}
+
+func (t *inProcessClient) GetGoAwayReason() (GoAwayReason, string) {
+	select {
+	case <-t.goAway:
+		return GoAwayNoReason, "the in-process server is draining"
+	default:
+		return GoAwayInvalid, ""
+	}
+}
+
+func (t *inProcessClient) RemoteAddr() net.Addr {
+	return InProcessAddr
+}
+
+func (t *inProcessClient) IncrMsgSent() {}
+
+func (t *inProcessClient) IncrMsgRecv() {}
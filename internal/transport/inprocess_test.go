@@ -0,0 +1,178 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transport
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/metadata"
+	"github.com/dubbogo/grpc-go/resolver"
+	"github.com/dubbogo/grpc-go/stats"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+func (s) TestInProcessTransport(t *testing.T) {
+	conn := NewInProcessConn(false)
+	st, err := NewInProcessServerTransport(conn, &ServerConfig{})
+	assert.Nil(t, err)
+	go st.HandleStreams(func(s *Stream) {
+		go func() {
+			if s.Method() != "/test.Echo/Echo" {
+				st.WriteStatus(s, status.Newf(codes.Unimplemented, "unknown method %s", s.Method()))
+				return
+			}
+			md, _ := metadata.FromIncomingContext(s.Context())
+			if _, ok := s.Context().Deadline(); !ok {
+				md.Set("no-deadline", "true")
+			}
+			st.WriteHeader(s, md)
+			for {
+				msg, err := s.RecvObject()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					// canceled by the client
+					st.WriteStatus(s, status.Convert(err))
+					return
+				}
+				assert.Nil(t, s.WriteObject(msg, &Options{}))
+			}
+			s.SetTrailer(metadata.Pairs("x-trailer", "done"))
+			st.WriteStatus(s, status.New(codes.OK, ""))
+		}()
+	}, func(ctx context.Context, _ string) context.Context { return ctx })
+
+	dial := func(context.Context) (*InProcessConn, error) { return conn, nil }
+	ct, err := NewClientTransport(context.Background(), context.Background(), resolver.Address{}, ConnectOptions{InProcessDial: dial}, func() {}, func(GoAwayReason) {}, func() {})
+	assert.Nil(t, err)
+	defer ct.Close(ErrConnClosing)
+
+	ctx, cancel := context.WithTimeout(metadata.AppendToOutgoingContext(context.Background(), "x-trace", "abc"), 5*time.Second)
+	defer cancel()
+	s, err := ct.NewStream(ctx, &CallHdr{Host: "localhost", Method: "/test.Echo/Echo"})
+	assert.Nil(t, err)
+	assert.True(t, s.InProcess())
+	msg := &struct{ Name string }{Name: "world"}
+	assert.Nil(t, s.WriteObject(msg, &Options{}))
+	assert.Nil(t, s.WriteObject(nil, &Options{Last: true}))
+	header, err := s.Header()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"abc"}, header.Get("x-trace"))
+	assert.Equal(t, []string{"localhost"}, header.Get(":authority"))
+	assert.Empty(t, header.Get("no-deadline"))
+	// messages are passed as is
+	got, err := s.RecvObject()
+	assert.Nil(t, err)
+	assert.True(t, got == msg)
+	got, err = s.RecvObject()
+	assert.Nil(t, err)
+	assert.Nil(t, got)
+	_, err = s.RecvObject()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, codes.OK, s.Status().Code())
+	assert.Equal(t, []string{"done"}, s.Trailer().Get("x-trailer"))
+
+	// bytes can not be written
+	s, err = ct.NewStream(ctx, &CallHdr{Method: "/test.Echo/Echo"})
+	assert.Nil(t, err)
+	assert.NotNil(t, ct.Write(s, []byte{0, 0, 0, 0, 1}, []byte{1}, &Options{}))
+	ct.CloseStream(s, status.Error(codes.Canceled, "canceled"))
+
+	s, err = ct.NewStream(ctx, &CallHdr{Method: "/test.Echo/Unknown"})
+	assert.Nil(t, err)
+	_, err = s.RecvObject()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, codes.Unimplemented, s.Status().Code())
+	assert.True(t, s.TrailersOnly())
+
+	// the client stops creating streams once the server drains
+	st.Drain()
+	select {
+	case <-ct.GoAway():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the client transport did not receive the drain")
+	}
+	_, err = ct.NewStream(ctx, &CallHdr{Method: "/test.Echo/Echo"})
+	assert.NotNil(t, err)
+}
+
+// blockingTagger is a stats.Handler whose TagRPC blocks until @unblock is
+// closed.
+type blockingTagger struct {
+	tagged  chan struct{}
+	unblock chan struct{}
+}
+
+func (h *blockingTagger) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	close(h.tagged)
+	<-h.unblock
+	return ctx
+}
+
+func (h *blockingTagger) HandleRPC(context.Context, stats.RPCStats) {}
+
+func (h *blockingTagger) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *blockingTagger) HandleConn(context.Context, stats.ConnStats) {}
+
+func (s) TestInProcessHandleStreamsWaitsForHandle(t *testing.T) {
+	conn := NewInProcessConn(false)
+	// the stream is created, then the transport closes before it is handled
+	tagger := &blockingTagger{tagged: make(chan struct{}), unblock: make(chan struct{})}
+	st, err := NewInProcessServerTransport(conn, &ServerConfig{StatsHandler: tagger})
+	assert.Nil(t, err)
+	var returned, late int32
+	go func() {
+		st.HandleStreams(func(s *Stream) {
+			if atomic.LoadInt32(&returned) == 1 {
+				atomic.StoreInt32(&late, 1)
+			}
+			st.WriteStatus(s, status.New(codes.OK, ""))
+		}, func(ctx context.Context, _ string) context.Context { return ctx })
+		atomic.StoreInt32(&returned, 1)
+		close(tagger.unblock)
+	}()
+
+	dial := func(context.Context) (*InProcessConn, error) { return conn, nil }
+	ct, err := NewClientTransport(context.Background(), context.Background(), resolver.Address{}, ConnectOptions{InProcessDial: dial}, func() {}, func(GoAwayReason) {}, func() {})
+	assert.Nil(t, err)
+	defer ct.Close(ErrConnClosing)
+	created := make(chan struct{})
+	go func() {
+		ct.NewStream(context.Background(), &CallHdr{Method: "/test.Echo/Echo"})
+		close(created)
+	}()
+	<-tagger.tagged
+	st.Close()
+	<-created
+	assert.Equal(t, int32(0), atomic.LoadInt32(&late), "handle was called after HandleStreams returned")
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/dubbogo/grpc-go/keepalive"
 	"github.com/dubbogo/grpc-go/metadata"
 	"github.com/dubbogo/grpc-go/resolver"
+	"github.com/dubbogo/grpc-go/resolver/protocol"
 	"github.com/dubbogo/grpc-go/stats"
 	"github.com/dubbogo/grpc-go/status"
 	"github.com/dubbogo/grpc-go/tap"
@@ -72,6 +73,9 @@ func (p *bufferPool) put(b *bytes.Buffer) {
 // protocol specific info has been removed.
 type recvMsg struct {
 	buffer *bytes.Buffer
+	// msg is a message passed as an object by an in-process transport, instead
+	// of buffer.
+	msg interface{}
 	// nil: received some data
 	// io.EOF: stream is completed. data is nil.
 	// other non-nil error: transport failure. data is nil.
@@ -217,6 +221,9 @@ func (r *recvBufferReader) readAdditional(m recvMsg, p []byte) (n int, err error
 	if m.err != nil {
 		return 0, m.err
 	}
+	if m.buffer == nil {
+		return 0, errObjectMessage
+	}
 	copied, _ := m.buffer.Read(p)
 	if m.buffer.Len() == 0 {
 		r.freeBuffer(m.buffer)
@@ -240,7 +247,7 @@ const (
 type Stream struct {
 	id           uint32
 	st           ServerTransport    // nil for client side Stream
-	ct           *http2Client       // nil for server side Stream
+	ct           ClientTransport    // nil for server side Stream
 	ctx          context.Context    // the associated context of the stream
 	cancel       context.CancelFunc // always nil for client side Stream
 	done         chan struct{}      // closed at the end of stream to unblock writers. On the client side.
@@ -291,6 +298,21 @@ type Stream struct {
 	// contentSubtype is the content-subtype for requests.
 	// this must be lowercase or the behavior is undefined.
 	contentSubtype string
+	// sendContentSubtype is the content-subtype of responses negotiated by
+	// the server, it defaults to contentSubtype. Server-side only.
+	sendContentSubtype string
+	// recvContentSubtype is the content-subtype of the response headers.
+	// Client-side only.
+	recvContentSubtype string
+	// acceptContentSubtypes are the content-subtypes the client accepts for
+	// responses, in order of preference. Server-side only.
+	acceptContentSubtypes []string
+
+	// inProcess is set on the streams of in-process transports, whose
+	// messages are objects, see RecvObject. deepCopy is set if the messages
+	// must be deep copied.
+	inProcess bool
+	deepCopy  bool
 }
 
 // isHeaderSent is only valid on the server-side.
@@ -400,6 +422,34 @@ func (s *Stream) ContentSubtype() string {
 	return s.contentSubtype
 }
 
+// SendContentSubtype returns the content-subtype of responses, which is the
+// one set by SetSendContentSubtype or ContentSubtype. Server-side only.
+func (s *Stream) SendContentSubtype() string {
+	if s.sendContentSubtype != "" {
+		return s.sendContentSubtype
+	}
+	return s.contentSubtype
+}
+
+// SetSendContentSubtype sets the content-subtype of responses, it must be
+// called before the header is sent. Server-side only.
+func (s *Stream) SetSendContentSubtype(contentSubtype string) {
+	s.sendContentSubtype = contentSubtype
+}
+
+// RecvContentSubtype returns the content-subtype of the response headers, it
+// blocks until the headers are received. Client-side only.
+func (s *Stream) RecvContentSubtype() string {
+	s.waitOnHeader()
+	return s.recvContentSubtype
+}
+
+// AcceptContentSubtypes returns the content-subtypes the client accepts for
+// responses, in order of preference. Server-side only.
+func (s *Stream) AcceptContentSubtypes() []string {
+	return s.acceptContentSubtypes
+}
+
 // Context returns the context of the stream.
 func (s *Stream) Context() context.Context {
 	return s.ctx
@@ -534,6 +584,9 @@ type ServerConfig struct {
 	ChannelzParentID      int64
 	MaxHeaderListSize     *uint32
 	HeaderTableSize       *uint32
+	// MaxReceiveMessageSize limits the bodies of dubbo2 packages, which are
+	// read at once. Zero means hessian.DEFAULT_LEN.
+	MaxReceiveMessageSize int
 }
 
 // ConnectOptions covers all relevant options for communicating with the server.
@@ -570,12 +623,26 @@ type ConnectOptions struct {
 	MaxHeaderListSize *uint32
 	// UseProxy specifies if a proxy should be used.
 	UseProxy bool
+	// InProcessDial, if set, creates the in-process connections of an
+	// in-process transport instead of dialing addresses.
+	InProcessDial func(context.Context) (*InProcessConn, error)
 }
 
 // NewClientTransport establishes the transport with the required ConnectOptions
-// and returns it to the caller.
+// and returns it to the caller. The transport speaks the protocol of addr,
+// see the resolver/protocol package.
 func NewClientTransport(connectCtx, ctx context.Context, addr resolver.Address, opts ConnectOptions, onPrefaceReceipt func(), onGoAway func(GoAwayReason), onClose func()) (ClientTransport, error) {
-	return newHTTP2Client(connectCtx, ctx, addr, opts, onPrefaceReceipt, onGoAway, onClose)
+	if opts.InProcessDial != nil {
+		return newInProcessClient(connectCtx, opts, onPrefaceReceipt, onGoAway, onClose)
+	}
+	switch p := protocol.Get(addr); p {
+	case protocol.Triple:
+		return newHTTP2Client(connectCtx, ctx, addr, opts, onPrefaceReceipt, onGoAway, onClose)
+	case protocol.Dubbo:
+		return newDubboClient(connectCtx, addr, opts, onPrefaceReceipt, onGoAway, onClose)
+	default:
+		return nil, connectionErrorf(false, nil, "transport: unsupported protocol %q of address %s", p, addr.Addr)
+	}
 }
 
 // Options provides additional hints and information for message
@@ -609,6 +676,11 @@ type CallHdr struct {
 	// for more details.
 	ContentSubtype string
 
+	// AcceptContentSubtypes are the content-subtypes the client accepts for
+	// responses besides ContentSubtype, in order of preference. They are sent
+	// in the grpc-accept-content-subtype header.
+	AcceptContentSubtypes []string
+
 	PreviousAttempts int // value of grpc-previous-rpc-attempts header to set
 
 	DoneFunc func() // called when the stream is finished
@@ -0,0 +1,214 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"strings"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/encoding/generic"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+// MethodNameMapper resolves the method or stream of a registered service
+// handling the calls of a method name of the request path.
+//
+// Experimental
+//
+// Notice: This type is EXPERIMENTAL and may be changed or removed in a
+// later release.
+type MethodNameMapper interface {
+	// MapMethod returns the one of @names, the methods and streams of the
+	// service, handling the calls of @method, or "" if none does.
+	MapMethod(method string, names []string) string
+}
+
+// OverloadMethodNameMapper is a MethodNameMapper which also resolves the
+// unary methods of wrapper codec requests by their java argument types, the
+// ArgTypes of TripleRequestWrapper. MapOverload is called before MapMethod
+// for the methods HasOverloads reports, as an overload may share the name of
+// a go method. Calls neither resolves are served by the
+// GenericServiceHandler if any, or else fail with Unimplemented.
+//
+// Experimental
+//
+// Notice: This type is EXPERIMENTAL and may be changed or removed in a
+// later release.
+type OverloadMethodNameMapper interface {
+	MethodNameMapper
+	// HasOverloads reports whether the calls of @method may be resolved by
+	// MapOverload. Other calls are resolved by MapMethod alone, without
+	// reading the request.
+	HasOverloads(method string) bool
+	// MapOverload returns the one of @names handling the calls of @method
+	// with arguments of java types @argTypes, or "" if none does.
+	MapOverload(method string, argTypes []string, names []string) string
+}
+
+// MethodNameMapperFunc is a MethodNameMapper function.
+type MethodNameMapperFunc func(method string, names []string) string
+
+// MapMethod returns f(method, names).
+func (f MethodNameMapperFunc) MapMethod(method string, names []string) string {
+	return f(method, names)
+}
+
+var (
+	// ExactMethodNameMapper resolves the method of the same name, as gRPC does.
+	ExactMethodNameMapper MethodNameMapper = MethodNameMapperFunc(mapExactMethod)
+	// JavaMethodNameMapper resolves java method "sayHello" to go method
+	// "SayHello", or else the method of the same name. It is the default one.
+	JavaMethodNameMapper MethodNameMapper = MethodNameMapperFunc(mapJavaMethod)
+	// CaseInsensitiveMethodNameMapper resolves the method of the same name, or
+	// else of the java name, or else of the same name regardless of case.
+	CaseInsensitiveMethodNameMapper MethodNameMapper = MethodNameMapperFunc(mapCaseInsensitiveMethod)
+)
+
+func mapExactMethod(method string, names []string) string {
+	for _, name := range names {
+		if name == method {
+			return name
+		}
+	}
+	return ""
+}
+
+func mapJavaMethod(method string, names []string) string {
+	if name := mapExactMethod(capitalize(method), names); name != "" {
+		return name
+	}
+	return mapExactMethod(method, names)
+}
+
+func mapCaseInsensitiveMethod(method string, names []string) string {
+	if name := mapJavaMethod(method, names); name != "" {
+		return name
+	}
+	for _, name := range names {
+		if strings.EqualFold(name, method) {
+			return name
+		}
+	}
+	return ""
+}
+
+func capitalize(method string) string {
+	if method == "" {
+		return method
+	}
+	return strings.ToUpper(method[:1]) + method[1:]
+}
+
+// MethodSignature returns the signature "method(type1,type2)" of java
+// @method with arguments of @argTypes.
+func MethodSignature(method string, argTypes []string) string {
+	return method + "(" + strings.Join(argTypes, ",") + ")"
+}
+
+type overloadMethodNameMapper struct {
+	MethodNameMapper
+	signatures map[string]string
+	// java method names of signatures
+	methods map[string]bool
+}
+
+// NewOverloadMethodNameMapper returns an OverloadMethodNameMapper resolving
+// the calls of the java method signatures of @signatures, see MethodSignature,
+// to the go methods they are mapped to, e.g.
+//
+//	"sayHello(java.lang.String,int)": "SayHelloWithAge"
+//
+// Other calls are resolved by @mapper, JavaMethodNameMapper if nil. The
+// calls of the java methods of @signatures are resolved by their signatures
+// first, even if @mapper maps the java method to a go method, so that an
+// overload may share the name of the go method of another one.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func NewOverloadMethodNameMapper(signatures map[string]string, mapper MethodNameMapper) OverloadMethodNameMapper {
+	if mapper == nil {
+		mapper = JavaMethodNameMapper
+	}
+	methods := make(map[string]bool, len(signatures))
+	for signature := range signatures {
+		if pos := strings.IndexByte(signature, '('); pos != -1 {
+			methods[signature[:pos]] = true
+		}
+	}
+	return &overloadMethodNameMapper{MethodNameMapper: mapper, signatures: signatures, methods: methods}
+}
+
+func (m *overloadMethodNameMapper) HasOverloads(method string) bool {
+	return m.methods[method]
+}
+
+func (m *overloadMethodNameMapper) MapOverload(method string, argTypes []string, names []string) string {
+	goMethod, ok := m.signatures[MethodSignature(method, argTypes)]
+	if !ok {
+		return ""
+	}
+	return mapExactMethod(goMethod, names)
+}
+
+// MethodNameMapping returns a ServerOption that sets the MethodNameMapper of
+// the methods of registered services, JavaMethodNameMapper by default.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func MethodNameMapping(mapper MethodNameMapper) ServerOption {
+	return newFuncServerOption(func(o *serverOptions) {
+		o.methodNameMapper = mapper
+	})
+}
+
+// overloadMethodDesc returns a MethodDesc which calls the unary method of
+// @srv resolved by @mapper from the java argument types of the request, or
+// else by the name of @method, or else @handler, if not nil. The resolved go
+// method is the method of the context of the call.
+func overloadMethodDesc(mapper OverloadMethodNameMapper, handler GenericHandler, srv *serviceInfo, service, method string) *MethodDesc {
+	return &MethodDesc{
+		MethodName: method,
+		Handler: func(impl interface{}, ctx context.Context, dec func(interface{}) error, interceptor UnaryServerInterceptor) (interface{}, error) {
+			args := &generic.RawArgs{}
+			if err := dec(args); err != nil {
+				return nil, err
+			}
+			name := mapper.MapOverload(method, args.Types, srv.names)
+			if name == "" {
+				name = mapper.MapMethod(method, srv.names)
+			}
+			if md, ok := srv.methods[name]; ok {
+				return md.Handler(impl, newContextWithTripleMethod(ctx, name), args.Unmarshal, interceptor)
+			}
+			if handler != nil {
+				return genericMethodDesc(handler, service, method).Handler(impl, ctx, func(v interface{}) error {
+					*(v.(*generic.RawArgs)) = *args
+					return nil
+				}, interceptor)
+			}
+			return nil, status.Errorf(codes.Unimplemented, "unknown method %v for service %v", MethodSignature(method, args.Types), service)
+		},
+	}
+}
@@ -0,0 +1,198 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/encoding/generic"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+func (s) TestMethodNameMappers(t *testing.T) {
+	names := []string{"SayHello", "sayBye", "Stream"}
+	for _, test := range []struct {
+		mapper MethodNameMapper
+		method string
+		want   string
+	}{
+		{ExactMethodNameMapper, "SayHello", "SayHello"},
+		{ExactMethodNameMapper, "sayHello", ""},
+		{JavaMethodNameMapper, "sayHello", "SayHello"},
+		{JavaMethodNameMapper, "sayBye", "sayBye"},
+		{JavaMethodNameMapper, "", ""},
+		{CaseInsensitiveMethodNameMapper, "SAYBYE", "sayBye"},
+		{CaseInsensitiveMethodNameMapper, "stream", "Stream"},
+		{CaseInsensitiveMethodNameMapper, "unknown", ""},
+	} {
+		if got := test.mapper.MapMethod(test.method, names); got != test.want {
+			t.Errorf("MapMethod(%q) = %q, want %q", test.method, got, test.want)
+		}
+	}
+}
+
+func (s) TestOverloadMethodNameMapper(t *testing.T) {
+	mapper := NewOverloadMethodNameMapper(map[string]string{
+		"sayHello(java.lang.String,java.lang.String)": "SayHelloTo",
+		"sayHello(int)": "SayHelloMissing",
+	}, nil)
+	names := []string{"SayHello", "SayHelloTo"}
+	for _, test := range []struct {
+		argTypes []string
+		want     string
+	}{
+		{[]string{"java.lang.String", "java.lang.String"}, "SayHelloTo"},
+		{[]string{"int"}, ""},
+		{[]string{"long"}, ""},
+	} {
+		if got := mapper.MapOverload("sayHello", test.argTypes, names); got != test.want {
+			t.Errorf("MapOverload(%v) = %q, want %q", MethodSignature("sayHello", test.argTypes), got, test.want)
+		}
+	}
+	if got := mapper.MapMethod("sayHello", names); got != "SayHello" {
+		t.Errorf("MapMethod() = %q, want %q", got, "SayHello")
+	}
+}
+
+// stringArgs decodes arguments as strings.
+type stringArgs struct{}
+
+func (stringArgs) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*string)) = string(data)
+	return nil
+}
+
+func rawArgsDec(types []string, args ...string) func(interface{}) error {
+	return func(v interface{}) error {
+		raw := v.(*generic.RawArgs)
+		raw.Types = types
+		raw.Codec = stringArgs{}
+		for _, arg := range args {
+			raw.Data = append(raw.Data, []byte(arg))
+		}
+		return nil
+	}
+}
+
+func (s) TestOverloadMethodDesc(t *testing.T) {
+	mapper := NewOverloadMethodNameMapper(map[string]string{
+		"sayHello(java.lang.String,java.lang.String)": "SayHelloTo",
+	}, nil)
+	if !mapper.HasOverloads("sayHello") || mapper.HasOverloads("sayBye") {
+		t.Fatalf("HasOverloads is wrong for %v", mapper)
+	}
+
+	srv := &serviceInfo{
+		methods: map[string]*MethodDesc{
+			"SayHelloTo": {
+				MethodName: "SayHelloTo",
+				Handler: func(_ interface{}, _ context.Context, dec func(interface{}) error, _ UnaryServerInterceptor) (interface{}, error) {
+					var from, to string
+					if err := dec([]interface{}{&from, &to}); err != nil {
+						return nil, err
+					}
+					return from + " to " + to, nil
+				},
+			},
+		},
+		names: []string{"SayHelloTo"},
+	}
+
+	md := overloadMethodDesc(mapper, nil, srv, "svc", "sayHello")
+	reply, err := md.Handler(nil, context.Background(), rawArgsDec([]string{"java.lang.String", "java.lang.String"}, "a", "b"), nil)
+	if err != nil || reply != "a to b" {
+		t.Fatalf("overloaded call = %v, %v, want %q", reply, err, "a to b")
+	}
+
+	_, err = md.Handler(nil, context.Background(), rawArgsDec([]string{"int"}, "1"), nil)
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("unresolved overloaded call error = %v, want Unimplemented", err)
+	}
+
+	handler := GenericHandlerFunc(func(_ context.Context, interfaceName, methodName string, args *generic.RawArgs) (interface{}, error) {
+		return interfaceName + "." + MethodSignature(methodName, args.Types), nil
+	})
+	md = overloadMethodDesc(mapper, handler, srv, "svc", "sayHello")
+	reply, err = md.Handler(nil, context.Background(), rawArgsDec([]string{"int"}, "1"), nil)
+	if err != nil || reply != "svc.sayHello(int)" {
+		t.Fatalf("generic fallback call = %v, %v, want %q", reply, err, "svc.sayHello(int)")
+	}
+}
+
+// greetServiceDesc replies the go method serving the call and its arguments.
+var greetServiceDesc = ServiceDesc{
+	ServiceName: "org.Greeter",
+	HandlerType: (*interface{})(nil),
+	Methods: []MethodDesc{
+		{MethodName: "SayHello", Handler: greetHandler(1)},
+		{MethodName: "SayHelloTo", Handler: greetHandler(2)},
+	},
+}
+
+func greetHandler(argc int) methodHandler {
+	return func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ UnaryServerInterceptor) (interface{}, error) {
+		args := make([]string, argc)
+		params := make([]interface{}, argc)
+		for i := range args {
+			params[i] = &args[i]
+		}
+		if err := dec(params); err != nil {
+			return nil, err
+		}
+		method, _ := TripleMethodFromContext(ctx)
+		return method + ":" + strings.Join(args, ","), nil
+	}
+}
+
+func (s) TestOverloadSharingMethodName(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() failed: %v", err)
+	}
+	srv := NewServer(MethodNameMapping(NewOverloadMethodNameMapper(map[string]string{
+		"sayHello(java.lang.String,java.lang.String)": "SayHelloTo",
+	}, nil)))
+	srv.RegisterService(&greetServiceDesc, struct{}{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+	cc, err := Dial(lis.Addr().String(), WithInsecure())
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	defer cc.Close()
+
+	for _, test := range []struct {
+		argTypes []string
+		args     []interface{}
+		want     string
+	}{
+		{[]string{"java.lang.String"}, []interface{}{"a"}, "SayHello:a"},
+		{[]string{"java.lang.String", "java.lang.String"}, []interface{}{"a", "b"}, "SayHelloTo:a,b"},
+	} {
+		reply, _, err := cc.GenericInvoke(context.Background(), "org.Greeter", "sayHello", test.argTypes, test.args)
+		if err != nil || reply != test.want {
+			t.Errorf("%v = %v, %v, want %q", MethodSignature("sayHello", test.argTypes), reply, err, test.want)
+		}
+	}
+}
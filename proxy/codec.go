@@ -0,0 +1,81 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/encoding"
+)
+
+// Name is the name of the pass-through codec. It is not registered, so that
+// peers can not negotiate it.
+const Name = "proxy"
+
+// Frame is a message forwarded as it is, without being decoded.
+type Frame struct {
+	Payload []byte
+}
+
+// Codec returns the pass-through codec, which only marshals and unmarshals
+// *Frame. A proxy server must force it by grpc.ForceServerCodec.
+func Codec() encoding.TwoWayCodec {
+	return rawCodec{}
+}
+
+type rawCodec struct{}
+
+func (rawCodec) Name() string {
+	return Name
+}
+
+func (rawCodec) MarshalRequest(v interface{}) ([]byte, error) {
+	return marshal(v)
+}
+
+func (rawCodec) MarshalResponse(v interface{}) ([]byte, error) {
+	return marshal(v)
+}
+
+func (rawCodec) UnmarshalRequest(data []byte, v interface{}) error {
+	return unmarshal(data, v)
+}
+
+func (rawCodec) UnmarshalResponse(data []byte, v interface{}) error {
+	return unmarshal(data, v)
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	frame, ok := v.(*Frame)
+	if !ok {
+		return nil, perrors.Errorf("proxy codec can not marshal %T, but *proxy.Frame", v)
+	}
+	return frame.Payload, nil
+}
+
+func unmarshal(data []byte, v interface{}) error {
+	frame, ok := v.(*Frame)
+	if !ok {
+		return perrors.Errorf("proxy codec can not unmarshal to %T, but *proxy.Frame", v)
+	}
+	// grpc allocates a new buffer for every received message, keep it as it is
+	frame.Payload = data
+	return nil
+}
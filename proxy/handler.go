@@ -0,0 +1,198 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package proxy implements a transparent reverse proxy of grpc and triple
+// calls. Payloads are forwarded without being decoded, with their headers,
+// trailers, deadline, cancellation, status details and content-subtype.
+//
+// A server proxying every unknown service:
+//
+//	server := grpc.NewServer(
+//		grpc.ForceServerCodec(proxy.Codec()),
+//		grpc.UnknownServiceHandler(proxy.TransparentHandler(director)))
+//
+// Proxy services can also be registered by RegisterService and routed by
+// grpc.ProxyRoutes.
+//
+// Experimental
+//
+// Notice: This package is EXPERIMENTAL and may be changed or removed in a
+// later release.
+package proxy
+
+import (
+	"context"
+	"io"
+)
+
+import (
+	grpc "github.com/dubbogo/grpc-go"
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/encoding/proto"
+	"github.com/dubbogo/grpc-go/metadata"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+// StreamDirector picks the backend of the call of @fullMethodName. The
+// returned context must be derived from @ctx, so that the deadline and the
+// cancellation of the call are forwarded. The incoming metadata is forwarded
+// as the outgoing metadata unless the returned context carries some.
+type StreamDirector func(ctx context.Context, fullMethodName string) (context.Context, *grpc.ClientConn, error)
+
+// clientStreamDesc forwards unary calls as streams as well.
+var clientStreamDesc = &grpc.StreamDesc{
+	ServerStreams: true,
+	ClientStreams: true,
+}
+
+// TransparentHandler returns a grpc.StreamHandler forwarding every call to
+// the backend picked by @director. It is meant for grpc.UnknownServiceHandler.
+func TransparentHandler(director StreamDirector) grpc.StreamHandler {
+	h := &handler{director: director}
+	return h.handle
+}
+
+// RegisterService registers a proxy service named @serviceName forwarding
+// every call routed to it by grpc.ProxyRoutes to the backend picked by @director.
+func RegisterService(server *grpc.Server, serviceName string, director StreamDirector) {
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: serviceName,
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    grpc.ProxyStreamMethod,
+			Handler:       TransparentHandler(director),
+			ServerStreams: true,
+			ClientStreams: true,
+		}},
+	}, nil)
+}
+
+type handler struct {
+	director StreamDirector
+}
+
+func (h *handler) handle(_ interface{}, serverStream grpc.ServerStream) error {
+	ctx := serverStream.Context()
+	fullMethodName, ok := grpc.MethodFromServerStream(serverStream)
+	if !ok {
+		return status.Errorf(codes.Internal, "proxy: no method in the context of server stream")
+	}
+	outgoingCtx, backend, err := h.director(ctx, fullMethodName)
+	if err != nil {
+		return err
+	}
+	if _, ok := metadata.FromOutgoingContext(outgoingCtx); !ok {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			outgoingCtx = metadata.NewOutgoingContext(outgoingCtx, md.Copy())
+		}
+	}
+
+	// keep the content-subtype, "" is the same with proto
+	subtype, _ := grpc.ContentSubtype(ctx)
+	if subtype == "" {
+		subtype = proto.Name
+	}
+	clientCtx, clientCancel := context.WithCancel(outgoingCtx)
+	defer clientCancel()
+	clientStream, err := backend.NewStream(clientCtx, clientStreamDesc, fullMethodName,
+		grpc.ForceCodec(Codec()), grpc.CallContentSubtype(subtype))
+	if err != nil {
+		return err
+	}
+
+	s2cErrChan := forwardServerToClient(serverStream, clientStream)
+	c2sErrChan := forwardClientToServer(clientStream, serverStream)
+	// the backend ends the call, the client may end sending earlier
+	for {
+		select {
+		case s2cErr := <-s2cErrChan:
+			if s2cErr != io.EOF {
+				clientCancel()
+				return status.Errorf(codes.Internal, "proxy: failed to forward request: %v", s2cErr)
+			}
+			// the client ends sending, the backend goes on replying
+			clientStream.CloseSend()
+			s2cErrChan = nil
+		case c2sErr := <-c2sErrChan:
+			serverStream.SetTrailer(clientStream.Trailer())
+			if c2sErr != io.EOF {
+				// the status of the backend, with its details
+				return c2sErr
+			}
+			return nil
+		}
+	}
+}
+
+// forwardServerToClient forwards the requests of @src to @dst, the returned
+// channel receives io.EOF when @src ends sending.
+func forwardServerToClient(src grpc.ServerStream, dst grpc.ClientStream) chan error {
+	ret := make(chan error, 1)
+	go func() {
+		frame := &Frame{}
+		for {
+			if err := src.RecvMsg(frame); err != nil {
+				ret <- err
+				return
+			}
+			if err := dst.SendMsg(frame); err != nil {
+				// the backend failed, its status is returned by dst.RecvMsg
+				ret <- io.EOF
+				return
+			}
+		}
+	}()
+	return ret
+}
+
+// forwardClientToServer forwards the header and the responses of @src to
+// @dst, the returned channel receives io.EOF when @src ends successfully.
+func forwardClientToServer(src grpc.ClientStream, dst grpc.ServerStream) chan error {
+	ret := make(chan error, 1)
+	go func() {
+		frame := &Frame{}
+		for i := 0; ; i++ {
+			if err := src.RecvMsg(frame); err != nil {
+				if i == 0 {
+					// sent along with the status
+					if md, hdrErr := src.Header(); hdrErr == nil {
+						dst.SetHeader(md)
+					}
+				}
+				ret <- err
+				return
+			}
+			if i == 0 {
+				// the header is only readable after the first response
+				md, err := src.Header()
+				if err != nil {
+					ret <- err
+					return
+				}
+				if err := dst.SendHeader(md); err != nil {
+					ret <- err
+					return
+				}
+			}
+			if err := dst.SendMsg(frame); err != nil {
+				ret <- err
+				return
+			}
+		}
+	}()
+	return ret
+}
@@ -0,0 +1,141 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	grpc "github.com/dubbogo/grpc-go"
+	"github.com/dubbogo/grpc-go/codes"
+	_ "github.com/dubbogo/grpc-go/encoding/json"
+	"github.com/dubbogo/grpc-go/metadata"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+const greeterMethod = "/org.example.Greeter/SayHello"
+
+var greeterServiceDesc = grpc.ServiceDesc{
+	ServiceName: "org.example.Greeter",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "SayHello",
+		Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+			var name string
+			if err := dec(&name); err != nil {
+				return nil, err
+			}
+			if subtype, _ := grpc.ContentSubtype(ctx); subtype != "json" {
+				return nil, status.Errorf(codes.InvalidArgument, "content-subtype %q", subtype)
+			}
+			if _, ok := ctx.Deadline(); !ok {
+				return nil, status.Errorf(codes.InvalidArgument, "no deadline")
+			}
+			md, _ := metadata.FromIncomingContext(ctx)
+			grpc.SetHeader(ctx, metadata.Pairs("x-backend", "greeter"))
+			grpc.SetTrailer(ctx, metadata.MD{"x-trace": md.Get("x-trace")})
+			if name == "" {
+				return nil, status.FromJavaException(codes.Unknown, &status.JavaException{
+					ClassName: "java.lang.IllegalArgumentException",
+					Message:   "empty name",
+				}).Err()
+			}
+			return "hello " + name, nil
+		},
+	}},
+}
+
+func serve(t *testing.T, server *grpc.Server) *grpc.ClientConn {
+	lis, err := net.Listen("tcp", "localhost:0")
+	assert.Nil(t, err)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	cc, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	assert.Nil(t, err)
+	t.Cleanup(func() { cc.Close() })
+	return cc
+}
+
+func newBackend(t *testing.T) *grpc.ClientConn {
+	backend := grpc.NewServer()
+	backend.RegisterService(&greeterServiceDesc, nil)
+	return serve(t, backend)
+}
+
+func testProxy(t *testing.T, cc *grpc.ClientConn) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-trace", "t1")
+
+	var reply string
+	var header metadata.MD
+	trailer, err := cc.Invoke(ctx, greeterMethod, []interface{}{"dubbo"}, &reply,
+		grpc.CallContentSubtype("json"), grpc.Header(&header))
+	assert.Nil(t, err)
+	assert.Equal(t, "hello dubbo", reply)
+	assert.Equal(t, []string{"greeter"}, header.Get("x-backend"))
+	assert.Equal(t, []string{"t1"}, trailer.Get("x-trace"))
+
+	_, err = cc.Invoke(ctx, greeterMethod, []interface{}{""}, &reply,
+		grpc.CallContentSubtype("json"), grpc.Header(&header))
+	assert.Equal(t, codes.Unknown, status.Code(err))
+	var ex *status.JavaException
+	assert.True(t, errors.As(err, &ex))
+	assert.Equal(t, "java.lang.IllegalArgumentException", ex.ClassName)
+	assert.Equal(t, []string{"greeter"}, header.Get("x-backend"))
+}
+
+func TestTransparentHandler(t *testing.T) {
+	backend := newBackend(t)
+	director := func(ctx context.Context, fullMethodName string) (context.Context, *grpc.ClientConn, error) {
+		if fullMethodName != greeterMethod {
+			return nil, nil, status.Errorf(codes.Unimplemented, "unknown method %s", fullMethodName)
+		}
+		return ctx, backend, nil
+	}
+	testProxy(t, serve(t, grpc.NewServer(
+		grpc.ForceServerCodec(Codec()),
+		grpc.UnknownServiceHandler(TransparentHandler(director)))))
+}
+
+func TestRegisterService(t *testing.T) {
+	backend := newBackend(t)
+	director := func(ctx context.Context, _ string) (context.Context, *grpc.ClientConn, error) {
+		return ctx, backend, nil
+	}
+	server := grpc.NewServer(
+		grpc.ForceServerCodec(Codec()),
+		grpc.ProxyRoutes(grpc.ProxyRoute{Prefix: "org.example.", Service: "example.proxy"}))
+	RegisterService(server, "example.proxy", director)
+	cc := serve(t, server)
+	testProxy(t, cc)
+
+	_, err := cc.Invoke(context.Background(), "/com.other.Service/Get", []interface{}{"x"}, new(string),
+		grpc.CallContentSubtype("json"))
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
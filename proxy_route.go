@@ -0,0 +1,110 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// ProxyServiceKey is the service proxying every unknown service when
+	// ProxyModeEnable is set, same with triple.constant.ProxyServiceKey.
+	ProxyServiceKey = "github.com.dubbogo.triple.proxy"
+	// ProxyUnaryMethod is the unary method of a proxy service handling the
+	// unary requests of every method it proxies.
+	ProxyUnaryMethod = "InvokeWithArgs"
+	// ProxyStreamMethod is the stream of a proxy service handling the requests
+	// of every method it proxies as streams, unary ones included, if the proxy
+	// service has no ProxyUnaryMethod.
+	ProxyStreamMethod = "InvokeStream"
+)
+
+// ProxyRoute routes the requests of unknown services matching it to the
+// registered proxy service named Service.
+//
+// Experimental
+//
+// Notice: This type is EXPERIMENTAL and may be changed or removed in a
+// later release.
+type ProxyRoute struct {
+	// Prefix matches the service names starting with it, "" matches every service.
+	Prefix string
+	// Regexp matches the service names it matches, it is used instead of Prefix if set.
+	Regexp *regexp.Regexp
+	// Service is the name of the proxy service, registered by RegisterService.
+	Service string
+}
+
+func (r *ProxyRoute) match(service string) bool {
+	if r.Regexp != nil {
+		return r.Regexp.MatchString(service)
+	}
+	return strings.HasPrefix(service, r.Prefix)
+}
+
+// ProxyRoutes returns a ServerOption that appends @routes to the proxy
+// routes. The requests of a service which is not registered are handled by the
+// proxy service of the first matching route, see ProxyUnaryMethod and
+// ProxyStreamMethod. Routes are matched before the catch-all route of
+// ProxyModeEnable. Requests routed to a proxy service which is not registered
+// are left to the generic and unknown service handlers.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func ProxyRoutes(routes ...ProxyRoute) ServerOption {
+	return newFuncServerOption(func(o *serverOptions) {
+		o.proxyRoutes = append(o.proxyRoutes, routes...)
+	})
+}
+
+// proxyRoute returns the route of unknown @service, or nil if none matches.
+func (s *Server) proxyRoute(service string) *ProxyRoute {
+	for i := range s.opts.proxyRoutes {
+		if s.opts.proxyRoutes[i].match(service) {
+			return &s.opts.proxyRoutes[i]
+		}
+	}
+	if s.opts.proxyModeEnable {
+		return &ProxyRoute{Service: ProxyServiceKey}
+	}
+	return nil
+}
+
+// proxyMethod returns the handler of @method of proxy service @srv, resolved
+// by @mapper, or else the proxy method, along with the name it is served as.
+// @md is for unary requests, @sd for streams.
+func proxyMethod(srv *serviceInfo, mapper MethodNameMapper, method string) (name string, md *MethodDesc, sd *StreamDesc) {
+	if name := mapper.MapMethod(method, srv.names); name != "" {
+		if md, ok := srv.methods[name]; ok {
+			return name, md, nil
+		}
+		if sd, ok := srv.streams[name]; ok {
+			return name, nil, sd
+		}
+	}
+	if md, ok := srv.methods[ProxyUnaryMethod]; ok {
+		return method, md, nil
+	}
+	if sd, ok := srv.streams[ProxyStreamMethod]; ok {
+		return method, nil, sd
+	}
+	return "", nil, nil
+}
@@ -0,0 +1,121 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+import (
+	wrapperspb "github.com/golang/protobuf/ptypes/wrappers"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+func (s) TestProxyRoute(t *testing.T) {
+	routes := ProxyRoutes(
+		// the regexp is used instead of the prefix
+		ProxyRoute{Prefix: "org.a.", Regexp: regexp.MustCompile(`^org\.b\.`), Service: "regexp"},
+		ProxyRoute{Prefix: "org.", Service: "prefix"},
+		ProxyRoute{Prefix: "org.b.", Service: "shadowed"},
+	)
+	for _, test := range []struct {
+		proxyMode bool
+		service   string
+		want      string
+	}{
+		{service: "org.b.Greeter", want: "regexp"},
+		{service: "org.a.Greeter", want: "prefix"},
+		{service: "org.c.Greeter", want: "prefix"},
+		{service: "com.Greeter", want: ""},
+		{proxyMode: true, service: "com.Greeter", want: ProxyServiceKey},
+		{proxyMode: true, service: "org.b.Greeter", want: "regexp"},
+	} {
+		srv := NewServer(routes, ProxyModeEnable(test.proxyMode))
+		got := ""
+		if route := srv.proxyRoute(test.service); route != nil {
+			got = route.Service
+		}
+		if got != test.want {
+			t.Errorf("proxyRoute(%q) with proxy mode %v = %q, want %q", test.service, test.proxyMode, got, test.want)
+		}
+	}
+}
+
+func (s) TestProxyMethod(t *testing.T) {
+	unary, invoke := &MethodDesc{MethodName: "SayHello"}, &MethodDesc{MethodName: ProxyUnaryMethod}
+	stream := &StreamDesc{StreamName: ProxyStreamMethod}
+	both := &serviceInfo{
+		methods: map[string]*MethodDesc{"SayHello": unary, ProxyUnaryMethod: invoke},
+		streams: map[string]*StreamDesc{ProxyStreamMethod: stream},
+		names:   []string{"SayHello", ProxyUnaryMethod, ProxyStreamMethod},
+	}
+	streamOnly := &serviceInfo{
+		methods: map[string]*MethodDesc{},
+		streams: map[string]*StreamDesc{ProxyStreamMethod: stream},
+		names:   []string{ProxyStreamMethod},
+	}
+	for _, test := range []struct {
+		srv      *serviceInfo
+		method   string
+		wantName string
+		wantMD   *MethodDesc
+		wantSD   *StreamDesc
+	}{
+		{srv: both, method: "sayHello", wantName: "SayHello", wantMD: unary},
+		{srv: both, method: "sayBye", wantName: "sayBye", wantMD: invoke},
+		{srv: streamOnly, method: "sayBye", wantName: "sayBye", wantSD: stream},
+		{srv: &serviceInfo{}, method: "sayBye"},
+	} {
+		name, md, sd := proxyMethod(test.srv, JavaMethodNameMapper, test.method)
+		if name != test.wantName || md != test.wantMD || sd != test.wantSD {
+			t.Errorf("proxyMethod(%q) = %q, %v, %v, want %q, %v, %v", test.method, name, md, sd, test.wantName, test.wantMD, test.wantSD)
+		}
+	}
+}
+
+func (s) TestProxyRouteUnregisteredService(t *testing.T) {
+	route := ProxyRoutes(ProxyRoute{Prefix: "org.", Service: "org.Missing"})
+	callUnknown := func(cc *ClientConn) error {
+		_, err := cc.Invoke(context.Background(), "/org.Greeter/sayHello", &wrapperspb.StringValue{}, new(wrapperspb.StringValue), CallContentSubtype("subtype-a"))
+		return err
+	}
+
+	cc, stop := startEchoServer(t, route)
+	defer stop()
+	err := callUnknown(cc)
+	if status.Code(err) != codes.Unimplemented || !strings.Contains(status.Convert(err).Message(), "org.Missing") {
+		t.Errorf("call routed to an unregistered proxy service = %v, want Unimplemented naming it", err)
+	}
+
+	// the unknown service handler still serves the request
+	handled := UnknownServiceHandler(func(interface{}, ServerStream) error {
+		return status.Error(codes.Aborted, "unknown service handler")
+	})
+	cc, stopHandled := startEchoServer(t, route, handled)
+	defer stopHandled()
+	if err := callUnknown(cc); status.Code(err) != codes.Aborted {
+		t.Errorf("call routed to an unregistered proxy service = %v, want the unknown service handler error", err)
+	}
+}
@@ -0,0 +1,349 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reflection
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+import (
+	hessian "github.com/apache/dubbo-go-hessian2"
+
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+
+	perrors "github.com/pkg/errors"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/encoding"
+)
+
+// POJOService describes a service which is not defined by protobuf, e.g. a
+// java interface served with hessian2 or msgpack through InvokeWithArgs, so
+// that reflection can list it and tools can discover its methods and types.
+//
+// Experimental
+//
+// Notice: This type is EXPERIMENTAL and may be changed or removed in a
+// later release.
+type POJOService struct {
+	// ServiceName is the full name of the service, e.g. the java interface
+	// "org.apache.dubbo.UserProvider".
+	ServiceName string
+	Methods     []POJOMethod
+}
+
+// POJOMethod describes a method of a POJOService by sample go values.
+type POJOMethod struct {
+	// Name is the method name of the request path, e.g. "getUser".
+	Name string
+	// Args are values of the argument types, e.g. "" or &User{}.
+	Args []interface{}
+	// Reply is a value of the reply type, nil for void.
+	Reply interface{}
+
+	ClientStreams bool
+	ServerStreams bool
+}
+
+// POJOMetadata returns the gzipped synthesized file descriptor of @svc, to be
+// the Metadata of its grpc.ServiceDesc.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func POJOMetadata(svc POJOService) ([]byte, error) {
+	fd, err := NewPOJOFileDescriptor(svc)
+	if err != nil {
+		return nil, err
+	}
+	data, err := proto.Marshal(fd)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NewPOJOFileDescriptor synthesizes the file descriptor of @svc. The request
+// of a method is the message "<Method>Request" with a field per argument,
+// its reply is the field "result" of "<Method>Response". Structs are
+// messages named after their java class, whose fields are named after their
+// hessian tags. The java types are given by the comments.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func NewPOJOFileDescriptor(svc POJOService) (*dpb.FileDescriptorProto, error) {
+	pos := strings.LastIndexByte(svc.ServiceName, '.')
+	if pos <= 0 || pos == len(svc.ServiceName)-1 {
+		return nil, perrors.Errorf("service name %q has no package", svc.ServiceName)
+	}
+	b := &pojoBuilder{
+		pkg: svc.ServiceName[:pos],
+		file: &dpb.FileDescriptorProto{
+			Name:           proto.String("dubbo/" + svc.ServiceName + ".proto"),
+			Package:        proto.String(svc.ServiceName[:pos]),
+			Syntax:         proto.String("proto3"),
+			SourceCodeInfo: &dpb.SourceCodeInfo{},
+		},
+		messages: make(map[reflect.Type]string),
+		names:    make(map[string]bool),
+	}
+	service := &dpb.ServiceDescriptorProto{Name: proto.String(svc.ServiceName[pos+1:])}
+	b.file.Service = append(b.file.Service, service)
+
+	for _, m := range svc.Methods {
+		if m.Name == "" {
+			return nil, perrors.Errorf("method of service %q has no name", svc.ServiceName)
+		}
+		methodName := strings.ToUpper(m.Name[:1]) + m.Name[1:]
+
+		req, reqIdx := b.newMessage(methodName + "Request")
+		for i, arg := range m.Args {
+			field := b.field("arg"+strconv.Itoa(i), int32(i+1), reflect.TypeOf(arg), req)
+			req.Field = append(req.Field, field)
+			b.comment(" java type: "+encoding.GetArgType(arg), 4, reqIdx, 2, int32(i))
+		}
+		resp, respIdx := b.newMessage(methodName + "Response")
+		if m.Reply != nil {
+			resp.Field = append(resp.Field, b.field("result", 1, reflect.TypeOf(m.Reply), resp))
+			b.comment(" java type: "+encoding.GetArgType(m.Reply), 4, respIdx, 2, 0)
+		}
+
+		service.Method = append(service.Method, &dpb.MethodDescriptorProto{
+			Name:            proto.String(m.Name),
+			InputType:       proto.String(b.fullName(req.GetName())),
+			OutputType:      proto.String(b.fullName(resp.GetName())),
+			ClientStreaming: proto.Bool(m.ClientStreams),
+			ServerStreaming: proto.Bool(m.ServerStreams),
+		})
+	}
+	return b.file, nil
+}
+
+type pojoBuilder struct {
+	pkg      string
+	file     *dpb.FileDescriptorProto
+	messages map[reflect.Type]string // struct type -> message name
+	names    map[string]bool
+}
+
+func (b *pojoBuilder) fullName(name string) string {
+	return "." + b.pkg + "." + name
+}
+
+// newMessage adds a top level message named @name, or @name with a number
+// suffix if taken, and returns it with its index.
+func (b *pojoBuilder) newMessage(name string) (*dpb.DescriptorProto, int32) {
+	unique := name
+	for i := 2; b.names[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	b.names[unique] = true
+	msg := &dpb.DescriptorProto{Name: proto.String(unique)}
+	b.file.MessageType = append(b.file.MessageType, msg)
+	return msg, int32(len(b.file.MessageType) - 1)
+}
+
+// comment adds @text as the leading comment of the element at @path.
+func (b *pojoBuilder) comment(text string, path ...int32) {
+	b.file.SourceCodeInfo.Location = append(b.file.SourceCodeInfo.Location, &dpb.SourceCodeInfo_Location{
+		Path:            path,
+		Span:            []int32{0, 0, 0},
+		LeadingComments: proto.String(text),
+	})
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+// field returns field @name of @parent of go type @t.
+func (b *pojoBuilder) field(name string, number int32, t reflect.Type, parent *dpb.DescriptorProto) *dpb.FieldDescriptorProto {
+	field := &dpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    dpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != nil && t != bytesType {
+		switch t.Kind() {
+		case reflect.Slice, reflect.Array:
+			if elem := derefType(t.Elem()); elem.Kind() != reflect.Slice && elem.Kind() != reflect.Array && elem.Kind() != reflect.Map {
+				field.Label = dpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+				t = elem
+			}
+		case reflect.Map:
+			field.Label = dpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			field.Type = dpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			field.TypeName = proto.String(b.mapEntry(name, t, parent))
+			return field
+		}
+	}
+	field.Type, field.TypeName = b.fieldType(t)
+	return field
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// mapEntry adds the map entry of field @name of @parent, and returns its full name.
+func (b *pojoBuilder) mapEntry(name string, t reflect.Type, parent *dpb.DescriptorProto) string {
+	entryName := mapEntryName(name)
+	entry := &dpb.DescriptorProto{
+		Name:    proto.String(entryName),
+		Options: &dpb.MessageOptions{MapEntry: proto.Bool(true)},
+	}
+	key := b.field("key", 1, t.Key(), entry)
+	switch key.GetType() {
+	case dpb.FieldDescriptorProto_TYPE_MESSAGE, dpb.FieldDescriptorProto_TYPE_BYTES,
+		dpb.FieldDescriptorProto_TYPE_FLOAT, dpb.FieldDescriptorProto_TYPE_DOUBLE:
+		// not allowed map keys
+		key.Type, key.TypeName = dpb.FieldDescriptorProto_TYPE_STRING.Enum(), nil
+	}
+	key.Label = dpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	value := b.field("value", 2, t.Elem(), entry)
+	if value.GetLabel() == dpb.FieldDescriptorProto_LABEL_REPEATED {
+		// no repeated map values
+		value.Label = dpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+		value.Type, value.TypeName = dpb.FieldDescriptorProto_TYPE_BYTES.Enum(), nil
+	}
+	entry.Field = []*dpb.FieldDescriptorProto{key, value}
+	parent.NestedType = append(parent.NestedType, entry)
+	return b.fullName(parent.GetName() + "." + entryName)
+}
+
+// mapEntryName returns the name protoc gives to the map entry of field @name,
+// e.g. "UserAttrsEntry" of "user_attrs": underscores are dropped and the
+// first letter and every letter following one are upper-cased.
+func mapEntryName(name string) string {
+	var sb strings.Builder
+	sb.Grow(len(name) + len("Entry"))
+	upper := true
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_':
+			upper = true
+		case upper && 'a' <= c && c <= 'z':
+			sb.WriteByte(c - 'a' + 'A')
+			upper = false
+		default:
+			sb.WriteByte(c)
+			upper = false
+		}
+	}
+	sb.WriteString("Entry")
+	return sb.String()
+}
+
+// fieldType returns the proto type of go type @t, and its message name.
+// Values which have no proto type, e.g. interface{}, are bytes.
+func (b *pojoBuilder) fieldType(t reflect.Type) (*dpb.FieldDescriptorProto_Type, *string) {
+	if t == nil {
+		return dpb.FieldDescriptorProto_TYPE_BYTES.Enum(), nil
+	}
+	if t == timeType {
+		// java.util.Date, milliseconds since epoch
+		return dpb.FieldDescriptorProto_TYPE_INT64.Enum(), nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return dpb.FieldDescriptorProto_TYPE_BOOL.Enum(), nil
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return dpb.FieldDescriptorProto_TYPE_INT32.Enum(), nil
+	case reflect.Int, reflect.Int64:
+		return dpb.FieldDescriptorProto_TYPE_INT64.Enum(), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return dpb.FieldDescriptorProto_TYPE_UINT32.Enum(), nil
+	case reflect.Uint, reflect.Uint64:
+		return dpb.FieldDescriptorProto_TYPE_UINT64.Enum(), nil
+	case reflect.Float32:
+		return dpb.FieldDescriptorProto_TYPE_FLOAT.Enum(), nil
+	case reflect.Float64:
+		return dpb.FieldDescriptorProto_TYPE_DOUBLE.Enum(), nil
+	case reflect.String:
+		return dpb.FieldDescriptorProto_TYPE_STRING.Enum(), nil
+	case reflect.Struct:
+		return dpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), proto.String(b.fullName(b.structMessage(t)))
+	default:
+		return dpb.FieldDescriptorProto_TYPE_BYTES.Enum(), nil
+	}
+}
+
+// structMessage returns the name of the message of struct @t, adding it if needed.
+func (b *pojoBuilder) structMessage(t reflect.Type) string {
+	if name, ok := b.messages[t]; ok {
+		return name
+	}
+
+	name, javaClass := t.Name(), ""
+	if pojo, ok := reflect.New(t).Interface().(hessian.POJO); ok {
+		javaClass = pojo.JavaClassName()
+		name = javaClass[strings.LastIndexByte(javaClass, '.')+1:]
+	}
+	if name == "" {
+		name = "Struct"
+	}
+	msg, idx := b.newMessage(name)
+	// before the fields, which may refer to it
+	b.messages[t] = msg.GetName()
+	if javaClass != "" {
+		b.comment(" java class: "+javaClass, 4, idx)
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		fieldName := sf.Tag.Get("hessian")
+		if fieldName == "-" {
+			continue
+		}
+		if fieldName == "" {
+			fieldName = strings.ToLower(sf.Name[:1]) + sf.Name[1:]
+		}
+		msg.Field = append(msg.Field, b.field(fieldName, int32(len(msg.Field)+1), sf.Type, msg))
+	}
+	return msg.GetName()
+}
@@ -0,0 +1,127 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reflection
+
+import (
+	"testing"
+	"time"
+)
+
+import (
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+type pojoAddress struct {
+	City string
+}
+
+func (pojoAddress) JavaClassName() string {
+	return "org.apache.dubbo.Address"
+}
+
+type pojoUser struct {
+	ID         string            `hessian:"id"`
+	Name       string            `hessian:"user_name"`
+	Age        int32             `hessian:"age"`
+	Tags       []string          `hessian:"tags"`
+	Attrs      map[string]string `hessian:"user_attrs"`
+	Scores     map[int64]float64 `hessian:"scores_by_day"`
+	Address    *pojoAddress      `hessian:"address"`
+	Friends    []*pojoUser       `hessian:"friends"`
+	Birthday   time.Time         `hessian:"birthday"`
+	Avatar     []byte            `hessian:"avatar"`
+	Extra      interface{}       `hessian:"extra"`
+	Ignored    string            `hessian:"-"`
+	unexported string
+}
+
+func (pojoUser) JavaClassName() string {
+	return "org.apache.dubbo.User"
+}
+
+func TestMapEntryName(t *testing.T) {
+	for name, want := range map[string]string{
+		"attrs":         "AttrsEntry",
+		"user_attrs":    "UserAttrsEntry",
+		"userAttrs":     "UserAttrsEntry",
+		"_private":      "PrivateEntry",
+		"scores_by_day": "ScoresByDayEntry",
+		"a__b":          "ABEntry",
+		"v2_map":        "V2MapEntry",
+	} {
+		if got := mapEntryName(name); got != want {
+			t.Errorf("mapEntryName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestNewPOJOFileDescriptor(t *testing.T) {
+	fdp, err := NewPOJOFileDescriptor(POJOService{
+		ServiceName: "org.apache.dubbo.UserProvider",
+		Methods: []POJOMethod{
+			{Name: "getUser", Args: []interface{}{"", int32(0)}, Reply: &pojoUser{}},
+			{Name: "addUsers", Args: []interface{}{[]*pojoUser{}}, ClientStreams: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewPOJOFileDescriptor() failed: %v", err)
+	}
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("protodesc.NewFile() failed: %v", err)
+	}
+
+	svc := fd.Services().ByName("UserProvider")
+	if svc == nil || svc.Methods().Len() != 2 {
+		t.Fatalf("service UserProvider = %v, want 2 methods", svc)
+	}
+	if m := svc.Methods().ByName("addUsers"); m == nil || !m.IsStreamingClient() {
+		t.Errorf("method addUsers = %v, want a client stream", m)
+	}
+	user := fd.Messages().ByName("User")
+	if user == nil {
+		t.Fatalf("message User not found")
+	}
+	for name, kind := range map[protoreflect.Name]protoreflect.Kind{
+		"id":            protoreflect.StringKind,
+		"user_name":     protoreflect.StringKind,
+		"age":           protoreflect.Int32Kind,
+		"user_attrs":    protoreflect.MessageKind,
+		"scores_by_day": protoreflect.MessageKind,
+		"address":       protoreflect.MessageKind,
+		"birthday":      protoreflect.Int64Kind,
+		"avatar":        protoreflect.BytesKind,
+		"extra":         protoreflect.BytesKind,
+	} {
+		f := user.Fields().ByName(name)
+		if f == nil || f.Kind() != kind {
+			t.Errorf("field %v = %v, want kind %v", name, f, kind)
+		}
+	}
+	if f := user.Fields().ByName("user_attrs"); f == nil || !f.IsMap() {
+		t.Errorf("field user_attrs = %v, want a map", f)
+	}
+	if f := user.Fields().ByName("friends"); f == nil || !f.IsList() || f.Message() != user {
+		t.Errorf("field friends = %v, want a list of User", f)
+	}
+	if user.Fields().ByName("Ignored") != nil || user.Fields().ByName("unexported") != nil {
+		t.Errorf("message User has ignored fields")
+	}
+}
@@ -76,6 +76,7 @@ type serverReflectionServer struct {
 	initSymbols  sync.Once
 	serviceNames []string
 	symbols      map[string]*dpb.FileDescriptorProto // map of fully-qualified names to files
+	files        map[string]*dpb.FileDescriptorProto // map of names to files of service metadata
 }
 
 // Register registers the server reflection service on the given gRPC server.
@@ -98,10 +99,13 @@ func (s *serverReflectionServer) getSymbols() (svcNames []string, symbolIndex ma
 		serviceInfo := s.s.GetServiceInfo()
 
 		s.symbols = map[string]*dpb.FileDescriptorProto{}
+		s.files = map[string]*dpb.FileDescriptorProto{}
 		s.serviceNames = make([]string, 0, len(serviceInfo))
 		processed := map[string]struct{}{}
 		for svc, info := range serviceInfo {
 			s.serviceNames = append(s.serviceNames, svc)
+			// dubbo service keys of the implementations with a group or a version
+			s.serviceNames = append(s.serviceNames, info.Keys...)
 			fdenc, ok := parseMetadata(info.Metadata)
 			if !ok {
 				continue
@@ -110,8 +114,18 @@ func (s *serverReflectionServer) getSymbols() (svcNames []string, symbolIndex ma
 			if err != nil {
 				continue
 			}
+			// files which are not registered to proto, e.g. of POJOMetadata
+			s.files[fd.GetName()] = fd
 			s.processFile(fd, processed)
 		}
+		// service keys resolve to the file of their service
+		for svc, info := range serviceInfo {
+			if fd, ok := s.symbols[svc]; ok {
+				for _, key := range info.Keys {
+					s.symbols[key] = fd
+				}
+			}
+		}
 		sort.Strings(s.serviceNames)
 	})
 
@@ -319,6 +333,10 @@ func fileDescWithDependencies(fd *dpb.FileDescriptorProto, sentFileDescriptors m
 func (s *serverReflectionServer) fileDescEncodingByFilename(name string, sentFileDescriptors map[string]bool) ([][]byte, error) {
 	enc := proto.FileDescriptor(name)
 	if enc == nil {
+		s.getSymbols()
+		if fd, ok := s.files[name]; ok {
+			return fileDescWithDependencies(fd, sentFileDescriptors)
+		}
 		return nil, fmt.Errorf("unknown file: %v", name)
 	}
 	fd, err := decodeFileDesc(enc)
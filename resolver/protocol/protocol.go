@@ -0,0 +1,58 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package protocol declares the protocol spoken to an address, to be set by
+// resolvers wishing to connect to providers which do not serve triple.
+//
+// Experimental
+//
+// Notice: This package is EXPERIMENTAL and may be changed or removed in a
+// later release.
+package protocol
+
+import (
+	"github.com/dubbogo/grpc-go/resolver"
+)
+
+const (
+	// Triple is the default protocol, gRPC over HTTP/2.
+	Triple = "tri"
+	// Dubbo is the dubbo2 protocol, i.e. dubbo:// with hessian2 bodies. Only
+	// unary calls of the content-subtype hessian2 can be made to its
+	// addresses.
+	Dubbo = "dubbo"
+)
+
+// keyType is the key to use for storing the protocol in Attributes.
+type keyType string
+
+const key = keyType("grpc.resolver.protocol")
+
+// Set returns a copy of the provided address with attributes containing
+// protocol.
+func Set(address resolver.Address, protocol string) resolver.Address {
+	address.Attributes = address.Attributes.WithValue(key, protocol)
+	return address
+}
+
+// Get returns the protocol of the resolver.Address, or Triple if not present.
+func Get(address resolver.Address) string {
+	if v, ok := address.Attributes.Value(key).(string); ok && v != "" {
+		return v
+	}
+	return Triple
+}
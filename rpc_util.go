@@ -160,6 +160,7 @@ type callInfo struct {
 	maxSendMessageSize    *int
 	creds                 credentials.PerRPCCredentials
 	contentSubtype        string
+	acceptContentSubtypes []string
 	codec                 encoding.TwoWayCodec
 	maxRetryRPCBufferSize int
 }
@@ -595,7 +596,9 @@ func encode(encodeType string, c encoding.TwoWayCodec, msg interface{}) ([]byte,
 	}
 	var b []byte
 	var err error
-	if encodeType == "req" {
+	if bc, ok := c.(encoding.BufferedTwoWayCodec); ok {
+		b, err = encodeBuffered(encodeType, bc, msg)
+	} else if encodeType == "req" {
 		b, err = c.MarshalRequest(msg)
 	} else {
 		b, err = c.MarshalResponse(msg)
@@ -609,6 +612,23 @@ func encode(encodeType string, c encoding.TwoWayCodec, msg interface{}) ([]byte,
 	return b, nil
 }
 
+// encodeBuffered serializes msg into a pooled buffer, and copies it out once
+// since the transport keeps the returned bytes until they are written.
+func encodeBuffered(encodeType string, c encoding.BufferedTwoWayCodec, msg interface{}) ([]byte, error) {
+	buf := encoding.GetBuffer()
+	defer encoding.PutBuffer(buf)
+	var err error
+	if encodeType == "req" {
+		err = c.MarshalRequestTo(buf, msg)
+	} else {
+		err = c.MarshalResponseTo(buf, msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
 // compress returns the input bytes compressed by compressor or cp.  If both
 // compressors are nil, returns nil.
 //
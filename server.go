@@ -109,6 +109,11 @@ type serviceInfo struct {
 	methods     map[string]*MethodDesc
 	streams     map[string]*StreamDesc
 	mdata       interface{}
+	// names of methods and streams, for MethodNameMapper
+	names []string
+	// group and version of a dubbo service implementation
+	group   string
+	version string
 }
 
 type serverWorkerData struct {
@@ -131,15 +136,22 @@ type Server struct {
 	drain    bool
 	cv       *sync.Cond              // signaled when connections close for GracefulStop
 	services map[string]*serviceInfo // service name -> service info
-	events   trace.EventLog
+	// dubbo service key -> service info, of the services registered with
+	// a group or a version
+	serviceKeys map[string]*serviceInfo
+	events      trace.EventLog
 
 	quit               *grpcsync.Event
 	done               *grpcsync.Event
+	draining           *grpcsync.Event // fired when Shutdown or GracefulStop starts draining, for DrainNotify
 	channelzRemoveOnce sync.Once
 	serveWG            sync.WaitGroup // counts active Serve goroutines for GracefulStop
 
 	channelzID int64 // channelz unique identification number
 	czData     *channelzData
+	// content-subtypes without a registered codec which getCodec already
+	// warned about
+	unknownSubtypes sync.Map
 
 	serverWorkerChannels []chan *serverWorkerData
 }
@@ -170,6 +182,15 @@ type serverOptions struct {
 	headerTableSize       *uint32
 	numServerWorkers      uint32
 	proxyModeEnable       bool
+	genericHandler        GenericHandler
+	proxyRoutes           []ProxyRoute
+	serviceKeyFallback    bool
+	methodNameMapper      MethodNameMapper
+	stackPolicy           *status.StackPolicy
+	strictContentSubtype  bool
+	goAwayBatch           int
+	goAwayInterval        time.Duration
+	allowOrigin           func(origin string) bool
 }
 
 var defaultServerOptions = serverOptions{
@@ -518,7 +539,8 @@ func NumStreamWorkers(numServerWorkers uint32) ServerOption {
 }
 
 // ProxyModeEnable enable grpc server proxy mechanism.
-// grpc will search proxy service to handle stream when it does not find target service key
+// grpc will search proxy service to handle stream when it does not find target service key.
+// It is a catch-all route to ProxyServiceKey matched after ProxyRoutes.
 func ProxyModeEnable(enable bool) ServerOption {
 	return newFuncServerOption(func(o *serverOptions) {
 		o.proxyModeEnable = enable
@@ -577,13 +599,15 @@ func NewServer(opt ...ServerOption) *Server {
 		o.apply(&opts)
 	}
 	s := &Server{
-		lis:      make(map[net.Listener]bool),
-		opts:     opts,
-		conns:    make(map[string]map[transport.ServerTransport]bool),
-		services: make(map[string]*serviceInfo),
-		quit:     grpcsync.NewEvent(),
-		done:     grpcsync.NewEvent(),
-		czData:   new(channelzData),
+		lis:         make(map[net.Listener]bool),
+		opts:        opts,
+		conns:       make(map[string]map[transport.ServerTransport]bool),
+		services:    make(map[string]*serviceInfo),
+		serviceKeys: make(map[string]*serviceInfo),
+		quit:        grpcsync.NewEvent(),
+		done:        grpcsync.NewEvent(),
+		draining:    grpcsync.NewEvent(),
+		czData:      new(channelzData),
 	}
 	chainUnaryServerInterceptors(s)
 	chainStreamServerInterceptors(s)
@@ -636,6 +660,11 @@ type ServiceRegistrar interface {
 // invoking Serve. If ss is non-nil (for legacy code), its type is checked to
 // ensure it implements sd.HandlerType.
 func (s *Server) RegisterService(sd *ServiceDesc, ss interface{}) {
+	s.checkServiceImpl(sd, ss)
+	s.register(sd, ss, "", "")
+}
+
+func (s *Server) checkServiceImpl(sd *ServiceDesc, ss interface{}) {
 	if ss != nil {
 		ht := reflect.TypeOf(sd.HandlerType).Elem()
 		st := reflect.TypeOf(ss)
@@ -643,34 +672,51 @@ func (s *Server) RegisterService(sd *ServiceDesc, ss interface{}) {
 			logger.Fatalf("grpc: Server.RegisterService found the handler of type %v that does not satisfy %v", st, ht)
 		}
 	}
-	s.register(sd, ss)
 }
 
-func (s *Server) register(sd *ServiceDesc, ss interface{}) {
+func (s *Server) register(sd *ServiceDesc, ss interface{}, group, version string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.printf("RegisterService(%q)", sd.ServiceName)
+	key := ServiceKey(group, sd.ServiceName, version)
+	s.printf("RegisterService(%q)", key)
 	if s.serve {
-		logger.Fatalf("grpc: Server.RegisterService after Server.Serve for %q", sd.ServiceName)
+		logger.Fatalf("grpc: Server.RegisterService after Server.Serve for %q", key)
 	}
-	if _, ok := s.services[sd.ServiceName]; ok {
-		logger.Fatalf("grpc: Server.RegisterService found duplicate service registration for %q", sd.ServiceName)
+	if group == "" && version == "" {
+		if srv, ok := s.services[sd.ServiceName]; ok && srv.group == "" && srv.version == "" {
+			logger.Fatalf("grpc: Server.RegisterService found duplicate service registration for %q", key)
+		}
+	} else if _, ok := s.serviceKeys[key]; ok {
+		logger.Fatalf("grpc: Server.RegisterService found duplicate service registration for %q", key)
 	}
 	info := &serviceInfo{
 		serviceImpl: ss,
 		methods:     make(map[string]*MethodDesc),
 		streams:     make(map[string]*StreamDesc),
 		mdata:       sd.Metadata,
+		group:       group,
+		version:     version,
 	}
 	for i := range sd.Methods {
 		d := &sd.Methods[i]
 		info.methods[d.MethodName] = d
+		info.names = append(info.names, d.MethodName)
 	}
 	for i := range sd.Streams {
 		d := &sd.Streams[i]
 		info.streams[d.StreamName] = d
+		info.names = append(info.names, d.StreamName)
+	}
+	if group == "" && version == "" {
+		s.services[sd.ServiceName] = info
+		return
+	}
+	s.serviceKeys[key] = info
+	// the first implementation is the default one, until one without
+	// group and version is registered
+	if _, ok := s.services[sd.ServiceName]; !ok {
+		s.services[sd.ServiceName] = info
 	}
-	s.services[sd.ServiceName] = info
 }
 
 // MethodInfo contains the information of an RPC including its method name and type.
@@ -688,6 +734,9 @@ type ServiceInfo struct {
 	Methods []MethodInfo
 	// Metadata is the metadata specified in ServiceDesc when registering service.
 	Metadata interface{}
+	// Keys are the sorted dubbo service keys of the implementations registered
+	// by RegisterServiceWithGroupVersion, see ServiceKey.
+	Keys []string
 }
 
 // GetServiceInfo returns a map from service names to ServiceInfo.
@@ -714,6 +763,7 @@ func (s *Server) GetServiceInfo() map[string]ServiceInfo {
 		ret[n] = ServiceInfo{
 			Methods:  methods,
 			Metadata: srv.mdata,
+			Keys:     s.serviceKeysOf(n),
 		}
 	}
 	return ret
@@ -749,7 +799,37 @@ func (l *listenSocket) Close() error {
 // Serve returns when lis.Accept fails with fatal errors.  lis will be closed when
 // this method returns.
 // Serve will return a non-nil error unless Stop or GracefulStop is called.
+// The streams of an InProcessListener pass their messages as objects.
 func (s *Server) Serve(lis net.Listener) error {
+	if _, ok := lis.(*InProcessListener); ok {
+		return s.serveListener(lis, s.newInProcessTransport)
+	}
+	return s.serveListener(lis, s.newHTTP2Transport)
+}
+
+// ServeDubbo is Serve for consumers of the dubbo2 protocol, i.e. dubbo://
+// with hessian2 bodies: it accepts incoming connections on lis and serves
+// their requests with the services registered for triple, so that one Server
+// serves triple and dubbo2 consumers on two listeners. Requests are unary
+// calls of the content-subtype hessian2, whose message is a
+// TripleRequestWrapper of the arguments; their group and version are read
+// from the tri-service-group and tri-service-version metadata. Requests
+// larger than MaxRecvMsgSize are answered with a bad request status without
+// being read into memory. The hessian2 codec must be registered by importing
+//  _ "github.com/dubbogo/grpc-go/encoding/hessian"
+// otherwise the connections of lis are closed.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func (s *Server) ServeDubbo(lis net.Listener) error {
+	return s.serveListener(lis, s.newDubboTransport)
+}
+
+// serveListener is Serve with the transports of the accepted connections
+// created by newTransport.
+func (s *Server) serveListener(lis net.Listener, newTransport func(net.Conn) transport.ServerTransport) error {
 	s.mu.Lock()
 	s.printf("serving")
 	s.serve = true
@@ -831,7 +911,7 @@ func (s *Server) Serve(lis net.Listener) error {
 		// s.conns before this conn can be added.
 		s.serveWG.Add(1)
 		go func() {
-			s.handleRawConn(lis.Addr().String(), rawConn)
+			s.handleRawConn(lis.Addr().String(), rawConn, newTransport)
 			s.serveWG.Done()
 		}()
 	}
@@ -839,7 +919,7 @@ func (s *Server) Serve(lis net.Listener) error {
 
 // handleRawConn forks a goroutine to handle a just-accepted connection that
 // has not had any I/O performed on it yet.
-func (s *Server) handleRawConn(lisAddr string, rawConn net.Conn) {
+func (s *Server) handleRawConn(lisAddr string, rawConn net.Conn, newTransport func(net.Conn) transport.ServerTransport) {
 	if s.quit.HasFired() {
 		rawConn.Close()
 		return
@@ -847,7 +927,7 @@ func (s *Server) handleRawConn(lisAddr string, rawConn net.Conn) {
 	rawConn.SetDeadline(time.Now().Add(s.opts.connectionTimeout))
 
 	// Finish handshaking (HTTP2)
-	st := s.newHTTP2Transport(rawConn)
+	st := newTransport(rawConn)
 	rawConn.SetDeadline(time.Time{})
 	if st == nil {
 		return
@@ -907,7 +987,35 @@ func (s *Server) newHTTP2Transport(c net.Conn) transport.ServerTransport {
 		return nil
 	}
 
-	return st
+	return s.withStackPolicy(st)
+}
+
+// newDubboTransport sets up a dubbo2 transport (using the dubbo2 server
+// transport in transport/dubbo_server.go).
+func (s *Server) newDubboTransport(c net.Conn) transport.ServerTransport {
+	config := &transport.ServerConfig{
+		ConnectionTimeout: s.opts.connectionTimeout,
+		Credentials:       s.opts.creds,
+		InTapHandle:       s.opts.inTapHandle,
+		StatsHandler:          s.opts.statsHandler,
+		ChannelzParentID:      s.channelzID,
+		MaxReceiveMessageSize: s.opts.maxReceiveMessageSize,
+	}
+	st, err := transport.NewDubboServerTransport(c, config)
+	if err != nil {
+		s.mu.Lock()
+		s.errorf("NewDubboServerTransport(%q) failed: %v", c.RemoteAddr(), err)
+		s.mu.Unlock()
+		if err != credentials.ErrConnDispatched {
+			if err != io.EOF {
+				channelz.Warning(logger, s.channelzID, "grpc: Server.ServeDubbo failed to create ServerTransport: ", err)
+			}
+			c.Close()
+		}
+		return nil
+	}
+
+	return s.withStackPolicy(st)
 }
 
 func (s *Server) serveStreams(st transport.ServerTransport) {
@@ -955,6 +1063,21 @@ var _ http.Handler = (*Server)(nil)
 // practically this means that the Request must also have arrived
 // over TLS.
 //
+// ServeHTTP also responds to gRPC-Web requests, of the content-types
+// "application/grpc-web" and "application/grpc-web-text", whose
+// trailers are sent in the body, and to unary requests of triple's
+// JSON gateway: POST requests of the content-type "application/json",
+// whose body is the json request message and whose response body is
+// the json response message. Errors of json requests are replied by
+// the HTTP status code of their status, with the json form of their
+// google.rpc.Status as the body. Arguments of methods taking several
+// arguments are sent as a json array. The json gateway requires
+// importing the encoding/json package, which registers its codec.
+// Neither needs HTTP/2, so browsers and curl can call services
+// directly. Browsers only let pages of other origins call them when
+// the server is created with WebCORS, which answers their OPTIONS
+// preflights; otherwise requests must be same-origin.
+//
 // To share one port (such as 443 for https) between gRPC and an
 // existing http.Handler, use a root http.Handler such as:
 //
@@ -975,11 +1098,15 @@ var _ http.Handler = (*Server)(nil)
 // Notice: This API is EXPERIMENTAL and may be changed or removed in a
 // later release.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	st, err := transport.NewServerHandlerTransport(w, r, s.opts.statsHandler)
+	if s.handleCORS(w, r) {
+		return
+	}
+	st, err := transport.NewServerHandlerTransport(w, r, s.opts.statsHandler, s.opts.maxReceiveMessageSize)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	st = s.withStackPolicy(st)
 	if !s.addConn(listenerAddressForServeHTTP, st) {
 		return
 	}
@@ -1055,6 +1182,7 @@ func (s *Server) channelzMetric() *channelz.ServerInternalMetric {
 		CallsSucceeded:           atomic.LoadInt64(&s.czData.callsSucceeded),
 		CallsFailed:              atomic.LoadInt64(&s.czData.callsFailed),
 		LastCallStartedTimestamp: time.Unix(0, atomic.LoadInt64(&s.czData.lastCallStartedTime)),
+		ServiceKeys:              s.allServiceKeys(),
 	}
 }
 
@@ -1072,7 +1200,10 @@ func (s *Server) incrCallsFailed() {
 }
 
 func (s *Server) sendResponse(t transport.ServerTransport, stream *transport.Stream, msg interface{}, cp Compressor, opts *transport.Options, comp encoding.Compressor) error {
-	data, err := encode("rsp", s.getCodec(stream.ContentSubtype()), msg)
+	if stream.InProcess() {
+		return s.sendResponseObject(stream, msg, opts)
+	}
+	data, err := encode("rsp", s.getCodec(stream.SendContentSubtype()), msg)
 	if err != nil {
 		channelz.Error(logger, s.channelzID, "grpc: server failed to encode response: ", err)
 		return err
@@ -1094,6 +1225,21 @@ func (s *Server) sendResponse(t transport.ServerTransport, stream *transport.Str
 	return err
 }
 
+// sendResponseObject is sendResponse for in-process streams, which send the
+// message object, or its encoding.
+func (s *Server) sendResponseObject(stream *transport.Stream, msg interface{}, opts *transport.Options) error {
+	obj, data, err := prepareObject("rsp", msg, s.getCodec(stream.SendContentSubtype()), stream.DeepCopy())
+	if err != nil {
+		channelz.Error(logger, s.channelzID, "grpc: server failed to encode response: ", err)
+		return err
+	}
+	err = stream.WriteObject(obj, opts)
+	if err == nil && s.opts.statsHandler != nil {
+		s.opts.statsHandler.HandleRPC(stream.Context(), objectPayload(false, msg, data, time.Now()))
+	}
+	return err
+}
+
 // chainUnaryServerInterceptors chains all unary server interceptors into one.
 func chainUnaryServerInterceptors(s *Server) {
 	// Prepend opts.unaryInt to the chaining interceptors if it exists, since unaryInt will
@@ -1258,7 +1404,16 @@ func (s *Server) processUnaryRPC(method string, t transport.ServerTransport, str
 	if sh != nil || binlog != nil {
 		payInfo = &payloadInfo{}
 	}
-	d, err := recvAndDecompress(&parser{r: stream}, stream, dc, s.opts.maxReceiveMessageSize, payInfo, decomp)
+	var (
+		d   []byte
+		obj interface{}
+	)
+	if stream.InProcess() {
+		// the request is assigned by df
+		obj, err = stream.RecvObject()
+	} else {
+		d, err = recvAndDecompress(&parser{r: stream}, stream, dc, s.opts.maxReceiveMessageSize, payInfo, decomp)
+	}
 	if err != nil {
 		if e := t.WriteStatus(stream, status.Convert(err)); e != nil {
 			channelz.Warningf(logger, s.channelzID, "grpc: Server.processUnaryRPC failed to write status %v", e)
@@ -1269,7 +1424,13 @@ func (s *Server) processUnaryRPC(method string, t transport.ServerTransport, str
 		t.IncrMsgRecv()
 	}
 	df := func(v interface{}) error {
-		if err := s.getCodec(stream.ContentSubtype()).UnmarshalRequest(d, v); err != nil {
+		if stream.InProcess() {
+			data, err := assignObject("req", s.getCodec(stream.ContentSubtype()), obj, v)
+			if err != nil {
+				return err
+			}
+			d = data
+		} else if err := s.getCodec(stream.ContentSubtype()).UnmarshalRequest(d, v); err != nil {
 			return status.Errorf(codes.Internal, "grpc: error unmarshalling request: %v", err)
 		}
 		if sh != nil {
@@ -1292,9 +1453,8 @@ func (s *Server) processUnaryRPC(method string, t transport.ServerTransport, str
 		return nil
 	}
 	ctx := NewContextWithServerTransportStream(stream.Context(), stream)
-	ctx = context.WithValue(ctx, "XXX_TRIPLE_GO_METHOD_NAME", method)
-	ctx = context.WithValue(ctx, "XXX_TRIPLE_GO_INTERFACE_NAME", stream.Method())
-	ctx = context.WithValue(ctx, "XXX_TRIPLE_GO_GENERIC_PAYLOAD", d)
+	ctx = s.newContextWithDrain(ctx)
+	ctx = newContextWithTripleInfo(ctx, method, stream.Method(), d)
 	reply, appErr := md.Handler(info.serviceImpl, ctx, df, s.opts.unaryInt)
 	if appErr != nil {
 		appStatus, ok := status.FromError(appErr)
@@ -1335,16 +1495,8 @@ func (s *Server) processUnaryRPC(method string, t transport.ServerTransport, str
 	if result, ok := reply.(OuterResult); ok {
 		// proceess header trailer
 		outerAttachment := result.Attachments()
-		responseAttachment = make(metadata.MD, len(outerAttachment))
 		channelz.Infof(logger, s.channelzID, "unaryProcessor.processUnaryRPC: get outerAttachment = %+v", outerAttachment)
-		for k, v := range outerAttachment {
-			if str, ok := v.(string); ok {
-				responseAttachment[k] = []string{str}
-			} else if strs, ok := v.([]string); ok {
-				responseAttachment[k] = strs
-			}
-			// todo deal with unsupported attachment
-		}
+		responseAttachment = attachmentsToMetadata(stream.Context(), sh, outerAttachment)
 		channelz.Infof(logger, s.channelzID, "unaryProcessor.processUnaryRPC: get triple attachment = %+v", responseAttachment)
 		rawReplyStruct = result.Result()
 		channelz.Infof(logger, s.channelzID, "unaryProcessor.processUnaryRPC: get reply %+v to be marshal", rawReplyStruct)
@@ -1477,12 +1629,15 @@ func (s *Server) processStreamingRPC(t transport.ServerTransport, stream *transp
 		sh.HandleRPC(stream.Context(), statsBegin)
 	}
 	ctx := NewContextWithServerTransportStream(stream.Context(), stream)
+	ctx = s.newContextWithDrain(ctx)
+	ctx = newContextWithTripleInfo(ctx, streamMethodName(sd, stream.Method()), stream.Method(), nil)
 	ss := &serverStream{
 		ctx:                   ctx,
 		t:                     t,
 		s:                     stream,
 		p:                     &parser{r: stream},
 		codec:                 s.getCodec(stream.ContentSubtype()),
+		sendCodec:             s.getCodec(stream.SendContentSubtype()),
 		maxReceiveMessageSize: s.opts.maxReceiveMessageSize,
 		maxSendMessageSize:    s.opts.maxSendMessageSize,
 		trInfo:                trInfo,
@@ -1656,45 +1811,122 @@ func (s *Server) handleStream(t transport.ServerTransport, stream *transport.Str
 		}
 		return
 	}
+	if st := s.negotiateContentSubtype(stream); st != nil {
+		if trInfo != nil {
+			trInfo.tr.LazyLog(&fmtStringer{"%v", []interface{}{st.Message()}}, true)
+			trInfo.tr.SetError()
+		}
+		if err := t.WriteStatus(stream, st); err != nil {
+			if trInfo != nil {
+				trInfo.tr.LazyLog(&fmtStringer{"%v", []interface{}{err}}, true)
+				trInfo.tr.SetError()
+			}
+			channelz.Warningf(logger, s.channelzID, "grpc: Server.handleStream failed to write status: %v", err)
+		}
+		if trInfo != nil {
+			trInfo.tr.Finish()
+		}
+		return
+	}
 	service := sm[:pos]
 	method := sm[pos+1:]
-	method = strings.ToUpper(string(method[0])) + method[1:]
 
-	srv, knownService := s.services[service]
+	mapper := s.opts.methodNameMapper
+	if mapper == nil {
+		mapper = JavaMethodNameMapper
+	}
+	srv, knownService, st := s.lookupService(stream.Context(), service)
+	if st != nil {
+		// the service is local, so it is neither proxied nor handled generically
+		if trInfo != nil {
+			trInfo.tr.LazyLog(&fmtStringer{"%v", []interface{}{st.Message()}}, true)
+			trInfo.tr.SetError()
+		}
+		if err := t.WriteStatus(stream, st); err != nil {
+			if trInfo != nil {
+				trInfo.tr.LazyLog(&fmtStringer{"%v", []interface{}{err}}, true)
+				trInfo.tr.SetError()
+			}
+			channelz.Warningf(logger, s.channelzID, "grpc: Server.handleStream failed to write status: %v", err)
+		}
+		if trInfo != nil {
+			trInfo.tr.Finish()
+		}
+		return
+	}
 	if knownService {
 		if md, ok := srv.methods["InvokeWithArgs"]; ok {
-			s.processUnaryRPC(method, t, stream, srv, md, trInfo)
+			// dubbo-go invokes the exported go method of the java method name
+			s.processUnaryRPC(capitalize(method), t, stream, srv, md, trInfo)
 			return
 		}
-		if md, ok := srv.methods[method]; ok {
-			s.processUnaryRPC(method, t, stream, srv, md, trInfo)
+		name := mapper.MapMethod(method, srv.names)
+		if sd, ok := srv.streams[name]; ok {
+			s.processStreamingRPC(t, stream, srv, sd, trInfo)
 			return
 		}
-		if sd, ok := srv.streams[method]; ok {
-			s.processStreamingRPC(t, stream, srv, sd, trInfo)
+		// overloads are resolved first, as they may share the name of a method
+		if overloadMapper, ok := mapper.(OverloadMethodNameMapper); ok && overloadMapper.HasOverloads(method) {
+			codec := s.getCodec(stream.ContentSubtype())
+			if isWrapperCodec(codec) {
+				md := overloadMethodDesc(overloadMapper, s.opts.genericHandler, srv, service, method)
+				s.processUnaryRPC(method, t, stream, srv, md, trInfo)
+				return
+			}
+		}
+		if md, ok := srv.methods[name]; ok {
+			s.processUnaryRPC(name, t, stream, srv, md, trInfo)
 			return
 		}
 	}
 
 	// grpc proxy mode
-	if s.opts.proxyModeEnable {
-		// same with in triple.constant.ProxyServiceKey
-		srv, knownService = s.services["github.com.dubbogo.triple.proxy"]
-		if md, ok := srv.methods["InvokeWithArgs"]; ok {
-			s.processUnaryRPC(method, t, stream, srv, md, trInfo)
-			return
+	var errDesc, unroutedDesc string
+	if route := s.proxyRoute(service); route != nil {
+		if proxySrv, ok := s.services[route.Service]; ok {
+			name, md, sd := proxyMethod(proxySrv, mapper, method)
+			if md != nil {
+				s.processUnaryRPC(name, t, stream, proxySrv, md, trInfo)
+				return
+			}
+			if sd != nil {
+				s.processStreamingRPC(t, stream, proxySrv, sd, trInfo)
+				return
+			}
+			errDesc = fmt.Sprintf("proxy service %v of service %v has no method %v, %v or %v",
+				route.Service, service, method, ProxyUnaryMethod, ProxyStreamMethod)
+		} else {
+			// left to the generic and unknown service handlers
+			unroutedDesc = fmt.Sprintf("proxy service %v of service %v is not registered", route.Service, service)
+		}
+	}
+
+	// generic handler serves unknown services and methods of wrapper codecs,
+	// unless they are routed to a proxy service
+	if s.opts.genericHandler != nil && errDesc == "" {
+		if isWrapperCodec(s.getCodec(stream.ContentSubtype())) {
+			if knownService && hasStreamMethod(srv, method) {
+				errDesc = fmt.Sprintf("streaming method %v of service %v can not be handled generically", method, service)
+			} else {
+				md := genericMethodDesc(s.opts.genericHandler, service, method)
+				s.processUnaryRPC(method, t, stream, &serviceInfo{}, md, trInfo)
+				return
+			}
 		}
 	}
 
 	// Unknown service, or known server unknown method.
-	if unknownDesc := s.opts.unknownStreamDesc; unknownDesc != nil {
+	if unknownDesc := s.opts.unknownStreamDesc; unknownDesc != nil && errDesc == "" {
 		s.processStreamingRPC(t, stream, nil, unknownDesc, trInfo)
 		return
 	}
-	var errDesc string
-	if !knownService {
+	switch {
+	case errDesc != "":
+	case unroutedDesc != "":
+		errDesc = unroutedDesc
+	case !knownService:
 		errDesc = fmt.Sprintf("unknown service %v", service)
-	} else {
+	default:
 		errDesc = fmt.Sprintf("unknown method %v for service %v", method, service)
 	}
 	if trInfo != nil {
@@ -1808,7 +2040,9 @@ func (s *Server) Stop() {
 
 // GracefulStop stops the gRPC server gracefully. It stops the server from
 // accepting new connections and RPCs and blocks until all the pending RPCs are
-// finished.
+// finished. Like Shutdown, it notifies DrainNotify and paces GOAWAYs if
+// GoAwayPacing is set, but it has no deadline and leaves the health service
+// unchanged.
 func (s *Server) GracefulStop() {
 	s.quit.Fire()
 	defer s.done.Fire()
@@ -1828,18 +2062,20 @@ func (s *Server) GracefulStop() {
 		lis.Close()
 	}
 	s.lis = nil
+	var drainConns []transport.ServerTransport
 	if !s.drain {
 		for _, conns := range s.conns {
 			for st := range conns {
-				st.Drain()
+				drainConns = append(drainConns, st)
 			}
 		}
 		s.drain = true
 	}
+	s.mu.Unlock()
+	s.startDrain(drainConns)
 
 	// Wait for serving threads to be ready to exit.  Only then can we be sure no
 	// new conns will be created.
-	s.mu.Unlock()
 	s.serveWG.Wait()
 	s.mu.Lock()
 
@@ -1865,6 +2101,9 @@ func (s *Server) getCodec(contentSubtype string) encoding.TwoWayCodec {
 	}
 	codec := encoding.GetCodec(contentSubtype)
 	if codec == nil {
+		if _, warned := s.unknownSubtypes.LoadOrStore(contentSubtype, true); !warned {
+			channelz.Warningf(logger, s.channelzID, "grpc: no codec registered for content-subtype %q, falling back to %q", contentSubtype, proto.Name)
+		}
 		return encoding.GetCodec(proto.Name)
 	}
 	return codec
@@ -1923,6 +2162,21 @@ func Method(ctx context.Context) (string, bool) {
 	return s.Method(), true
 }
 
+// ContentSubtype returns the content-subtype of the request of the server
+// context, e.g. "proto" or "hessian2", "" for "application/grpc".
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func ContentSubtype(ctx context.Context) (string, bool) {
+	s, ok := ServerTransportStreamFromContext(ctx).(interface{ ContentSubtype() string })
+	if !ok {
+		return "", false
+	}
+	return s.ContentSubtype(), true
+}
+
 type channelzServer struct {
 	s *Server
 }
@@ -0,0 +1,173 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/metadata"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+const (
+	// TripleServiceGroupHeader is the header of the group of the called dubbo service.
+	TripleServiceGroupHeader = "tri-service-group"
+	// TripleServiceVersionHeader is the header of the version of the called dubbo service.
+	TripleServiceVersionHeader = "tri-service-version"
+)
+
+// ServiceKey returns the dubbo service key "group/service:version" of
+// @service, without the empty group or version.
+func ServiceKey(group, service, version string) string {
+	var sb strings.Builder
+	sb.Grow(len(group) + len(service) + len(version) + 2)
+	if group != "" {
+		sb.WriteString(group)
+		sb.WriteByte('/')
+	}
+	sb.WriteString(service)
+	if version != "" {
+		sb.WriteByte(':')
+		sb.WriteString(version)
+	}
+	return sb.String()
+}
+
+// TripleServiceRegistrar wraps a single method that supports registering
+// dubbo service implementations of a group and a version, it is used by the
+// stubs generated by protoc-gen-go-grpc with option triple.
+//
+// Experimental
+//
+// Notice: This type is EXPERIMENTAL and may be changed or removed in a
+// later release.
+type TripleServiceRegistrar interface {
+	ServiceRegistrar
+	// RegisterServiceWithGroupVersion registers a service implementation of
+	// dubbo group and version, see Server.RegisterServiceWithGroupVersion.
+	RegisterServiceWithGroupVersion(desc *ServiceDesc, impl interface{}, group, version string)
+}
+
+var _ TripleServiceRegistrar = (*Server)(nil)
+
+// RegisterServiceWithGroupVersion registers the implementation @ss of service
+// @sd for dubbo @group and @version, so that one server can expose several
+// implementations of the same interface. Requests are dispatched by their
+// tri-service-group and tri-service-version headers. Requests without them are
+// served by the implementation registered by RegisterService, or else by the
+// first one registered by RegisterServiceWithGroupVersion. Requests of a group
+// and version which is not registered fail with Unimplemented, unless the
+// server has the ServiceKeyFallback option. They are not left to the proxy,
+// generic or unknown service handlers.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func (s *Server) RegisterServiceWithGroupVersion(sd *ServiceDesc, ss interface{}, group, version string) {
+	s.checkServiceImpl(sd, ss)
+	s.register(sd, ss, group, version)
+}
+
+// ServiceKeyFallback returns a ServerOption that makes requests of a group and
+// version which is not registered by RegisterServiceWithGroupVersion be served
+// by the default implementation of the service, instead of failing with
+// Unimplemented.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func ServiceKeyFallback() ServerOption {
+	return newFuncServerOption(func(o *serverOptions) {
+		o.serviceKeyFallback = true
+	})
+}
+
+// lookupService returns the implementation of @service picked by the group
+// and version headers of @ctx. The headers are ignored for services which are
+// only registered by RegisterService. If @service is registered with a group
+// or a version, but not the requested ones, and the server has no
+// ServiceKeyFallback, it returns the Unimplemented status of the request.
+func (s *Server) lookupService(ctx context.Context, service string) (*serviceInfo, bool, *status.Status) {
+	if len(s.serviceKeys) > 0 {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			group, version := firstValue(md, TripleServiceGroupHeader), firstValue(md, TripleServiceVersionHeader)
+			if group != "" || version != "" {
+				if srv, ok := s.serviceKeys[ServiceKey(group, service, version)]; ok {
+					return srv, true, nil
+				}
+				if !s.opts.serviceKeyFallback && s.hasServiceKeys(service) {
+					return nil, false, status.Newf(codes.Unimplemented, "no implementation of interface:group:version %s:%s:%s, registered ones are %v",
+						service, group, version, s.serviceKeysOf(service))
+				}
+			}
+		}
+	}
+	srv, ok := s.services[service]
+	return srv, ok, nil
+}
+
+// serviceKeysOf returns the sorted keys of the implementations of @service
+// registered with a group or a version.
+func (s *Server) serviceKeysOf(service string) []string {
+	var keys []string
+	for key, srv := range s.serviceKeys {
+		if ServiceKey(srv.group, service, srv.version) == key {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hasServiceKeys reports whether an implementation of @service is registered
+// with a group or a version.
+func (s *Server) hasServiceKeys(service string) bool {
+	for key, srv := range s.serviceKeys {
+		if ServiceKey(srv.group, service, srv.version) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// allServiceKeys returns the sorted keys of all the implementations registered
+// with a group or a version.
+func (s *Server) allServiceKeys() []string {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.serviceKeys))
+	for key := range s.serviceKeys {
+		keys = append(keys, key)
+	}
+	s.mu.Unlock()
+	sort.Strings(keys)
+	return keys
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+import (
+	wrapperspb "github.com/golang/protobuf/ptypes/wrappers"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/metadata"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+// implServiceDesc replies the name of the implementation serving the call,
+// which is a string.
+func implServiceDesc(service string) *ServiceDesc {
+	return &ServiceDesc{
+		ServiceName: service,
+		HandlerType: (*interface{})(nil),
+		Methods: []MethodDesc{{
+			MethodName: "Which",
+			Handler: func(impl interface{}, ctx context.Context, dec func(interface{}) error, _ UnaryServerInterceptor) (interface{}, error) {
+				if err := dec(new(wrapperspb.StringValue)); err != nil {
+					return nil, err
+				}
+				return &wrapperspb.StringValue{Value: impl.(string)}, nil
+			},
+		}},
+	}
+}
+
+// startServiceKeyServer starts a server with @opts serving the
+// implementations of "org.Greeter": "default", "g1" of group g1 and "g1v1" of
+// group g1 and version 1.0, and "proxy" of the proxy service.
+func startServiceKeyServer(t *testing.T, opts ...ServerOption) (*ClientConn, func()) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() failed: %v", err)
+	}
+	srv := NewServer(opts...)
+	desc := implServiceDesc("org.Greeter")
+	srv.RegisterService(desc, "default")
+	srv.RegisterServiceWithGroupVersion(desc, "g1", "g1", "")
+	srv.RegisterServiceWithGroupVersion(desc, "g1v1", "g1", "1.0")
+	proxy := implServiceDesc(ProxyServiceKey)
+	proxy.Methods[0].MethodName = ProxyUnaryMethod
+	srv.RegisterService(proxy, "proxy")
+	go srv.Serve(lis)
+
+	cc, err := Dial(lis.Addr().String(), WithInsecure(), WithDefaultCallOptions(CallContentSubtype("subtype-a")))
+	if err != nil {
+		srv.Stop()
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	return cc, func() {
+		cc.Close()
+		srv.Stop()
+	}
+}
+
+func which(cc *ClientConn, group, version string) (string, error) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		TripleServiceGroupHeader, group, TripleServiceVersionHeader, version)
+	out := new(wrapperspb.StringValue)
+	_, err := cc.Invoke(ctx, "/org.Greeter/Which", &wrapperspb.StringValue{}, out)
+	return out.Value, err
+}
+
+func (s) TestServiceKeyDispatch(t *testing.T) {
+	cc, stop := startServiceKeyServer(t, ProxyModeEnable(true))
+	defer stop()
+	for _, test := range []struct {
+		group, version string
+		want           string
+	}{
+		{want: "default"},
+		{group: "g1", want: "g1"},
+		{group: "g1", version: "1.0", want: "g1v1"},
+	} {
+		if got, err := which(cc, test.group, test.version); err != nil || got != test.want {
+			t.Errorf("call of group %q and version %q = %q, %v, want %q", test.group, test.version, got, err, test.want)
+		}
+	}
+
+	// the proxy service does not capture the call of a local service
+	_, err := which(cc, "g1", "2.0")
+	if status.Code(err) != codes.Unimplemented || !strings.Contains(status.Convert(err).Message(), "org.Greeter:g1:2.0") {
+		t.Errorf("call of an unregistered version = %v, want Unimplemented naming org.Greeter:g1:2.0", err)
+	}
+}
+
+func (s) TestServiceKeyFallback(t *testing.T) {
+	cc, stop := startServiceKeyServer(t, ServiceKeyFallback())
+	defer stop()
+	if got, err := which(cc, "g1", "2.0"); err != nil || got != "default" {
+		t.Errorf("call of an unregistered version = %q, %v, want \"default\"", got, err)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"time"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/internal/grpcsync"
+	"github.com/dubbogo/grpc-go/internal/transport"
+)
+
+const (
+	// healthServiceName is the name of the standard health service, whose
+	// implementation is notified of the shutdown.
+	healthServiceName = "grpc.health.v1.Health"
+)
+
+// GoAwayPacing returns a ServerOption that paces the GOAWAYs sent to the
+// connections by Shutdown and GracefulStop: they are sent to @batch
+// connections every @interval, so that thousands of clients do not
+// reconnect at once. By default, or with a @batch of 0, they are all sent at
+// once.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func GoAwayPacing(batch int, interval time.Duration) ServerOption {
+	return newFuncServerOption(func(o *serverOptions) {
+		o.goAwayBatch = batch
+		o.goAwayInterval = interval
+	})
+}
+
+// Shutdown stops the server gracefully within the deadline of @ctx. It stops
+// the server from accepting new connections and RPCs, notifies the handlers
+// waiting on DrainNotify, sets the statuses of the health service registered
+// on the server, if it is a health.Server, to NOT_SERVING, and sends GOAWAYs
+// paced by GoAwayPacing. It then waits for the pending RPCs to finish. If
+// @ctx is done first, the remaining connections are closed like Stop does,
+// and the error of @ctx is returned.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownHealth()
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		// interrupts GracefulStop
+		s.Stop()
+		<-stopped
+		return ctx.Err()
+	}
+}
+
+// DrainNotify returns a channel which is closed when the server serving the
+// RPC of @ctx starts to shut down, by Shutdown or GracefulStop. Handlers of
+// long-running streams may wait on it to finish them cleanly. It returns nil,
+// which blocks forever, if @ctx is not the context of a server RPC.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func DrainNotify(ctx context.Context) <-chan struct{} {
+	draining, ok := ctx.Value(drainKey{}).(*grpcsync.Event)
+	if !ok {
+		return nil
+	}
+	return draining.Done()
+}
+
+// The key to save the draining event of the server in the context.
+type drainKey struct{}
+
+// newContextWithDrain attaches the draining event of the server to the RPC
+// context @ctx, for DrainNotify.
+func (s *Server) newContextWithDrain(ctx context.Context) context.Context {
+	return context.WithValue(ctx, drainKey{}, s.draining)
+}
+
+// shutdownHealth sets the statuses of the health service registered on the
+// server to NOT_SERVING, for good.
+func (s *Server) shutdownHealth() {
+	s.mu.Lock()
+	healthSrv := s.services[healthServiceName]
+	s.mu.Unlock()
+	if healthSrv != nil {
+		// a health.Server, which can not be imported here
+		if hs, ok := healthSrv.serviceImpl.(interface{ Shutdown() }); ok {
+			hs.Shutdown()
+		}
+	}
+}
+
+// startDrain notifies the handlers that the server is shutting down, and
+// sends GOAWAY to the connections @conns.
+func (s *Server) startDrain(conns []transport.ServerTransport) {
+	s.draining.Fire()
+	s.drainConns(conns)
+}
+
+// drainConns sends GOAWAY to the connections @conns, paced as configured
+// by GoAwayPacing. It gives up once the server is stopped.
+func (s *Server) drainConns(conns []transport.ServerTransport) {
+	batch := s.opts.goAwayBatch
+	for i, st := range conns {
+		if batch > 0 && i > 0 && i%batch == 0 {
+			time.Sleep(s.opts.goAwayInterval)
+			s.mu.Lock()
+			stopped := s.conns == nil
+			s.mu.Unlock()
+			if stopped {
+				return
+			}
+		}
+		st.Drain()
+	}
+}
@@ -0,0 +1,162 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+import (
+	wrapperspb "github.com/golang/protobuf/ptypes/wrappers"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/internal/transport"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+// fakeHealth records the Shutdown of the health service.
+type fakeHealth struct {
+	shutdown bool
+}
+
+func (h *fakeHealth) Shutdown() {
+	h.shutdown = true
+}
+
+var fakeHealthServiceDesc = ServiceDesc{
+	ServiceName: healthServiceName,
+	HandlerType: (*interface{})(nil),
+}
+
+func (s) TestShutdownHealth(t *testing.T) {
+	hs := &fakeHealth{}
+	srv := NewServer()
+	srv.RegisterService(&fakeHealthServiceDesc, hs)
+	srv.GracefulStop()
+	if hs.shutdown {
+		t.Errorf("GracefulStop() shut the health service down")
+	}
+
+	hs = &fakeHealth{}
+	srv = NewServer()
+	srv.RegisterService(&fakeHealthServiceDesc, hs)
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() = %v, want nil", err)
+	}
+	if !hs.shutdown {
+		t.Errorf("Shutdown() did not shut the health service down")
+	}
+}
+
+// drainTransport counts the GOAWAYs sent to it.
+type drainTransport struct {
+	transport.ServerTransport
+	drained *int32
+}
+
+func (t drainTransport) Drain() {
+	atomic.AddInt32(t.drained, 1)
+}
+
+func (s) TestGoAwayPacing(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		opts    []ServerOption
+		minTime time.Duration
+	}{
+		{name: "default"},
+		{name: "paced", opts: []ServerOption{GoAwayPacing(2, 20*time.Millisecond)}, minTime: 40 * time.Millisecond},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			srv := NewServer(test.opts...)
+			defer srv.Stop()
+			var drained int32
+			conns := make([]transport.ServerTransport, 5)
+			for i := range conns {
+				conns[i] = drainTransport{drained: &drained}
+			}
+			start := time.Now()
+			srv.drainConns(conns)
+			if elapsed := time.Since(start); elapsed < test.minTime {
+				t.Errorf("drainConns() took %v, want at least %v", elapsed, test.minTime)
+			}
+			if drained != int32(len(conns)) {
+				t.Errorf("drainConns() drained %d connections, want %d", drained, len(conns))
+			}
+		})
+	}
+}
+
+func (s) TestDrainNotify(t *testing.T) {
+	if DrainNotify(context.Background()) != nil {
+		t.Errorf("DrainNotify() of a context without server = non-nil, want nil")
+	}
+
+	started := make(chan struct{})
+	desc := ServiceDesc{
+		ServiceName: "grpc.testing.Drain",
+		HandlerType: (*interface{})(nil),
+		Methods: []MethodDesc{{
+			MethodName: "Wait",
+			Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ UnaryServerInterceptor) (interface{}, error) {
+				in := new(wrapperspb.StringValue)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				close(started)
+				select {
+				case <-DrainNotify(ctx):
+					return &wrapperspb.StringValue{Value: "drained"}, nil
+				case <-time.After(5 * time.Second):
+					return nil, status.Error(codes.DeadlineExceeded, "not drained")
+				}
+			},
+		}},
+	}
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() failed: %v", err)
+	}
+	srv := NewServer()
+	srv.RegisterService(&desc, struct{}{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+	cc, err := Dial(lis.Addr().String(), WithInsecure())
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	defer cc.Close()
+
+	done := make(chan error, 1)
+	out := new(wrapperspb.StringValue)
+	go func() {
+		_, err := cc.Invoke(context.Background(), "/grpc.testing.Drain/Wait", &wrapperspb.StringValue{}, out)
+		done <- err
+	}()
+	<-started
+	srv.GracefulStop()
+	if err := <-done; err != nil || out.Value != "drained" {
+		t.Errorf("Invoke() = %q, %v, want \"drained\", nil", out.Value, err)
+	}
+}
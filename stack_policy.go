@@ -0,0 +1,73 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"net"
+	"strings"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/internal/transport"
+	"github.com/dubbogo/grpc-go/peer"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+// StatusStackPolicy returns a ServerOption that sets the policy deciding
+// which stack traces of returned statuses are sent to clients. Servers
+// without it send them as they are. Whether stack traces are captured at all
+// is set process wide by status.SetStackCapture.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func StatusStackPolicy(p status.StackPolicy) ServerOption {
+	return newFuncServerOption(func(o *serverOptions) {
+		o.stackPolicy = &p
+	})
+}
+
+// withStackPolicy wraps @st to filter the stacks of the statuses it writes,
+// unless stack traces are sent as they are.
+func (s *Server) withStackPolicy(st transport.ServerTransport) transport.ServerTransport {
+	if st == nil || s.opts.stackPolicy == nil || s.opts.stackPolicy.Mode == status.StackSend {
+		return st
+	}
+	return &stackPolicyTransport{ServerTransport: st, policy: *s.opts.stackPolicy}
+}
+
+// stackPolicyTransport is a ServerTransport which applies policy to the
+// errdetails.DebugInfo details of the statuses written to the client.
+type stackPolicyTransport struct {
+	transport.ServerTransport
+	policy status.StackPolicy
+}
+
+func (t *stackPolicyTransport) WriteStatus(stream *transport.Stream, st *status.Status) error {
+	var addr net.Addr
+	if pr, ok := peer.FromContext(stream.Context()); ok {
+		addr = pr.Addr
+	}
+	filtered, stripped := t.policy.Filter(st, addr)
+	if stripped && t.policy.Mode == status.StackLogOnly {
+		logger.Infof("grpc: stacks of status %q returned by %s:\n%s",
+			st.Message(), stream.Method(), strings.Join(st.StackEntries(), "\n"))
+	}
+	return t.ServerTransport.WriteStatus(stream, filtered)
+}
@@ -209,6 +209,22 @@ func (s *End) IsClient() bool { return s.Client }
 
 func (s *End) isRPCStats() {}
 
+// DroppedAttachment contains stats when an attachment of an OuterResult is
+// dropped because its value can not be mapped to metadata.
+type DroppedAttachment struct {
+	// Client is true if this DroppedAttachment is from client side.
+	Client bool
+	// Key is the key of the dropped attachment.
+	Key string
+	// Value is the dropped attachment value.
+	Value interface{}
+}
+
+// IsClient indicates if the stats information is from client side.
+func (s *DroppedAttachment) IsClient() bool { return s.Client }
+
+func (s *DroppedAttachment) isRPCStats() {}
+
 // ConnStats contains stats information about connections.
 type ConnStats interface {
 	isConnStats()
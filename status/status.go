@@ -30,6 +30,7 @@ package status
 import (
 	"context"
 	"fmt"
+	"net"
 )
 
 import (
@@ -141,3 +142,103 @@ func FromContextError(err error) *Status {
 		return New(codes.Unknown, err.Error())
 	}
 }
+
+// StackPolicy controls the stack traces of the statuses a server sends to
+// clients, see grpc.StatusStackPolicy.
+type StackPolicy = status.StackPolicy
+
+// StackMode selects how the stack traces of statuses are sent.
+type StackMode = status.StackMode
+
+const (
+	// StackSend sends stack traces to every peer, it is the default.
+	StackSend = status.StackSend
+	// StackSkip never sends stack traces. Use SetStackCapture to not capture
+	// them at all.
+	StackSkip = status.StackSkip
+	// StackLogOnly logs stack traces on the server, but never sends them.
+	StackLogOnly = status.StackLogOnly
+	// StackAllowlist sends stack traces only to the peers accepted by StackPolicy.AllowPeer.
+	StackAllowlist = status.StackAllowlist
+	// StackTruncate sends at most StackPolicy.MaxFrames frames.
+	StackTruncate = status.StackTruncate
+)
+
+// SetStackCapture sets the max number of frames New and the other
+// constructors capture in the process, 0 disables stack capture to save CPU
+// on hot error paths. At most 32 frames are captured, which is the default.
+// It must only be called at init time.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func SetStackCapture(maxFrames int) {
+	status.SetStackCapture(maxFrames)
+}
+
+// AllowPeerNetworks returns a StackPolicy.AllowPeer accepting the TCP and
+// UDP peers whose IP is in one of the CIDR @networks, e.g. "10.0.0.0/8".
+func AllowPeerNetworks(networks ...string) (func(net.Addr) bool, error) {
+	nets := make([]*net.IPNet, 0, len(networks))
+	for _, n := range networks {
+		_, ipNet, err := net.ParseCIDR(n)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, ipNet)
+	}
+	return func(addr net.Addr) bool {
+		var ip net.IP
+		switch a := addr.(type) {
+		case *net.TCPAddr:
+			ip = a.IP
+		case *net.UDPAddr:
+			ip = a.IP
+		default:
+			return false
+		}
+		for _, ipNet := range nets {
+			if ipNet.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// JavaException is an exception thrown by a java provider, or thrown by a go
+// provider to java consumers. Errors of statuses carrying one unwrap to it,
+// so that callers can use errors.As:
+//
+//	var ex *status.JavaException
+//	if errors.As(err, &ex) && ex.ClassName == "com.foo.BizException" {
+//		...
+//	}
+type JavaException = status.JavaException
+
+// StackTraceElement is a frame of a java stack trace.
+type StackTraceElement = status.StackTraceElement
+
+// FromJavaException returns a Status representing code @c and exception @e,
+// which java consumers see as an RpcException caused by @e.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func FromJavaException(c codes.Code, e *JavaException) *Status {
+	return status.FromJavaException(c, e)
+}
+
+// RegisterJavaException makes remote exceptions of @className unwrap to the
+// error returned by @factory, so that callers can use errors.As against their
+// own error types. It must only be called at init time.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func RegisterJavaException(className string, factory func(*JavaException) error) {
+	status.RegisterJavaException(className, factory)
+}
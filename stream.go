@@ -254,10 +254,11 @@ func newClientStreamWithParams(ctx context.Context, desc *StreamDesc, cc *Client
 	}
 
 	callHdr := &transport.CallHdr{
-		Host:           cc.authority,
-		Method:         method,
-		ContentSubtype: c.contentSubtype,
-		DoneFunc:       doneFunc,
+		Host:                  cc.authority,
+		Method:                method,
+		ContentSubtype:        c.contentSubtype,
+		AcceptContentSubtypes: c.acceptContentSubtypes,
+		DoneFunc:              doneFunc,
 	}
 
 	// Set our outgoing compression according to the UseCompressor CallOption, if
@@ -504,6 +505,8 @@ type csAttempt struct {
 	dc        Decompressor
 	decomp    encoding.Compressor
 	decompSet bool
+	// recvCodec decodes responses, see clientStream.responseCodec.
+	recvCodec encoding.TwoWayCodec
 
 	mu sync.Mutex // guards trInfo.tr
 	// trInfo may be nil (if EnableTracing is false).
@@ -792,19 +795,29 @@ func (cs *clientStream) SendMsg(m interface{}) (err error) {
 		cs.sentLast = true
 	}
 
-	// load hdr, payload, data
-	hdr, payload, data, err := prepareMsg("req", m, cs.codec, cs.cp, cs.comp)
-	if err != nil {
-		return err
-	}
+	var (
+		obj                interface{}
+		hdr, payload, data []byte
+	)
+	if lis := cs.cc.dopts.inProcess; lis != nil {
+		// in-process streams send the message object, or its encoding.
+		if obj, data, err = prepareObject("req", m, cs.codec, lis.deepCopy); err != nil {
+			return err
+		}
+	} else {
+		// load hdr, payload, data
+		if hdr, payload, data, err = prepareMsg("req", m, cs.codec, cs.cp, cs.comp); err != nil {
+			return err
+		}
 
-	// TODO(dfawley): should we be checking len(data) instead?
-	if len(payload) > *cs.callInfo.maxSendMessageSize {
-		return status.Errorf(codes.ResourceExhausted, "trying to send message larger than max (%d vs. %d)", len(payload), *cs.callInfo.maxSendMessageSize)
+		// TODO(dfawley): should we be checking len(data) instead?
+		if len(payload) > *cs.callInfo.maxSendMessageSize {
+			return status.Errorf(codes.ResourceExhausted, "trying to send message larger than max (%d vs. %d)", len(payload), *cs.callInfo.maxSendMessageSize)
+		}
 	}
 	msgBytes := data // Store the pointer before setting to nil. For binary logging.
 	op := func(a *csAttempt) error {
-		err := a.sendMsg(m, hdr, payload, data)
+		err := a.sendMsg(m, obj, hdr, payload, data)
 		// nil out the message and uncomp when replaying; they are only needed for
 		// stats which is disabled for subsequent attempts.
 		m, data = nil, nil
@@ -930,7 +943,9 @@ func (cs *clientStream) finish(err error) {
 	cs.cancel()
 }
 
-func (a *csAttempt) sendMsg(m interface{}, hdr, payld, data []byte) error {
+// sendMsg sends message m, which is encoded in payld, or passed as obj on
+// in-process streams.
+func (a *csAttempt) sendMsg(m, obj interface{}, hdr, payld, data []byte) error {
 	cs := a.cs
 	if a.trInfo != nil {
 		a.mu.Lock()
@@ -939,7 +954,13 @@ func (a *csAttempt) sendMsg(m interface{}, hdr, payld, data []byte) error {
 		}
 		a.mu.Unlock()
 	}
-	if err := a.t.Write(a.s, hdr, payld, &transport.Options{Last: !cs.desc.ClientStreams}); err != nil {
+	var err error
+	if a.s.InProcess() {
+		err = a.s.WriteObject(obj, &transport.Options{Last: !cs.desc.ClientStreams})
+	} else {
+		err = a.t.Write(a.s, hdr, payld, &transport.Options{Last: !cs.desc.ClientStreams})
+	}
+	if err != nil {
 		if !cs.desc.ClientStreams {
 			// For non-client-streaming RPCs, we return nil instead of EOF on error
 			// because the generated code requires it.  finish is not called; RecvMsg()
@@ -949,7 +970,11 @@ func (a *csAttempt) sendMsg(m interface{}, hdr, payld, data []byte) error {
 		return io.EOF
 	}
 	if a.statsHandler != nil {
-		a.statsHandler.HandleRPC(a.ctx, outPayload(true, m, data, payld, time.Now()))
+		if a.s.InProcess() {
+			a.statsHandler.HandleRPC(a.ctx, objectPayload(true, m, data, time.Now()))
+		} else {
+			a.statsHandler.HandleRPC(a.ctx, outPayload(true, m, data, payld, time.Now()))
+		}
 	}
 	if channelz.IsOn() {
 		a.t.IncrMsgSent()
@@ -979,7 +1004,16 @@ func (a *csAttempt) recvMsg(m interface{}, payInfo *payloadInfo) (err error) {
 		// Only initialize this state once per stream.
 		a.decompSet = true
 	}
-	err = recv("rsp", a.p, cs.codec, a.s, a.dc, m, *cs.callInfo.maxReceiveMessageSize, payInfo, a.decomp)
+	if a.recvCodec == nil {
+		if a.recvCodec, err = cs.responseCodec(a.s); err != nil {
+			return err
+		}
+	}
+	if a.s.InProcess() {
+		err = recvObject("rsp", a.recvCodec, a.s, m, payInfo)
+	} else {
+		err = recv("rsp", a.p, a.recvCodec, a.s, a.dc, m, *cs.callInfo.maxReceiveMessageSize, payInfo, a.decomp)
+	}
 	if err != nil {
 		if err == io.EOF {
 			if statusErr := a.s.Status().Err(); statusErr != nil {
@@ -1016,7 +1050,11 @@ func (a *csAttempt) recvMsg(m interface{}, payInfo *payloadInfo) (err error) {
 	}
 	// Special handling for non-server-stream rpcs.
 	// This recv expects EOF or errors, so we don't collect inPayload.
-	err = recv("rsp", a.p, cs.codec, a.s, a.dc, m, *cs.callInfo.maxReceiveMessageSize, nil, a.decomp)
+	if a.s.InProcess() {
+		err = recvObject("rsp", a.recvCodec, a.s, m, nil)
+	} else {
+		err = recv("rsp", a.p, a.recvCodec, a.s, a.dc, m, *cs.callInfo.maxReceiveMessageSize, nil, a.decomp)
+	}
 	if err == nil {
 		return toRPCErr(errors.New("grpc: client streaming protocol violation: get <nil>, want <EOF>"))
 	}
@@ -1261,18 +1299,27 @@ func (as *addrConnStream) SendMsg(m interface{}) (err error) {
 		as.sentLast = true
 	}
 
-	// load hdr, payload, data
-	hdr, payld, _, err := prepareMsg("req", m, as.codec, as.cp, as.comp)
-	if err != nil {
-		return err
-	}
+	if as.s.InProcess() {
+		// in-process streams send the message object, or its encoding.
+		obj, _, err := prepareObject("req", m, as.codec, as.s.DeepCopy())
+		if err != nil {
+			return err
+		}
+		err = as.s.WriteObject(obj, &transport.Options{Last: !as.desc.ClientStreams})
+	} else {
+		// load hdr, payload, data
+		hdr, payld, _, err := prepareMsg("req", m, as.codec, as.cp, as.comp)
+		if err != nil {
+			return err
+		}
 
-	// TODO(dfawley): should we be checking len(data) instead?
-	if len(payld) > *as.callInfo.maxSendMessageSize {
-		return status.Errorf(codes.ResourceExhausted, "trying to send message larger than max (%d vs. %d)", len(payld), *as.callInfo.maxSendMessageSize)
+		// TODO(dfawley): should we be checking len(data) instead?
+		if len(payld) > *as.callInfo.maxSendMessageSize {
+			return status.Errorf(codes.ResourceExhausted, "trying to send message larger than max (%d vs. %d)", len(payld), *as.callInfo.maxSendMessageSize)
+		}
+		err = as.t.Write(as.s, hdr, payld, &transport.Options{Last: !as.desc.ClientStreams})
 	}
-
-	if err := as.t.Write(as.s, hdr, payld, &transport.Options{Last: !as.desc.ClientStreams}); err != nil {
+	if err != nil {
 		if !as.desc.ClientStreams {
 			// For non-client-streaming RPCs, we return nil instead of EOF on error
 			// because the generated code requires it.  finish is not called; RecvMsg()
@@ -1312,7 +1359,11 @@ func (as *addrConnStream) RecvMsg(m interface{}) (err error) {
 		// Only initialize this state once per stream.
 		as.decompSet = true
 	}
-	err = recv("rsp", as.p, as.codec, as.s, as.dc, m, *as.callInfo.maxReceiveMessageSize, nil, as.decomp)
+	if as.s.InProcess() {
+		err = recvObject("rsp", as.codec, as.s, m, nil)
+	} else {
+		err = recv("rsp", as.p, as.codec, as.s, as.dc, m, *as.callInfo.maxReceiveMessageSize, nil, as.decomp)
+	}
 	if err != nil {
 		if err == io.EOF {
 			if statusErr := as.s.Status().Err(); statusErr != nil {
@@ -1333,7 +1384,11 @@ func (as *addrConnStream) RecvMsg(m interface{}) (err error) {
 
 	// Special handling for non-server-stream rpcs.
 	// This recv expects EOF or errors, so we don't collect inPayload.
-	err = recv("rsp", as.p, as.codec, as.s, as.dc, m, *as.callInfo.maxReceiveMessageSize, nil, as.decomp)
+	if as.s.InProcess() {
+		err = recvObject("rsp", as.codec, as.s, m, nil)
+	} else {
+		err = recv("rsp", as.p, as.codec, as.s, as.dc, m, *as.callInfo.maxReceiveMessageSize, nil, as.decomp)
+	}
 	if err == nil {
 		return toRPCErr(errors.New("grpc: client streaming protocol violation: get <nil>, want <EOF>"))
 	}
@@ -1406,6 +1461,10 @@ type ServerStream interface {
 	// It is safe to have a goroutine calling SendMsg and another goroutine
 	// calling RecvMsg on the same stream at the same time, but it is not safe
 	// to call SendMsg on the same stream in different goroutines.
+	//
+	// If m implements OuterResult, its Result is sent and its Attachments are
+	// sent as headers if headers have not been sent yet, or as trailers
+	// otherwise.
 	SendMsg(m interface{}) error
 	// RecvMsg blocks until it receives a message into m or the stream is
 	// done. It returns io.EOF when the client has performed a CloseSend. On
@@ -1425,6 +1484,9 @@ type serverStream struct {
 	s     *transport.Stream
 	p     *parser
 	codec encoding.TwoWayCodec
+	// sendCodec encodes responses, it differs from codec when the server
+	// negotiated another content-subtype for responses.
+	sendCodec encoding.TwoWayCodec
 
 	cp     Compressor
 	dc     Decompressor
@@ -1512,18 +1574,40 @@ func (ss *serverStream) SendMsg(m interface{}) (err error) {
 		}
 	}()
 
-	// load hdr, payload, data
-	hdr, payload, data, err := prepareMsg("rsp", m, ss.codec, ss.cp, ss.comp)
-	if err != nil {
-		return err
+	if result, ok := m.(OuterResult); ok {
+		// attachments are sent as headers with the first message, and as
+		// trailers once headers have been sent.
+		md := attachmentsToMetadata(ss.s.Context(), ss.statsHandler, result.Attachments())
+		if err := ss.s.SetHeader(md); err == transport.ErrIllegalHeaderWrite {
+			ss.s.SetTrailer(md)
+		}
+		m = result.Result()
 	}
 
-	// TODO(dfawley): should we be checking len(data) instead?
-	if len(payload) > ss.maxSendMessageSize {
-		return status.Errorf(codes.ResourceExhausted, "trying to send message larger than max (%d vs. %d)", len(payload), ss.maxSendMessageSize)
-	}
-	if err := ss.t.Write(ss.s, hdr, payload, &transport.Options{Last: false}); err != nil {
-		return toRPCErr(err)
+	var payload, data []byte
+	if ss.s.InProcess() {
+		// in-process streams send the message object, or its encoding.
+		var obj interface{}
+		if obj, data, err = prepareObject("rsp", m, ss.sendCodec, ss.s.DeepCopy()); err != nil {
+			return err
+		}
+		if err := ss.s.WriteObject(obj, &transport.Options{Last: false}); err != nil {
+			return toRPCErr(err)
+		}
+	} else {
+		// load hdr, payload, data
+		var hdr []byte
+		if hdr, payload, data, err = prepareMsg("rsp", m, ss.sendCodec, ss.cp, ss.comp); err != nil {
+			return err
+		}
+
+		// TODO(dfawley): should we be checking len(data) instead?
+		if len(payload) > ss.maxSendMessageSize {
+			return status.Errorf(codes.ResourceExhausted, "trying to send message larger than max (%d vs. %d)", len(payload), ss.maxSendMessageSize)
+		}
+		if err := ss.t.Write(ss.s, hdr, payload, &transport.Options{Last: false}); err != nil {
+			return toRPCErr(err)
+		}
 	}
 	if ss.binlog != nil {
 		if !ss.serverHeaderBinlogged {
@@ -1538,7 +1622,11 @@ func (ss *serverStream) SendMsg(m interface{}) (err error) {
 		})
 	}
 	if ss.statsHandler != nil {
-		ss.statsHandler.HandleRPC(ss.s.Context(), outPayload(false, m, data, payload, time.Now()))
+		if ss.s.InProcess() {
+			ss.statsHandler.HandleRPC(ss.s.Context(), objectPayload(false, m, data, time.Now()))
+		} else {
+			ss.statsHandler.HandleRPC(ss.s.Context(), outPayload(false, m, data, payload, time.Now()))
+		}
 	}
 	return nil
 }
@@ -1575,7 +1663,12 @@ func (ss *serverStream) RecvMsg(m interface{}) (err error) {
 	if ss.statsHandler != nil || ss.binlog != nil {
 		payInfo = &payloadInfo{}
 	}
-	if err := recv("req", ss.p, ss.codec, ss.s, ss.dc, m, ss.maxReceiveMessageSize, payInfo, ss.decomp); err != nil {
+	if ss.s.InProcess() {
+		err = recvObject("req", ss.codec, ss.s, m, payInfo)
+	} else {
+		err = recv("req", ss.p, ss.codec, ss.s, ss.dc, m, ss.maxReceiveMessageSize, payInfo, ss.decomp)
+	}
+	if err != nil {
 		if err == io.EOF {
 			if ss.binlog != nil {
 				ss.binlog.Log(&binarylog.ClientHalfClose{})
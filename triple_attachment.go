@@ -0,0 +1,115 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/internal/attachment"
+	"github.com/dubbogo/grpc-go/metadata"
+	"github.com/dubbogo/grpc-go/stats"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+// attachmentsToMetadata converts the attachments of an OuterResult to
+// metadata sent as response headers or trailers. Values which can not be
+// mapped are dropped, logged and reported to @sh as stats.DroppedAttachment.
+func attachmentsToMetadata(ctx context.Context, sh stats.Handler, attachments map[string]interface{}) metadata.MD {
+	md, unsupported := attachment.ToMetadata(attachments)
+	for _, k := range unsupported {
+		logger.Warningf("grpc: dropping attachment %q, unsupported value type %T", k, attachments[k])
+		if sh != nil {
+			sh.HandleRPC(ctx, &stats.DroppedAttachment{
+				Key:   k,
+				Value: attachments[k],
+			})
+		}
+	}
+	return md
+}
+
+// AttachmentsFromMetadata converts response headers or trailers back to dubbo
+// attachments. It reverts the mapping used for OuterResult attachments:
+// "-bin" keys are decoded to []byte under the key without the suffix, and
+// bool and numeric values are decoded to their original go type.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func AttachmentsFromMetadata(md metadata.MD) map[string]interface{} {
+	return attachment.FromMetadata(md)
+}
+
+// InvokeWithAttachments is like Invoke, but sends @attachments as request
+// metadata, using the same mapping as OuterResult attachments, and returns
+// the response attachments merged from the headers and trailers, trailers
+// taking precedence. It allows dubbo implicit parameters such as traceId, tag
+// and group to propagate between go and java.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func (cc *ClientConn) InvokeWithAttachments(ctx context.Context, method string, args, reply interface{}, attachments map[string]interface{}, opts ...CallOption) (map[string]interface{}, error) {
+	md, unsupported := attachment.ToMetadata(attachments)
+	if len(unsupported) > 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "grpc: unsupported attachment value types: %s", formatUnsupported(attachments, unsupported))
+	}
+	if outgoing, ok := metadata.FromOutgoingContext(ctx); ok {
+		md = metadata.Join(outgoing, md)
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	var header metadata.MD
+	opts = append(opts[:len(opts):len(opts)], Header(&header))
+	trailer, err := cc.Invoke(ctx, method, args, reply, opts...)
+	return MergeAttachments(header, trailer), err
+}
+
+// MergeAttachments converts the response @header and @trailer to dubbo
+// attachments like AttachmentsFromMetadata, values of @trailer take
+// precedence. It is used by InvokeWithAttachments and the stubs generated by
+// protoc-gen-go-grpc with option triple.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func MergeAttachments(header, trailer metadata.MD) map[string]interface{} {
+	attachments := attachment.FromMetadata(header)
+	for k, v := range attachment.FromMetadata(trailer) {
+		attachments[k] = v
+	}
+	return attachments
+}
+
+func formatUnsupported(attachments map[string]interface{}, keys []string) string {
+	desc := ""
+	for i, k := range keys {
+		if i > 0 {
+			desc += ", "
+		}
+		desc += fmt.Sprintf("%s(%T)", k, attachments[k])
+	}
+	return desc
+}
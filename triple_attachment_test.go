@@ -0,0 +1,173 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+import (
+	wrapperspb "github.com/golang/protobuf/ptypes/wrappers"
+)
+
+import (
+	"github.com/dubbogo/grpc-go/codes"
+	"github.com/dubbogo/grpc-go/metadata"
+	"github.com/dubbogo/grpc-go/stats"
+	"github.com/dubbogo/grpc-go/status"
+)
+
+// attachmentResult is an OuterResult.
+type attachmentResult struct {
+	result      interface{}
+	attachments map[string]interface{}
+}
+
+func (r *attachmentResult) Result() interface{} {
+	return r.result
+}
+
+func (r *attachmentResult) Attachments() map[string]interface{} {
+	return r.attachments
+}
+
+// attachmentServiceDesc replies the attachments of the request, and a value
+// which can not be mapped. It also sends "source" as header and trailer.
+var attachmentServiceDesc = ServiceDesc{
+	ServiceName: "grpc.testing.Attachment",
+	HandlerType: (*interface{})(nil),
+	Methods: []MethodDesc{{
+		MethodName: "Echo",
+		Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ UnaryServerInterceptor) (interface{}, error) {
+			in := new(wrapperspb.StringValue)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			md, _ := metadata.FromIncomingContext(ctx)
+			received := AttachmentsFromMetadata(md)
+			attachments := map[string]interface{}{
+				"unsupported": make(chan int),
+				"source":      "trailer",
+			}
+			for _, k := range []string{"traceid", "payload", "count", "enabled", "ratio"} {
+				attachments[k] = received[k]
+			}
+			SetHeader(ctx, metadata.Pairs("source", "header"))
+			return &attachmentResult{result: in, attachments: attachments}, nil
+		},
+	}},
+}
+
+// droppedCounter counts the stats.DroppedAttachment events.
+type droppedCounter struct {
+	mu      sync.Mutex
+	dropped []string
+}
+
+func (h *droppedCounter) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (h *droppedCounter) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if d, ok := s.(*stats.DroppedAttachment); ok {
+		h.mu.Lock()
+		h.dropped = append(h.dropped, d.Key)
+		h.mu.Unlock()
+	}
+}
+
+func (h *droppedCounter) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *droppedCounter) HandleConn(context.Context, stats.ConnStats) {}
+
+func (s) TestInvokeWithAttachments(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("net.Listen() failed: %v", err)
+	}
+	counter := &droppedCounter{}
+	srv := NewServer(StatsHandler(counter))
+	srv.RegisterService(&attachmentServiceDesc, struct{}{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+	cc, err := Dial(lis.Addr().String(), WithInsecure(), WithDefaultCallOptions(CallContentSubtype("subtype-a")))
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	defer cc.Close()
+
+	sent := map[string]interface{}{
+		"traceId": "abc",
+		"payload": []byte{0, 1, 0xff},
+		"count":   int64(7),
+		"enabled": true,
+		"ratio":   0.5,
+	}
+	out := new(wrapperspb.StringValue)
+	got, err := cc.InvokeWithAttachments(context.Background(), "/grpc.testing.Attachment/Echo", &wrapperspb.StringValue{Value: "hi"}, out, sent)
+	if err != nil || out.Value != "hi" {
+		t.Fatalf("InvokeWithAttachments() = %q, %v, want \"hi\", nil", out.Value, err)
+	}
+	for k, want := range map[string]interface{}{
+		"traceid": "abc",
+		"payload": []byte{0, 1, 0xff},
+		"count":   int64(7),
+		"enabled": true,
+		"ratio":   0.5,
+		// the trailer takes precedence over the header
+		"source": "trailer",
+	} {
+		if !reflect.DeepEqual(got[k], want) {
+			t.Errorf("attachment %q = %#v, want %#v", k, got[k], want)
+		}
+	}
+	if _, ok := got["unsupported"]; ok {
+		t.Errorf("the unsupported attachment was sent")
+	}
+	counter.mu.Lock()
+	dropped := counter.dropped
+	counter.mu.Unlock()
+	if !reflect.DeepEqual(dropped, []string{"unsupported"}) {
+		t.Errorf("dropped attachments = %v, want [unsupported]", dropped)
+	}
+
+	_, err = cc.InvokeWithAttachments(context.Background(), "/grpc.testing.Attachment/Echo", &wrapperspb.StringValue{}, out, map[string]interface{}{"bad": struct{}{}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("InvokeWithAttachments() with an unsupported value = %v, want InvalidArgument", err)
+	}
+}
+
+func (s) TestMergeAttachments(t *testing.T) {
+	header := metadata.MD{"key": {"header"}, "only-header": {"1"}, "only-header-tri-type": {"int32"}}
+	trailer := metadata.MD{"key": {"trailer"}, "data-bin": {"\x00\x01"}}
+	got := MergeAttachments(header, trailer)
+	want := map[string]interface{}{
+		"key":         "trailer",
+		"only-header": int32(1),
+		"data":        []byte{0, 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeAttachments() = %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"strings"
+)
+
+// The string context keys used by triple dispatch before the typed accessors
+// below. They are still set during a deprecation window so that existing
+// dubbo-go integrations keep working, and will be removed in a later release.
+// They keep their former values: legacyTripleInterfaceKey holds the full
+// method "/service/method", not the interface name.
+const (
+	legacyTripleMethodKey    = "XXX_TRIPLE_GO_METHOD_NAME"
+	legacyTripleInterfaceKey = "XXX_TRIPLE_GO_INTERFACE_NAME"
+	legacyTriplePayloadKey   = "XXX_TRIPLE_GO_GENERIC_PAYLOAD"
+)
+
+// The keys to save triple dispatch info in the context.
+type (
+	tripleMethodKey    struct{}
+	tripleInterfaceKey struct{}
+	rawPayloadKey      struct{}
+)
+
+// newContextWithTripleInfo attaches the method name, the interface name and
+// the raw request payload of the triple call on @fullMethod to @ctx.
+// @payload is nil for streaming calls, which have no single request message.
+func newContextWithTripleInfo(ctx context.Context, method, fullMethod string, payload []byte) context.Context {
+	ctx = context.WithValue(ctx, tripleMethodKey{}, method)
+	ctx = context.WithValue(ctx, tripleInterfaceKey{}, interfaceFromFullMethod(fullMethod))
+	if payload != nil {
+		ctx = context.WithValue(ctx, rawPayloadKey{}, payload)
+	}
+
+	// deprecated string keys, kept for existing dubbo-go integrations
+	ctx = context.WithValue(ctx, legacyTripleMethodKey, method)
+	ctx = context.WithValue(ctx, legacyTripleInterfaceKey, fullMethod)
+	if payload != nil {
+		ctx = context.WithValue(ctx, legacyTriplePayloadKey, payload)
+	}
+	return ctx
+}
+
+// newContextWithTripleMethod replaces the method name of @ctx by @method, the
+// go method resolved from an overloaded java method.
+func newContextWithTripleMethod(ctx context.Context, method string) context.Context {
+	ctx = context.WithValue(ctx, tripleMethodKey{}, method)
+	return context.WithValue(ctx, legacyTripleMethodKey, method)
+}
+
+// streamMethodName returns the name of the streaming method @sd, or the method
+// of @fullMethod for the unknown service handler, which has no name.
+func streamMethodName(sd *StreamDesc, fullMethod string) string {
+	if sd.StreamName != "" {
+		return sd.StreamName
+	}
+	if pos := strings.LastIndex(fullMethod, "/"); pos != -1 {
+		return fullMethod[pos+1:]
+	}
+	return fullMethod
+}
+
+// interfaceFromFullMethod returns "service" of "/service/method".
+func interfaceFromFullMethod(fullMethod string) string {
+	sm := strings.TrimPrefix(fullMethod, "/")
+	if pos := strings.LastIndex(sm, "/"); pos != -1 {
+		return sm[:pos]
+	}
+	return sm
+}
+
+// TripleMethodFromContext returns the name of the method being served, with
+// the first letter upper-cased as it is dispatched to the go service.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func TripleMethodFromContext(ctx context.Context) (string, bool) {
+	method, ok := ctx.Value(tripleMethodKey{}).(string)
+	return method, ok
+}
+
+// TripleInterfaceFromContext returns the interface (service) name of the call
+// being served, e.g. "org.apache.dubbo.Greeter". Unlike the deprecated
+// "XXX_TRIPLE_GO_INTERFACE_NAME" context key, which holds the full method
+// "/org.apache.dubbo.Greeter/sayHello", it has no method name.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func TripleInterfaceFromContext(ctx context.Context) (string, bool) {
+	interfaceName, ok := ctx.Value(tripleInterfaceKey{}).(string)
+	return interfaceName, ok
+}
+
+// RawPayloadFromContext returns the received, decompressed but not yet
+// unmarshalled request message of the unary call being served.
+//
+// Experimental
+//
+// Notice: This API is EXPERIMENTAL and may be changed or removed in a
+// later release.
+func RawPayloadFromContext(ctx context.Context) ([]byte, bool) {
+	payload, ok := ctx.Value(rawPayloadKey{}).([]byte)
+	return payload, ok
+}
@@ -0,0 +1,52 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func (s) TestTripleContextLegacyKeys(t *testing.T) {
+	payload := []byte("payload")
+	ctx := newContextWithTripleInfo(context.Background(), "SayHello", "/org.apache.dubbo.Greeter/sayHello", payload)
+
+	method, ok := TripleMethodFromContext(ctx)
+	if !ok || method != "SayHello" || ctx.Value(legacyTripleMethodKey) != method {
+		t.Errorf("method = %q, %v, legacy %v, want both %q", method, ok, ctx.Value(legacyTripleMethodKey), "SayHello")
+	}
+	interfaceName, ok := TripleInterfaceFromContext(ctx)
+	if !ok || interfaceName != "org.apache.dubbo.Greeter" {
+		t.Errorf("TripleInterfaceFromContext() = %q, %v, want %q", interfaceName, ok, "org.apache.dubbo.Greeter")
+	}
+	// the legacy key keeps holding the full method
+	if got := ctx.Value(legacyTripleInterfaceKey); got != "/org.apache.dubbo.Greeter/sayHello" {
+		t.Errorf("legacy interface = %v, want the full method", got)
+	}
+	got, ok := RawPayloadFromContext(ctx)
+	legacy, _ := ctx.Value(legacyTriplePayloadKey).([]byte)
+	if !ok || !bytes.Equal(got, payload) || !bytes.Equal(legacy, payload) {
+		t.Errorf("payload = %q, %v, legacy %q, want both %q", got, ok, legacy, payload)
+	}
+
+	ctx = newContextWithTripleInfo(context.Background(), "Stream", "/org.apache.dubbo.Greeter/stream", nil)
+	if _, ok := RawPayloadFromContext(ctx); ok || ctx.Value(legacyTriplePayloadKey) != nil {
+		t.Errorf("streaming call has a payload")
+	}
+}